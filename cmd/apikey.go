@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/service"
+)
+
+const apiKeyUsage = `usage:
+  backend apikey create -name <name> -role <viewer|operator|finance|owner>
+  backend apikey revoke -id <key id>
+  backend apikey list`
+
+// APIKeyCLI mints and revokes admin API keys from the command line, it is
+// the only way to create the first key.
+type APIKeyCLI struct {
+	logger *slog.Logger
+	apiKey service.APIKey
+	out    io.Writer
+}
+
+func NewAPIKeyCLI(lg *slog.Logger, apiKey service.APIKey) *APIKeyCLI {
+	return &APIKeyCLI{
+		logger: lg.With("module", "apikey-cli"),
+		apiKey: apiKey,
+		out:    os.Stdout,
+	}
+}
+
+func (a *APIKeyCLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return a.create(ctx, args[1:])
+	case "revoke":
+		return a.revoke(ctx, args[1:])
+	case "list":
+		return a.list(ctx)
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
+
+func (a *APIKeyCLI) create(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := fs.String("name", "", "who or what the key is for")
+	roleName := fs.String("role", "", "viewer, operator, finance or owner")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+	role, ok := entity.ParseAdminRole(*roleName)
+	if !ok {
+		return fmt.Errorf("unknown role %q", *roleName)
+	}
+
+	plaintext, key, err := a.apiKey.Mint(ctx, *name, role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "id:   %s\nrole: %s\nkey:  %s\n\nstore the key now, it cannot be shown again\n",
+		key.ID, key.Role, plaintext)
+	return nil
+}
+
+func (a *APIKeyCLI) revoke(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("apikey revoke", flag.ContinueOnError)
+	id := fs.String("id", "", "id of the key to revoke")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id == "" {
+		return errors.New("-id is required")
+	}
+
+	if err := a.apiKey.Revoke(ctx, *id); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "revoked %s\n", *id)
+	return nil
+}
+
+func (a *APIKeyCLI) list(ctx context.Context) error {
+	keys, err := a.apiKey.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLE\tLAST USED\tREVOKED")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.KeyPrefix, k.Role, formatUnix(k.LastUsedAt), formatUnix(k.RevokedAt))
+	}
+	return w.Flush()
+}
+
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
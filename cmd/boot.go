@@ -1,17 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/routes"
+	"github.com/playture/backend/internal/app/worker"
 	"github.com/playture/backend/internal/infrastructure/godotenv"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
 	"github.com/playture/backend/internal/infrastructure/redis"
-	"log/slog"
+	"github.com/playture/backend/internal/service"
 )
 
+const shutdownTimeout = 15 * time.Second
+
 type Boot struct {
 	env        *godotenv.Env
 	logger     *slog.Logger
 	postgresql *postgresql.Postgres
 	rdis       *redis.Redis
+	router     *routes.Router
+	relay      *worker.OutboxRelay
+	claimer    *worker.JobClaimer
+	delivery   *worker.DeliveryWorker
+	sweeper    *worker.OrderSweeper
+
+	// pipeline runs a claimed job's step, it stays nil until the Veo and
+	// QUE steps are part of this service so the claimer is never started
+	// with a handler that can only fail
+	pipeline worker.JobHandler
 }
 
 func NewBoot(
@@ -19,12 +43,22 @@ func NewBoot(
 	lg *slog.Logger,
 	rd *redis.Redis,
 	pg *postgresql.Postgres,
+	router *routes.Router,
+	relay *worker.OutboxRelay,
+	claimer *worker.JobClaimer,
+	delivery *worker.DeliveryWorker,
+	sweeper *worker.OrderSweeper,
 ) *Boot {
 	return &Boot{
 		env:        e,
 		logger:     lg.With("module", "boot"),
 		postgresql: pg,
 		rdis:       rd,
+		router:     router,
+		relay:      relay,
+		claimer:    claimer,
+		delivery:   delivery,
+		sweeper:    sweeper,
 	}
 }
 
@@ -32,4 +66,39 @@ func (b *Boot) Boot() {
 	lg := b.logger.With("method", "Boot")
 	lg.Info("it's running")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go b.relay.Run(ctx)
+	go b.claimer.Run(ctx, service.PipelineStatuses, b.pipeline)
+	go b.delivery.Run(ctx)
+	go b.sweeper.Run(ctx)
+
+	if b.env.Environment != "development" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	b.router.Setup(engine)
+
+	srv := &http.Server{
+		Addr:    ":" + b.env.HTTPPort,
+		Handler: engine,
+	}
+	go func() {
+		lg.Info("http server listening", "addr", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			lg.Error("http server failed", "err", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	lg.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		lg.Error("http server shutdown failed", "err", err)
+	}
 }
@@ -2,13 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/playture/backend/internal/infrastructure/godotenv"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
 	"github.com/playture/backend/internal/infrastructure/redis"
 	"log"
 	"log/slog"
 	"os"
-	"time"
 )
 
 func main() {
@@ -16,16 +16,25 @@ func main() {
 	logger := initSlogLogger()
 	logger.Info("service started")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), postgresql.SetupTimeout(env))
 	defer cancel()
 
-	pg := postgresql.NewPostgres(env)
+	pg := postgresql.NewPostgres(env, logger)
 	err := pg.Setup(ctx)
 	if err != nil {
 		log.Fatalf("postgresql error %s\n", err)
 	}
 	defer pg.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := wireAPIKeyCLI(env, logger, pg).Run(ctx, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			pg.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
 	rdis := redis.NewRedis(env)
 	err = rdis.Setup(ctx)
 	if err != nil {
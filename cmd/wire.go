@@ -5,9 +5,13 @@ package main
 
 import (
 	"github.com/google/wire"
+	"github.com/playture/backend/internal/app"
 	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/infrastructure/imaging"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
 	"github.com/playture/backend/internal/infrastructure/redis"
+	"github.com/playture/backend/internal/repository"
+	"github.com/playture/backend/internal/service"
 
 	"log/slog"
 )
@@ -19,7 +23,24 @@ func wireApp(
 	rdis *redis.Redis,
 ) *Boot {
 	wire.Build(
+		imaging.NewImaging,
+		repository.ProviderSet,
+		service.ProviderSet,
+		app.ProviderSet,
 		wire.NewSet(NewBoot),
 	)
 	return &Boot{}
 }
+
+func wireAPIKeyCLI(
+	env *godotenv.Env,
+	logger *slog.Logger,
+	postgresql *postgresql.Postgres,
+) *APIKeyCLI {
+	wire.Build(
+		repository.ProviderSet,
+		service.ProviderSet,
+		NewAPIKeyCLI,
+	)
+	return &APIKeyCLI{}
+}
@@ -7,15 +7,85 @@
 package main
 
 import (
+	"github.com/playture/backend/internal/app/api/controllers"
+	"github.com/playture/backend/internal/app/api/middleware"
+	"github.com/playture/backend/internal/app/api/routes"
+	"github.com/playture/backend/internal/app/worker"
 	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/infrastructure/imaging"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
 	"github.com/playture/backend/internal/infrastructure/redis"
+	"github.com/playture/backend/internal/repository/apikey_repository/apikey_pgx"
+	"github.com/playture/backend/internal/repository/audit_repository/audit_pgx"
+	"github.com/playture/backend/internal/repository/delivery_repository/delivery_pgx"
+	"github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	"github.com/playture/backend/internal/repository/job_status_event_repository/job_status_event_pgx"
+	"github.com/playture/backend/internal/repository/mail_repository/mail_postmark"
+	"github.com/playture/backend/internal/repository/order_repository/order_pgx"
+	"github.com/playture/backend/internal/repository/outbox_repository/outbox_pgx"
+	"github.com/playture/backend/internal/repository/payment_repository/payment_stripe"
+	"github.com/playture/backend/internal/repository/product_repository/product_pgx"
+	"github.com/playture/backend/internal/repository/promotion_repository/promotion_pgx"
+	"github.com/playture/backend/internal/repository/refund_repository/refund_pgx"
+	"github.com/playture/backend/internal/repository/storage_repository/storage_cloudfront"
+	"github.com/playture/backend/internal/repository/stream_repository/stream_rueidis"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/internal/service"
 	"log/slog"
 )
 
 // Injectors from wire.go:
 
 func wireApp(env *godotenv.Env, logger *slog.Logger, postgresql2 *postgresql.Postgres, rdis *redis.Redis) *Boot {
-	boot := NewBoot(env, logger, rdis, postgresql2)
+	iuow := uow.NewUOW(postgresql2)
+	auditPgx := auditPGX.NewAuditPgx(logger, postgresql2)
+	audit := service.NewAudit(logger, auditPgx)
+	outboxPgx := outboxPGX.NewOutboxPgx(logger, postgresql2)
+	outbox := service.NewOutbox(logger, outboxPgx)
+	jobPgx := jobPGX.NewJobPgx(logger, postgresql2)
+	orderPgx := order_pgx.NewOrderPgx(logger, postgresql2)
+	productPgx := productPGX.NewProductPgx(logger, postgresql2)
+	deliveryPgx := deliveryPGX.NewDeliveryPgx(logger, postgresql2)
+	production := service.NewProduction(logger, outbox, jobPgx, orderPgx, productPgx, deliveryPgx)
+	jobStatusEventPgx := jobStatusEventPGX.NewJobStatusEventPgx(logger, postgresql2)
+	timeline := service.NewTimeline(logger, iuow, outbox, production, jobPgx, jobStatusEventPgx)
+	admin := service.NewAdmin(logger, iuow, audit, timeline, jobPgx, orderPgx)
+	catalog := service.NewCatalog(logger, iuow, audit, productPgx)
+	promotionPgx := promotionPGX.NewPromotionPgx(logger, postgresql2)
+	promotions := service.NewPromotions(logger, iuow, audit, promotionPgx)
+	refundPgx := refundPGX.NewRefundPgx(logger, postgresql2)
+	paymentStripePaymentStripe := paymentStripe.NewPaymentStripe(logger, env)
+	mailPostmarkMailPostmark := mailPostmark.NewMailPostmark(logger, env)
+	refunds := service.NewRefunds(logger, env, iuow, audit, outbox, orderPgx, refundPgx, deliveryPgx, jobPgx, paymentStripePaymentStripe, mailPostmarkMailPostmark)
+	storageCloudfrontStorageCloudfront := storageCloudfront.NewStorageCloudfront(logger, env)
+	deliveries := service.NewDeliveries(logger, env, iuow, audit, outbox, deliveryPgx, orderPgx, jobPgx, mailPostmarkMailPostmark, storageCloudfrontStorageCloudfront)
+	adminController := controllers.NewAdminController(logger, admin, audit, timeline, catalog, promotions, refunds, deliveries)
+	imagingImaging := imaging.NewImaging(env)
+	job := service.NewJob(logger, imagingImaging, jobPgx, orderPgx, storageCloudfrontStorageCloudfront)
+	jobController := controllers.NewJobController(logger, job)
+	checkout := service.NewCheckout(logger, env, iuow, catalog, promotions, jobPgx, orderPgx)
+	orderController := controllers.NewOrderController(logger, checkout)
+	payments := service.NewPayments(logger, iuow, outbox, production, jobPgx, orderPgx, paymentStripePaymentStripe)
+	webhookController := controllers.NewWebhookController(logger, payments)
+	deliveryController := controllers.NewDeliveryController(logger, deliveries)
+	healthController := controllers.NewHealthController(logger, postgresql2, rdis)
+	apiKeyPgx := apiKeyPGX.NewAPIKeyPgx(logger, postgresql2)
+	apiKey := service.NewAPIKey(logger, apiKeyPgx)
+	adminAuth := middleware.NewAdminAuth(logger, apiKey)
+	router := routes.NewRouter(env, logger, adminController, jobController, orderController, webhookController, deliveryController, healthController, adminAuth)
+	streamRueidisStreamRueidis := streamRueidis.NewStreamRueidis(logger, rdis)
+	outboxRelay := worker.NewOutboxRelay(logger, env, iuow, outboxPgx, streamRueidisStreamRueidis)
+	jobClaimer := worker.NewJobClaimer(logger, env, jobPgx)
+	deliveryWorker := worker.NewDeliveryWorker(logger, env, deliveries)
+	orderExpiry := service.NewOrderExpiry(logger, iuow, outbox, jobPgx, orderPgx, deliveryPgx, paymentStripePaymentStripe, promotionPgx)
+	orderSweeper := worker.NewOrderSweeper(logger, env, orderExpiry, refunds)
+	boot := NewBoot(env, logger, rdis, postgresql2, router, outboxRelay, jobClaimer, deliveryWorker, orderSweeper)
 	return boot
 }
+
+func wireAPIKeyCLI(env *godotenv.Env, logger *slog.Logger, postgresql2 *postgresql.Postgres) *APIKeyCLI {
+	apiKeyPgx := apiKeyPGX.NewAPIKeyPgx(logger, postgresql2)
+	apiKey := service.NewAPIKey(logger, apiKeyPgx)
+	apiKeyCLI := NewAPIKeyCLI(logger, apiKey)
+	return apiKeyCLI
+}
@@ -0,0 +1,438 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/response"
+	"github.com/playture/backend/internal/dto"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	"github.com/playture/backend/internal/service"
+)
+
+type AdminController struct {
+	logger     *slog.Logger
+	admin      service.Admin
+	audit      service.Audit
+	timeline   service.Timeline
+	catalog    service.Catalog
+	promotions service.Promotions
+	refunds    service.Refunds
+	deliveries service.Deliveries
+}
+
+func NewAdminController(
+	logger *slog.Logger,
+	admin service.Admin,
+	audit service.Audit,
+	timeline service.Timeline,
+	catalog service.Catalog,
+	promotions service.Promotions,
+	refunds service.Refunds,
+	deliveries service.Deliveries,
+) *AdminController {
+	return &AdminController{
+		logger:     logger.With("layer", "AdminController"),
+		admin:      admin,
+		audit:      audit,
+		timeline:   timeline,
+		catalog:    catalog,
+		promotions: promotions,
+		refunds:    refunds,
+		deliveries: deliveries,
+	}
+}
+
+func (a *AdminController) ListJobs(c *gin.Context) {
+	var req dto.AdminListJobsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	page, err := a.admin.ListJobs(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "ListJobs", err)
+		return
+	}
+	response.Page(c, page.Items, page.NextCursor, page.PrevCursor, "ok")
+}
+
+func (a *AdminController) ListOrders(c *gin.Context) {
+	var req dto.AdminListOrdersReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	page, err := a.admin.ListOrders(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "ListOrders", err)
+		return
+	}
+	response.Page(c, page.Items, page.NextCursor, page.PrevCursor, "ok")
+}
+
+func (a *AdminController) GetJob(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	detail, err := a.admin.GetJob(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "GetJob", err)
+		return
+	}
+	response.Ok(c, detail, "ok")
+}
+
+func (a *AdminController) JobTimeline(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := a.timeline.Get(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "JobTimeline", err)
+		return
+	}
+	response.Ok(c, timeline, "ok")
+}
+
+func (a *AdminController) StageDurations(c *gin.Context) {
+	var req dto.AdminStageDurationsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	stages, err := a.timeline.StageDurations(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "StageDurations", err)
+		return
+	}
+	response.Ok(c, stages, "ok")
+}
+
+func (a *AdminController) RetryJob(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := a.admin.RetryJob(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "RetryJob", err)
+		return
+	}
+	response.Ok(c, job, "job queued for retry")
+}
+
+func (a *AdminController) CancelJob(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	job, err := a.admin.CancelJob(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "CancelJob", err)
+		return
+	}
+	response.Ok(c, job, "job cancelled")
+}
+
+func (a *AdminController) DeleteJob(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	if err := a.admin.DeleteJob(c.Request.Context(), id); err != nil {
+		a.handleError(c, "DeleteJob", err)
+		return
+	}
+	response.Ok(c, nil, "job deleted")
+}
+
+func (a *AdminController) UpdateOrderNotes(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AdminUpdateOrderNotesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	order, err := a.admin.UpdateOrderNotes(c.Request.Context(), id, req)
+	if err != nil {
+		a.handleError(c, "UpdateOrderNotes", err)
+		return
+	}
+	response.Ok(c, order, "order updated")
+}
+
+func (a *AdminController) RefundOrder(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AdminRefundOrderReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	res, err := a.refunds.RefundOrder(c.Request.Context(), id, req)
+	if errors.Is(err, service.ErrRefundPending) {
+		response.Custom(c, http.StatusAccepted, res, err.Error())
+		return
+	}
+	if err != nil {
+		a.handleError(c, "RefundOrder", err)
+		return
+	}
+	response.Ok(c, res, "order refunded")
+}
+
+func (a *AdminController) ListRefunds(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	refunds, err := a.refunds.ListRefunds(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "ListRefunds", err)
+		return
+	}
+	response.Ok(c, refunds, "ok")
+}
+
+func (a *AdminController) GetDelivery(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := a.deliveries.GetDelivery(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "GetDelivery", err)
+		return
+	}
+	response.Ok(c, delivery, "ok")
+}
+
+func (a *AdminController) RedeliverOrder(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := a.deliveries.Redeliver(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "RedeliverOrder", err)
+		return
+	}
+	response.Ok(c, delivery, "delivery queued")
+}
+
+func (a *AdminController) ListAudit(c *gin.Context) {
+	var req dto.AdminListAuditReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	page, err := a.audit.List(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "ListAudit", err)
+		return
+	}
+	response.Page(c, page.Items, page.NextCursor, page.PrevCursor, "ok")
+}
+
+func (a *AdminController) ListProducts(c *gin.Context) {
+	var req dto.AdminListProductsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	products, err := a.catalog.ListProducts(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "ListProducts", err)
+		return
+	}
+	response.Ok(c, products, "ok")
+}
+
+func (a *AdminController) GetProduct(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	product, err := a.catalog.GetProduct(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "GetProduct", err)
+		return
+	}
+	response.Ok(c, product, "ok")
+}
+
+func (a *AdminController) CreateProduct(c *gin.Context) {
+	var req dto.AdminProductReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	product, err := a.catalog.CreateProduct(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "CreateProduct", err)
+		return
+	}
+	response.Created(c, product)
+}
+
+func (a *AdminController) UpdateProduct(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AdminProductReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	product, err := a.catalog.UpdateProduct(c.Request.Context(), id, req)
+	if err != nil {
+		a.handleError(c, "UpdateProduct", err)
+		return
+	}
+	response.Ok(c, product, "product updated")
+}
+
+func (a *AdminController) DeleteProduct(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	if err := a.catalog.DeleteProduct(c.Request.Context(), id); err != nil {
+		a.handleError(c, "DeleteProduct", err)
+		return
+	}
+	response.Ok(c, nil, "product deleted")
+}
+
+func (a *AdminController) ListPromotions(c *gin.Context) {
+	var req dto.AdminListPromotionsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	page, err := a.promotions.ListPromotions(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "ListPromotions", err)
+		return
+	}
+	response.Page(c, page.Items, page.NextCursor, page.PrevCursor, "ok")
+}
+
+func (a *AdminController) GetPromotion(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	promotion, err := a.promotions.GetPromotion(c.Request.Context(), id)
+	if err != nil {
+		a.handleError(c, "GetPromotion", err)
+		return
+	}
+	response.Ok(c, promotion, "ok")
+}
+
+func (a *AdminController) CreatePromotion(c *gin.Context) {
+	var req dto.AdminPromotionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	promotion, err := a.promotions.CreatePromotion(c.Request.Context(), req)
+	if err != nil {
+		a.handleError(c, "CreatePromotion", err)
+		return
+	}
+	response.Created(c, promotion)
+}
+
+func (a *AdminController) UpdatePromotion(c *gin.Context) {
+	id, ok := idParam(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AdminPromotionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	promotion, err := a.promotions.UpdatePromotion(c.Request.Context(), id, req)
+	if err != nil {
+		a.handleError(c, "UpdatePromotion", err)
+		return
+	}
+	response.Ok(c, promotion, "promotion updated")
+}
+
+// handleError maps service and repository errors onto HTTP responses.
+func (a *AdminController) handleError(c *gin.Context, method string, err error) {
+	switch {
+	case errors.Is(err, jobRepository.ErrJobNotFound),
+		errors.Is(err, orderRepository.ErrOrderNotFound),
+		errors.Is(err, productRepository.ErrProductNotFound),
+		errors.Is(err, promotionRepository.ErrPromotionNotFound),
+		errors.Is(err, deliveryRepository.ErrDeliveryNotFound):
+		response.NotFound(c)
+	case errors.Is(err, service.ErrInvalidFilter),
+		errors.Is(err, pagination.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidProduct),
+		errors.Is(err, service.ErrInvalidPromotion),
+		errors.Is(err, service.ErrInvalidRefund):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, service.ErrJobNotRetryable),
+		errors.Is(err, service.ErrJobNotCancellable),
+		errors.Is(err, service.ErrJobHasOrder),
+		errors.Is(err, productRepository.ErrActiveProductExists),
+		errors.Is(err, promotionRepository.ErrCodeExists),
+		errors.Is(err, service.ErrOrderNotRefundable),
+		errors.Is(err, service.ErrOrderNotDeliverable):
+		response.Custom(c, http.StatusConflict, nil, err.Error())
+	case errors.Is(err, service.ErrRefundFailed):
+		response.Custom(c, http.StatusBadGateway, nil, err.Error())
+	default:
+		a.logger.Error("request failed", "method", method, "err", err)
+		response.InternalError(c)
+	}
+}
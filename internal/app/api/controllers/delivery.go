@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/response"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	"github.com/playture/backend/internal/service"
+)
+
+type DeliveryController struct {
+	logger     *slog.Logger
+	deliveries service.Deliveries
+}
+
+func NewDeliveryController(
+	logger *slog.Logger,
+	deliveries service.Deliveries,
+) *DeliveryController {
+	return &DeliveryController{
+		logger:     logger.With("layer", "DeliveryController"),
+		deliveries: deliveries,
+	}
+}
+
+// Download redirects to a short-lived URL of the render behind the token.
+func (d *DeliveryController) Download(c *gin.Context) {
+	url, err := d.deliveries.Download(c.Request.Context(), c.Param("token"))
+	switch {
+	case err == nil:
+		c.Redirect(http.StatusFound, url)
+	case errors.Is(err, deliveryRepository.ErrDeliveryNotFound):
+		response.NotFound(c)
+	case errors.Is(err, service.ErrDownloadExpired), errors.Is(err, service.ErrOrderNotDeliverable):
+		response.Custom(c, http.StatusGone, nil, err.Error())
+	default:
+		d.logger.Error("request failed", "method", "Download", "err", err)
+		response.InternalError(c)
+	}
+}
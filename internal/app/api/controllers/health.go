@@ -1 +1,58 @@
 package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/response"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/infrastructure/redis"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+type HealthController struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+	redis    *redis.Redis
+}
+
+func NewHealthController(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+	redis *redis.Redis,
+) *HealthController {
+	return &HealthController{
+		logger:   logger.With("layer", "HealthController"),
+		postgres: postgres,
+		redis:    redis,
+	}
+}
+
+type healthReport struct {
+	Postgres map[string]postgresql.PoolStats `json:"postgres"`
+}
+
+// Check answers 200 when every database pool and redis respond, 503
+// otherwise. Pool statistics are included either way.
+func (h *HealthController) Check(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	report := healthReport{Postgres: h.postgres.Stats()}
+
+	if err := h.postgres.HealthCheck(ctx); err != nil {
+		h.logger.Error("postgres unhealthy", "err", err)
+		response.Custom(c, http.StatusServiceUnavailable, report, "postgres unavailable")
+		return
+	}
+	if err := h.redis.HealthCheck(ctx); err != nil {
+		h.logger.Error("redis unhealthy", "err", err)
+		response.Custom(c, http.StatusServiceUnavailable, report, "redis unavailable")
+		return
+	}
+	response.Ok(c, report, "ok")
+}
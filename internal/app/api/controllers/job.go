@@ -1 +1,71 @@
 package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/response"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/infrastructure/imaging"
+	"github.com/playture/backend/internal/service"
+)
+
+type JobController struct {
+	logger *slog.Logger
+	job    service.Job
+}
+
+func NewJobController(
+	logger *slog.Logger,
+	job service.Job,
+) *JobController {
+	return &JobController{
+		logger: logger.With("layer", "JobController"),
+		job:    job,
+	}
+}
+
+// Create takes a multipart form with the photo in "image" and the customer
+// in "email" and "name".
+func (j *JobController) Create(c *gin.Context) {
+	file, err := c.FormFile("image")
+	if err != nil {
+		response.BadRequest(c, "image is required")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		j.handleError(c, "Create", err)
+		return
+	}
+	defer f.Close()
+
+	res, err := j.job.CreateJob(c.Request.Context(), dto.CreateJobReq{
+		UserEmail: c.PostForm("email"),
+		UserName:  c.PostForm("name"),
+		Style:     c.PostForm("style"),
+		Image:     f,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		j.handleError(c, "Create", err)
+		return
+	}
+	response.Created(c, res)
+}
+
+// handleError maps service and imaging errors onto HTTP responses.
+func (j *JobController) handleError(c *gin.Context, method string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidJob), errors.Is(err, imaging.ErrUnsupportedFormat):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		response.Custom(c, http.StatusRequestEntityTooLarge, nil, err.Error())
+	default:
+		j.logger.Error("request failed", "method", method, "err", err)
+		response.InternalError(c)
+	}
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/app/api/response"
+	"github.com/playture/backend/internal/dto"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	"github.com/playture/backend/internal/service"
+)
+
+type OrderController struct {
+	logger   *slog.Logger
+	checkout service.Checkout
+}
+
+func NewOrderController(
+	logger *slog.Logger,
+	checkout service.Checkout,
+) *OrderController {
+	return &OrderController{
+		logger:   logger.With("layer", "OrderController"),
+		checkout: checkout,
+	}
+}
+
+func (o *OrderController) Create(c *gin.Context) {
+	var req dto.CreateOrderReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if _, err := uuid.Parse(req.JobID); err != nil {
+		response.BadRequest(c, "jobId must be a UUID")
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	order, err := o.checkout.CreateOrder(c.Request.Context(), req)
+	if err != nil {
+		o.handleError(c, "Create", err)
+		return
+	}
+	response.Created(c, order)
+}
+
+// handleError maps service and repository errors onto HTTP responses.
+func (o *OrderController) handleError(c *gin.Context, method string, err error) {
+	switch {
+	case errors.Is(err, jobRepository.ErrJobNotFound):
+		response.NotFound(c)
+	case errors.Is(err, service.ErrNotForSale), errors.Is(err, service.ErrPromoNotApplicable),
+		errors.Is(err, service.ErrInvalidDeliveryMethod):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, service.ErrJobNotConvertible), errors.Is(err, service.ErrJobHasOrder):
+		response.Custom(c, http.StatusConflict, nil, err.Error())
+	default:
+		o.logger.Error("request failed", "method", method, "err", err)
+		response.InternalError(c)
+	}
+}
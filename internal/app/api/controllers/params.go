@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/app/api/response"
+)
+
+// idParam returns the :id path parameter when it is a UUID. Anything else
+// cannot name a row, it is answered with 404 before reaching the database.
+func idParam(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		response.NotFound(c)
+		return "", false
+	}
+	return id, true
+}
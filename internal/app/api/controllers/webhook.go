@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/response"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/internal/service"
+)
+
+// maxWebhookBody bounds what is read from a webhook before its signature
+// is checked, Stripe events stay far below it.
+const maxWebhookBody = 1 << 16
+
+type WebhookController struct {
+	logger   *slog.Logger
+	payments service.Payments
+}
+
+func NewWebhookController(
+	logger *slog.Logger,
+	payments service.Payments,
+) *WebhookController {
+	return &WebhookController{
+		logger:   logger.With("layer", "WebhookController"),
+		payments: payments,
+	}
+}
+
+// Stripe receives payment events. The signature covers the raw body, so it
+// is read as is rather than bound.
+func (w *WebhookController) Stripe(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	err = w.payments.HandleWebhook(c.Request.Context(), payload, c.GetHeader("Stripe-Signature"))
+	switch {
+	case err == nil:
+		response.Ok(c, nil, "ok")
+	case errors.Is(err, paymentRepository.ErrInvalidSignature):
+		response.Custom(c, http.StatusBadRequest, nil, err.Error())
+	default:
+		w.logger.Error("request failed", "method", "Stripe", "err", err)
+		response.InternalError(c)
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/service"
+)
+
+// fakeAPIKey authenticates the keys it holds, a key mapped to nil is revoked.
+type fakeAPIKey struct {
+	service.APIKey
+	keys map[string]*entity.AdminAPIKey
+}
+
+func (f fakeAPIKey) Authenticate(ctx context.Context, token string) (*entity.AdminAPIKey, error) {
+	key, ok := f.keys[token]
+	if !ok {
+		return nil, service.ErrInvalidAPIKey
+	}
+	if key == nil {
+		return nil, service.ErrAPIKeyRevoked
+	}
+	return key, nil
+}
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := NewAdminAuth(slog.New(slog.NewTextHandler(io.Discard, nil)), fakeAPIKey{keys: map[string]*entity.AdminAPIKey{
+		"operator": {ID: uuid.New(), Name: "ops", Role: entity.AdminRoleOperator},
+		"finance":  {ID: uuid.New(), Name: "books", Role: entity.AdminRoleFinance},
+		"revoked":  nil,
+	}})
+
+	engine := gin.New()
+	engine.POST("/orders/:id/refund", auth.Authenticate(), auth.Require(entity.PermissionOrdersRefund), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"missing key", "", http.StatusUnauthorized},
+		{"not a bearer token", "operator", http.StatusUnauthorized},
+		{"unknown key", "Bearer nope", http.StatusUnauthorized},
+		{"revoked key", "Bearer revoked", http.StatusUnauthorized},
+		{"missing permission", "Bearer operator", http.StatusForbidden},
+		{"permitted", "Bearer finance", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders/1/refund", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+)
+
+// ReadPrimary sends the reads of a request to the primary. Use it on routes
+// whose callers expect to see what was just written, the replica may not
+// have it yet.
+func ReadPrimary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(postgresql.WithPrimary(c.Request.Context()))
+		c.Next()
+	}
+}
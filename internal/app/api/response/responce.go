@@ -48,3 +48,35 @@ func BadRequest(c *gin.Context, message string) {
 func Pure(c *gin.Context, statusCode int, data any) {
 	c.JSON(statusCode, data)
 }
+
+type Pagination struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+}
+
+type PageResponse[T any] struct {
+	Message    string     `json:"message"`
+	Status     int        `json:"status"`
+	Data       T          `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Page sends a status 200 response for one page of a cursor paginated listing
+func Page(c *gin.Context, data any, nextCursor, prevCursor string, message string) {
+	c.JSON(
+		http.StatusOK,
+		PageResponse[any]{
+			Message: message,
+			Status:  http.StatusOK,
+			Data:    data,
+			Pagination: Pagination{
+				NextCursor: nextCursor,
+				PrevCursor: prevCursor,
+				HasNext:    nextCursor != "",
+				HasPrev:    prevCursor != "",
+			},
+		},
+	)
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/middleware"
+	"github.com/playture/backend/internal/entity"
+)
+
+func (r *Router) adminRoutes(rg *gin.RouterGroup) {
+	rg.Use(r.adminAuth.Authenticate())
+	// writes check the state they change against the primary, and the
+	// refund and delivery views are read back right after a refund or a
+	// redelivery. Everything else may lag behind on the replica.
+	primary := middleware.ReadPrimary()
+
+	jobs := rg.Group("/jobs")
+	jobs.GET("", r.adminAuth.Require(entity.PermissionJobsRead), r.admin.ListJobs)
+	jobs.GET("/:id", r.adminAuth.Require(entity.PermissionJobsRead), r.admin.GetJob)
+	jobs.GET("/:id/timeline", r.adminAuth.Require(entity.PermissionJobsRead), r.admin.JobTimeline)
+	jobs.POST("/:id/retry", r.adminAuth.Require(entity.PermissionJobsWrite), primary, r.admin.RetryJob)
+	jobs.POST("/:id/cancel", r.adminAuth.Require(entity.PermissionJobsWrite), primary, r.admin.CancelJob)
+	jobs.DELETE("/:id", r.adminAuth.Require(entity.PermissionJobsWrite), primary, r.admin.DeleteJob)
+
+	orders := rg.Group("/orders")
+	orders.GET("", r.adminAuth.Require(entity.PermissionOrdersRead), r.admin.ListOrders)
+	orders.PATCH("/:id/notes", r.adminAuth.Require(entity.PermissionOrdersWrite), primary, r.admin.UpdateOrderNotes)
+	orders.GET("/:id/refunds", r.adminAuth.Require(entity.PermissionOrdersRead), primary, r.admin.ListRefunds)
+	orders.POST("/:id/refunds", r.adminAuth.Require(entity.PermissionOrdersRefund), primary, r.admin.RefundOrder)
+	orders.GET("/:id/delivery", r.adminAuth.Require(entity.PermissionOrdersRead), primary, r.admin.GetDelivery)
+	orders.POST("/:id/redeliver", r.adminAuth.Require(entity.PermissionOrdersWrite), primary, r.admin.RedeliverOrder)
+
+	products := rg.Group("/products")
+	products.GET("", r.adminAuth.Require(entity.PermissionOrdersRead), r.admin.ListProducts)
+	products.GET("/:id", r.adminAuth.Require(entity.PermissionOrdersRead), r.admin.GetProduct)
+	products.POST("", r.adminAuth.Require(entity.PermissionCatalogWrite), primary, r.admin.CreateProduct)
+	products.PUT("/:id", r.adminAuth.Require(entity.PermissionCatalogWrite), primary, r.admin.UpdateProduct)
+	products.DELETE("/:id", r.adminAuth.Require(entity.PermissionCatalogWrite), primary, r.admin.DeleteProduct)
+
+	promotions := rg.Group("/promotions")
+	promotions.GET("", r.adminAuth.Require(entity.PermissionOrdersRead), r.admin.ListPromotions)
+	promotions.GET("/:id", r.adminAuth.Require(entity.PermissionOrdersRead), r.admin.GetPromotion)
+	promotions.POST("", r.adminAuth.Require(entity.PermissionCatalogWrite), primary, r.admin.CreatePromotion)
+	promotions.PUT("/:id", r.adminAuth.Require(entity.PermissionCatalogWrite), primary, r.admin.UpdatePromotion)
+
+	rg.GET("/stats/job-stages", r.adminAuth.Require(entity.PermissionJobsRead), r.admin.StageDurations)
+	rg.GET("/audit", r.adminAuth.Require(entity.PermissionAuditRead), r.admin.ListAudit)
+}
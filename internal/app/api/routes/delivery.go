@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/middleware"
+)
+
+func (r *Router) deliveryRoutes(rg *gin.RouterGroup) {
+	// links are opened right after the delivery that issued them committed
+	rg.GET("/:token", middleware.ReadPrimary(), r.delivery.Download)
+}
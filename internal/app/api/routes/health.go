@@ -1 +1,9 @@
 package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) healthRoutes(rg *gin.RouterGroup) {
+	rg.GET("", r.health.Check)
+}
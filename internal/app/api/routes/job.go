@@ -1 +1,9 @@
 package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) jobRoutes(rg *gin.RouterGroup) {
+	rg.POST("", r.job.Create)
+}
@@ -0,0 +1,9 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) orderRoutes(rg *gin.RouterGroup) {
+	rg.POST("", r.order.Create)
+}
@@ -1 +1,56 @@
 package routes
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/playture/backend/internal/app/api/controllers"
+	"github.com/playture/backend/internal/app/api/middleware"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+)
+
+type Router struct {
+	env       *godotenv.Env
+	logger    *slog.Logger
+	admin     *controllers.AdminController
+	job       *controllers.JobController
+	order     *controllers.OrderController
+	webhook   *controllers.WebhookController
+	delivery  *controllers.DeliveryController
+	health    *controllers.HealthController
+	adminAuth *middleware.AdminAuth
+}
+
+func NewRouter(
+	env *godotenv.Env,
+	logger *slog.Logger,
+	admin *controllers.AdminController,
+	job *controllers.JobController,
+	order *controllers.OrderController,
+	webhook *controllers.WebhookController,
+	delivery *controllers.DeliveryController,
+	health *controllers.HealthController,
+	adminAuth *middleware.AdminAuth,
+) *Router {
+	return &Router{
+		env:       env,
+		logger:    logger.With("layer", "Router"),
+		admin:     admin,
+		job:       job,
+		order:     order,
+		webhook:   webhook,
+		delivery:  delivery,
+		health:    health,
+		adminAuth: adminAuth,
+	}
+}
+
+// Setup registers every route group on the engine.
+func (r *Router) Setup(engine *gin.Engine) {
+	r.healthRoutes(engine.Group("/health"))
+	r.jobRoutes(engine.Group("/jobs"))
+	r.orderRoutes(engine.Group("/orders"))
+	r.webhookRoutes(engine.Group("/webhooks"))
+	r.deliveryRoutes(engine.Group("/downloads"))
+	r.adminRoutes(engine.Group("/admin"))
+}
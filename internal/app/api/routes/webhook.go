@@ -0,0 +1,9 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Router) webhookRoutes(rg *gin.RouterGroup) {
+	rg.POST("/stripe", r.webhook.Stripe)
+}
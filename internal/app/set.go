@@ -1 +1,24 @@
 package app
+
+import (
+	"github.com/google/wire"
+	"github.com/playture/backend/internal/app/api/controllers"
+	"github.com/playture/backend/internal/app/api/middleware"
+	"github.com/playture/backend/internal/app/api/routes"
+	"github.com/playture/backend/internal/app/worker"
+)
+
+var ProviderSet = wire.NewSet(
+	controllers.NewAdminController,
+	controllers.NewHealthController,
+	controllers.NewJobController,
+	controllers.NewOrderController,
+	controllers.NewWebhookController,
+	controllers.NewDeliveryController,
+	middleware.NewAdminAuth,
+	routes.NewRouter,
+	worker.NewOutboxRelay,
+	worker.NewJobClaimer,
+	worker.NewDeliveryWorker,
+	worker.NewOrderSweeper,
+)
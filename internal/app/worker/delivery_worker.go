@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/service"
+)
+
+const (
+	defaultDeliveryPollInterval = 5 * time.Second
+	deliveryBatchSize           = 10
+)
+
+// DeliveryWorker hands out the deliveries queued when production jobs
+// complete or admins ask for a re-delivery.
+type DeliveryWorker struct {
+	logger       *slog.Logger
+	deliveries   service.Deliveries
+	pollInterval time.Duration
+}
+
+func NewDeliveryWorker(
+	logger *slog.Logger,
+	env *godotenv.Env,
+	deliveries service.Deliveries,
+) *DeliveryWorker {
+	w := &DeliveryWorker{
+		logger:       logger.With("layer", "DeliveryWorker"),
+		deliveries:   deliveries,
+		pollInterval: defaultDeliveryPollInterval,
+	}
+	if v, err := strconv.Atoi(env.DeliveryPollIntervalMS); err == nil && v > 0 {
+		w.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	return w
+}
+
+// Run delivers until ctx is cancelled.
+func (w *DeliveryWorker) Run(ctx context.Context) {
+	lg := w.logger.With("method", "Run")
+	lg.Info("delivery worker started", "pollInterval", w.pollInterval)
+
+	poll := time.NewTicker(w.pollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			lg.Info("delivery worker stopped")
+			return
+		case <-poll.C:
+			// drain the backlog before waiting for the next tick
+			for ctx.Err() == nil {
+				n, err := w.deliveries.DeliverPending(ctx, deliveryBatchSize)
+				if err != nil {
+					lg.Error("delivery batch failed", "err", err)
+					break
+				}
+				if n < deliveryBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+)
+
+const (
+	defaultJobLease          = 5 * time.Minute
+	defaultJobClaimPollDelay = 2 * time.Second
+	defaultJobRetryDelay     = time.Minute
+
+	// releaseTimeout bounds giving a lease back after ctx was cancelled
+	releaseTimeout = 5 * time.Second
+)
+
+// JobHandler runs the pipeline step for a claimed job. Its ctx is cancelled
+// when the lease is lost, the handler must stop without writing anything
+// further since another worker owns the job by then. A job that cannot
+// succeed should be moved to a terminal status rather than failed again.
+type JobHandler func(ctx context.Context, job *entity.Job) error
+
+// JobClaimer feeds jobs to a handler straight from the jobs table, for
+// deployments that run without the Redis queue. Any number of claimers can
+// run side by side, each job is held by one of them at a time. It only runs
+// when JOB_CLAIMER_ENABLED is set.
+type JobClaimer struct {
+	logger       *slog.Logger
+	enabled      bool
+	jobRepo      jobRepository.Repository
+	workerID     string
+	lease        time.Duration
+	pollInterval time.Duration
+	retryDelay   time.Duration
+}
+
+func NewJobClaimer(
+	logger *slog.Logger,
+	env *godotenv.Env,
+	jobRepo jobRepository.Repository,
+) *JobClaimer {
+	c := &JobClaimer{
+		logger:       logger.With("layer", "JobClaimer"),
+		jobRepo:      jobRepo,
+		workerID:     newWorkerID(),
+		lease:        defaultJobLease,
+		pollInterval: defaultJobClaimPollDelay,
+		retryDelay:   defaultJobRetryDelay,
+	}
+	c.enabled, _ = strconv.ParseBool(env.JobClaimerEnabled)
+	if v, err := strconv.Atoi(env.JobLeaseSeconds); err == nil && v > 0 {
+		c.lease = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(env.JobClaimPollIntervalMS); err == nil && v > 0 {
+		c.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(env.JobRetryDelaySeconds); err == nil && v > 0 {
+		c.retryDelay = time.Duration(v) * time.Second
+	}
+	return c
+}
+
+// Run claims jobs in one of statuses and hands them to handle, one at a
+// time, until ctx is cancelled. It returns straight away when the claimer
+// is disabled or there is no handler, a claimed job would only fail.
+func (c *JobClaimer) Run(ctx context.Context, statuses []entity.JobStatus, handle JobHandler) {
+	lg := c.logger.With("method", "Run", "workerID", c.workerID)
+	if !c.enabled {
+		lg.Info("job claimer disabled")
+		return
+	}
+	if handle == nil {
+		lg.Error("job claimer enabled without a pipeline step handler, not started")
+		return
+	}
+	lg.Info("job claimer started", "statuses", statuses, "lease", c.lease)
+
+	for {
+		job, err := c.jobRepo.ClaimNext(ctx, statuses, c.workerID, c.lease)
+		if err == nil {
+			c.process(ctx, job, handle)
+			continue
+		}
+		if !errors.Is(err, jobRepository.ErrNoClaimableJob) && ctx.Err() == nil {
+			lg.Error("claim failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			lg.Info("job claimer stopped")
+			return
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// process runs handle while renewing the lease in the background, and gives
+// the lease back once handle returns. When handle fails the lease is kept
+// for retryDelay instead, so the job is not claimed again straight away.
+func (c *JobClaimer) process(ctx context.Context, job *entity.Job, handle JobHandler) {
+	lg := c.logger.With("method", "process", "jobID", job.ID, "status", job.Status)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		c.renew(jobCtx, cancel, job.ID.String())
+	}()
+
+	handleErr := handle(jobCtx, job)
+	cancel()
+	<-renewed
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancelRelease()
+	var err error
+	if handleErr != nil {
+		lg.Error("handler failed", "err", handleErr, "retryIn", c.retryDelay)
+		err = c.jobRepo.RenewLease(releaseCtx, job.ID.String(), c.workerID, c.retryDelay)
+	} else {
+		err = c.jobRepo.ReleaseLease(releaseCtx, job.ID.String(), c.workerID)
+	}
+	if err != nil && !errors.Is(err, jobRepository.ErrLeaseLost) {
+		// the lease runs out on its own, the job is only picked up later
+		lg.Error("release lease failed", "err", err)
+	}
+}
+
+// renew extends the lease every third of its length until ctx is done. When
+// the lease is lost it cancels the handler through lose.
+func (c *JobClaimer) renew(ctx context.Context, lose context.CancelFunc, jobID string) {
+	lg := c.logger.With("method", "renew", "jobID", jobID)
+
+	ticker := time.NewTicker(c.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := c.jobRepo.RenewLease(ctx, jobID, c.workerID, c.lease)
+			switch {
+			case err == nil:
+			case errors.Is(err, jobRepository.ErrLeaseLost):
+				lg.Warn("lease lost, abandoning job")
+				lose()
+				return
+			case ctx.Err() == nil:
+				// a later tick may still get through before the lease ends
+				lg.Error("renew lease failed", "err", err)
+			}
+		}
+	}
+}
+
+// newWorkerID names this process in lease_owner, unique across restarts so
+// a restarted worker does not renew leases of its previous run.
+func newWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
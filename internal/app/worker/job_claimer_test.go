@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+)
+
+var received = []entity.JobStatus{entity.JobStatusReceived}
+
+func newTestClaimer(t *testing.T) (*JobClaimer, *jobMemory.JobMemory, *entity.Job) {
+	t.Helper()
+	repo := jobMemory.NewJobMemory()
+	c := NewJobClaimer(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{JobRetryDelaySeconds: "3600"}, repo)
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+	if _, err := repo.Create(ctx, &entity.Job{Status: entity.JobStatusReceived, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	job, err := repo.ClaimNext(ctx, received, c.workerID, c.lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, repo, job
+}
+
+func TestJobClaimerKeepsLeaseAfterFailure(t *testing.T) {
+	c, repo, job := newTestClaimer(t)
+	ctx := context.Background()
+
+	c.process(ctx, job, func(ctx context.Context, job *entity.Job) error { return errors.New("boom") })
+
+	if _, err := repo.ClaimNext(ctx, received, "other", time.Minute); !errors.Is(err, jobRepository.ErrNoClaimableJob) {
+		t.Fatalf("ClaimNext after a failure = %v, want ErrNoClaimableJob", err)
+	}
+	got, err := repo.FindByField(ctx, "id", job.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LeaseOwner != c.workerID || got.LeaseExpiresAt < time.Now().Add(59*time.Minute).Unix() {
+		t.Errorf("lease = %s until %d, want %s for the retry delay", got.LeaseOwner, got.LeaseExpiresAt, c.workerID)
+	}
+}
+
+func TestJobClaimerReleasesLeaseAfterSuccess(t *testing.T) {
+	c, repo, job := newTestClaimer(t)
+	ctx := context.Background()
+
+	c.process(ctx, job, func(ctx context.Context, job *entity.Job) error { return nil })
+
+	if _, err := repo.ClaimNext(ctx, received, "other", time.Minute); err != nil {
+		t.Fatalf("ClaimNext after a success = %v, want the job", err)
+	}
+}
+
+func TestJobClaimerRunsOnlyWhenEnabledWithHandler(t *testing.T) {
+	tests := map[string]struct {
+		enabled string
+		handle  JobHandler
+	}{
+		"disabled":   {"", func(ctx context.Context, job *entity.Job) error { return errors.New("claimed") }},
+		"no handler": {"true", nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := jobMemory.NewJobMemory()
+			c := NewJobClaimer(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{JobClaimerEnabled: tt.enabled}, repo)
+			ctx := context.Background()
+			now := time.Now().Unix()
+			if _, err := repo.Create(ctx, &entity.Job{Status: entity.JobStatusReceived, CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Run must return on its own, a claimer that started would poll
+			// until ctx is cancelled
+			c.Run(ctx, received, tt.handle)
+
+			if _, err := repo.ClaimNext(ctx, received, "other", time.Minute); err != nil {
+				t.Fatalf("ClaimNext = %v, want the job left unclaimed", err)
+			}
+		})
+	}
+}
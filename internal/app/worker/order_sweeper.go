@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/service"
+)
+
+const (
+	defaultSweepInterval = time.Minute
+	sweepBatchSize       = 50
+)
+
+// OrderSweeper expires orders left unpaid past their payment window,
+// revokes the downloads of delivered orders past their retention window and
+// retries refunds Stripe did not confirm.
+type OrderSweeper struct {
+	logger   *slog.Logger
+	expiry   service.OrderExpiry
+	refunds  service.Refunds
+	interval time.Duration
+}
+
+func NewOrderSweeper(
+	logger *slog.Logger,
+	env *godotenv.Env,
+	expiry service.OrderExpiry,
+	refunds service.Refunds,
+) *OrderSweeper {
+	w := &OrderSweeper{
+		logger:   logger.With("layer", "OrderSweeper"),
+		expiry:   expiry,
+		refunds:  refunds,
+		interval: defaultSweepInterval,
+	}
+	if v, err := strconv.Atoi(env.OrderSweepIntervalSeconds); err == nil && v > 0 {
+		w.interval = time.Duration(v) * time.Second
+	}
+	return w
+}
+
+// Run sweeps until ctx is cancelled. Each tick handles one batch of each
+// kind, orders skipped in a batch would be listed again by an immediate
+// retry, so the rest waits for the next tick.
+func (w *OrderSweeper) Run(ctx context.Context) {
+	lg := w.logger.With("method", "Run")
+	lg.Info("order sweeper started", "interval", w.interval)
+
+	tick := time.NewTicker(w.interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			lg.Info("order sweeper stopped")
+			return
+		case <-tick.C:
+			if n, err := w.expiry.ExpireUnpaid(ctx, sweepBatchSize); err != nil {
+				lg.Error("expiring unpaid orders failed", "err", err)
+			} else if n > 0 {
+				lg.Info("unpaid orders expired", "count", n)
+			}
+			if n, err := w.expiry.RevokeExpired(ctx, sweepBatchSize); err != nil {
+				lg.Error("revoking expired downloads failed", "err", err)
+			} else if n > 0 {
+				lg.Info("expired downloads revoked", "count", n)
+			}
+			if n, err := w.refunds.RetryPending(ctx, sweepBatchSize); err != nil {
+				lg.Error("retrying pending refunds failed", "err", err)
+			} else if n > 0 {
+				lg.Info("pending refunds settled", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	outboxRepository "github.com/playture/backend/internal/repository/outbox_repository"
+	streamRepository "github.com/playture/backend/internal/repository/stream_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxRetention    = 7 * 24 * time.Hour
+
+	outboxPruneInterval = time.Hour
+	outboxTxTimeout     = 30 * time.Second
+)
+
+// OutboxRelay publishes committed outbox rows to Redis streams. Delivery is
+// at least once: a crash between XADD and the commit that marks the row
+// publishes it again, consumers dedupe on the outboxId field.
+type OutboxRelay struct {
+	logger       *slog.Logger
+	uow          uow.IUOW
+	outboxRepo   outboxRepository.Repository
+	streamRepo   streamRepository.Repository
+	pollInterval time.Duration
+	batchSize    int
+	retention    time.Duration
+}
+
+func NewOutboxRelay(
+	logger *slog.Logger,
+	env *godotenv.Env,
+	uow uow.IUOW,
+	outboxRepo outboxRepository.Repository,
+	streamRepo streamRepository.Repository,
+) *OutboxRelay {
+	r := &OutboxRelay{
+		logger:       logger.With("layer", "OutboxRelay"),
+		uow:          uow,
+		outboxRepo:   outboxRepo,
+		streamRepo:   streamRepo,
+		pollInterval: defaultOutboxPollInterval,
+		batchSize:    defaultOutboxBatchSize,
+		retention:    defaultOutboxRetention,
+	}
+	if v, err := strconv.Atoi(env.OutboxPollIntervalMS); err == nil && v > 0 {
+		r.pollInterval = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(env.OutboxBatchSize); err == nil && v > 0 {
+		r.batchSize = v
+	}
+	if v, err := strconv.Atoi(env.OutboxRetentionHours); err == nil && v > 0 {
+		r.retention = time.Duration(v) * time.Hour
+	}
+	return r
+}
+
+// Run relays until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	lg := r.logger.With("method", "Run")
+	lg.Info("outbox relay started", "pollInterval", r.pollInterval, "batchSize", r.batchSize)
+
+	poll := time.NewTicker(r.pollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(outboxPruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			lg.Info("outbox relay stopped")
+			return
+		case <-poll.C:
+			// drain the backlog before waiting for the next tick
+			for ctx.Err() == nil {
+				n, err := r.relayBatch(ctx)
+				if err != nil {
+					lg.Error("relay batch failed", "err", err)
+					break
+				}
+				if n < r.batchSize {
+					break
+				}
+			}
+		case <-prune.C:
+			r.prune(ctx)
+		}
+	}
+}
+
+// relayBatch publishes one batch and returns how many rows it claimed.
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	return uow.Do(ctx, r.uow, func(ctx context.Context) (int, error) {
+		msgs, err := r.outboxRepo.ClaimPending(ctx, r.batchSize)
+		if err != nil {
+			return 0, err
+		}
+
+		delivered := make([]int64, 0, len(msgs))
+		for _, msg := range msgs {
+			if _, err := r.streamRepo.Publish(ctx, msg.Topic, streamFields(msg)); err != nil {
+				// keep the rest for the next tick, redis is most likely down
+				if markErr := r.outboxRepo.MarkFailed(ctx, msg.ID, err.Error()); markErr != nil {
+					return 0, markErr
+				}
+				break
+			}
+			delivered = append(delivered, msg.ID)
+		}
+
+		if len(delivered) > 0 {
+			if err := r.outboxRepo.MarkDelivered(ctx, delivered, time.Now().Unix()); err != nil {
+				return 0, err
+			}
+		}
+		if len(delivered) < len(msgs) {
+			// stop draining, the failed message is retried on the next tick
+			return 0, nil
+		}
+		return len(msgs), nil
+	}, outboxTxTimeout)
+}
+
+func (r *OutboxRelay) prune(ctx context.Context) {
+	lg := r.logger.With("method", "prune")
+
+	before := time.Now().Add(-r.retention).Unix()
+	n, err := r.outboxRepo.Prune(ctx, before)
+	if err != nil {
+		lg.Error("prune failed", "err", err)
+		return
+	}
+	if n > 0 {
+		lg.Info("pruned delivered outbox messages", "count", n)
+	}
+}
+
+func streamFields(msg *entity.OutboxMessage) map[string]string {
+	return map[string]string{
+		"outboxId":      strconv.FormatInt(msg.ID, 10),
+		"eventType":     msg.EventType,
+		"aggregateType": msg.AggregateType,
+		"aggregateId":   msg.AggregateID,
+		"payload":       string(msg.Payload),
+		"createdAt":     strconv.FormatInt(msg.CreatedAt, 10),
+	}
+}
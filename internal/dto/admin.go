@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"github.com/playture/backend/internal/entity"
+)
+
+type AdminListJobsReq struct {
+	Statuses []string `form:"status"`
+	Email    string   `form:"email"`
+	Style    string   `form:"style"`
+	From     int64    `form:"from"` // unix seconds, inclusive
+	To       int64    `form:"to"`   // unix seconds, exclusive
+	Cursor   string   `form:"cursor"`
+	Limit    int      `form:"limit"`
+}
+
+type AdminListOrdersReq struct {
+	PaymentStatus    string `form:"paymentStatus"`
+	ProductionStatus string `form:"productionStatus"`
+	Cursor           string `form:"cursor"`
+	Limit            int    `form:"limit"`
+}
+
+// AdminJobDetail is the full job row, including error and moderation data,
+// together with the order it was converted to, if any.
+type AdminJobDetail struct {
+	Job   *entity.Job   `json:"job"`
+	Order *entity.Order `json:"order,omitempty"`
+}
+
+type AdminListAuditReq struct {
+	EntityType string `form:"entityType"`
+	EntityID   string `form:"entityId"`
+	ActorID    string `form:"actorId"`
+	Cursor     string `form:"cursor"`
+	Limit      int    `form:"limit"`
+}
+
+type AdminUpdateOrderNotesReq struct {
+	CustomerNotes string `json:"customerNotes"`
+}
+
+type AdminStageDurationsReq struct {
+	From int64 `form:"from"` // unix seconds, inclusive
+	To   int64 `form:"to"`   // unix seconds, exclusive
+}
+
+// StageSpan is one stretch of time a job spent in a status. LeftAt and
+// Seconds are zero while the job is still in it.
+type StageSpan struct {
+	Status    entity.JobStatus `json:"status"`
+	EnteredAt int64            `json:"enteredAt"`
+	LeftAt    int64            `json:"leftAt,omitempty"`
+	Seconds   int64            `json:"seconds"`
+}
+
+type JobTimeline struct {
+	Events              []*entity.JobStatusEvent `json:"events"`
+	Stages              []StageSpan              `json:"stages"`
+	TotalProcessingTime int64                    `json:"totalProcessingTime"`
+}
+
+type AdminListProductsReq struct {
+	IncludeInactive bool `form:"includeInactive"`
+}
+
+// AdminProductPrice is one price of a product. Amount is in major units of
+// Currency, "19.99" for USD or "2500" for JPY.
+type AdminProductPrice struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type AdminProductReq struct {
+	OrderType          entity.OrderType    `json:"orderType"`
+	Name               string              `json:"name"`
+	VideoWidth         int                 `json:"videoWidth"`
+	VideoHeight        int                 `json:"videoHeight"`
+	MaxDurationSeconds int                 `json:"maxDurationSeconds"`
+	WatermarkFree      bool                `json:"watermarkFree"`
+	Active             bool                `json:"active"`
+	Prices             []AdminProductPrice `json:"prices"`
+}
+
+type AdminListPromotionsReq struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+// AdminPromotionReq creates or replaces a discount code. Percent codes set
+// PercentOff, fixed codes set AmountOff in major units of Currency. Zero
+// times and limits leave them open, no OrderTypes means every type.
+type AdminPromotionReq struct {
+	Code           string               `json:"code"`
+	Kind           entity.PromotionKind `json:"kind"`
+	PercentOff     int                  `json:"percentOff"`
+	AmountOff      string               `json:"amountOff"`
+	Currency       string               `json:"currency"`
+	StartsAt       int64                `json:"startsAt"`
+	EndsAt         int64                `json:"endsAt"`
+	MaxRedemptions int                  `json:"maxRedemptions"`
+	MaxPerEmail    int                  `json:"maxPerEmail"`
+	OrderTypes     []entity.OrderType   `json:"orderTypes"`
+	Active         bool                 `json:"active"`
+}
+
+// AdminRefundOrderReq refunds Amount, in major units of the order
+// currency, or whatever is left to refund when Amount is empty.
+type AdminRefundOrderReq struct {
+	Amount string `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type RefundOrderRes struct {
+	Refund *entity.Refund `json:"refund"`
+	Order  *entity.Order  `json:"order"`
+}
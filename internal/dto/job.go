@@ -1,7 +1,23 @@
 package dto
 
+import (
+	"io"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+// CreateJobReq is a sample request with the customer's photo. Image is read
+// from the multipart upload, not from JSON.
 type CreateJobReq struct {
+	UserEmail string    `json:"email"`
+	UserName  string    `json:"name"`
+	Style     string    `json:"style"` // "default" when empty
+	Image     io.Reader `json:"-"`
+	IPAddress string    `json:"-"`
+	UserAgent string    `json:"-"`
 }
 
 type CreateJobRes struct {
+	ID     string           `json:"id"`
+	Status entity.JobStatus `json:"status"`
 }
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"github.com/playture/backend/internal/entity"
+)
+
+// CreateOrderReq turns a finished sample job into an order. It carries no
+// amount, the price comes from the product catalog.
+type CreateOrderReq struct {
+	JobID          string                `json:"jobId"`
+	OrderType      entity.OrderType      `json:"orderType"`
+	Currency       string                `json:"currency"` // ISO 4217, USD when empty
+	Requirements   string                `json:"requirements"`
+	PromoCode      string                `json:"promoCode"`
+	DeliveryMethod entity.DeliveryMethod `json:"deliveryMethod"` // DOWNLOAD when empty
+	IPAddress      string                `json:"-"`
+	UserAgent      string                `json:"-"`
+}
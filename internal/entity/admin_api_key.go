@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type AdminRole uint8
+
+const (
+	AdminRoleViewer   AdminRole = 1
+	AdminRoleOperator AdminRole = 2
+	AdminRoleFinance  AdminRole = 3
+	AdminRoleOwner    AdminRole = 4
+)
+
+func (r AdminRole) String() string {
+	switch r {
+	case AdminRoleViewer:
+		return "VIEWER"
+	case AdminRoleOperator:
+		return "OPERATOR"
+	case AdminRoleFinance:
+		return "FINANCE"
+	case AdminRoleOwner:
+		return "OWNER"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseAdminRole accepts the name returned by String in any case.
+func ParseAdminRole(s string) (AdminRole, bool) {
+	for r := AdminRoleViewer; r <= AdminRoleOwner; r++ {
+		if strings.EqualFold(s, r.String()) {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+type Permission string
+
+const (
+	PermissionJobsRead     Permission = "jobs:read"
+	PermissionJobsWrite    Permission = "jobs:write"
+	PermissionOrdersRead   Permission = "orders:read"
+	PermissionOrdersWrite  Permission = "orders:write"
+	PermissionOrdersRefund Permission = "orders:refund"
+	PermissionAuditRead    Permission = "audit:read"
+	PermissionCatalogWrite Permission = "catalog:write"
+)
+
+var rolePermissions = map[AdminRole][]Permission{
+	AdminRoleViewer: {
+		PermissionJobsRead, PermissionOrdersRead,
+	},
+	AdminRoleOperator: {
+		PermissionJobsRead, PermissionJobsWrite, PermissionOrdersRead, PermissionOrdersWrite,
+	},
+	AdminRoleFinance: {
+		PermissionJobsRead, PermissionOrdersRead, PermissionOrdersRefund, PermissionCatalogWrite,
+	},
+	AdminRoleOwner: {
+		PermissionJobsRead, PermissionJobsWrite, PermissionOrdersRead, PermissionOrdersWrite, PermissionOrdersRefund,
+		PermissionAuditRead, PermissionCatalogWrite,
+	},
+}
+
+func (r AdminRole) Can(p Permission) bool {
+	return slices.Contains(rolePermissions[r], p)
+}
+
+type AdminAPIKey struct {
+	ID         uuid.UUID `json:"id" bson:"_id"`
+	Name       string    `json:"name" bson:"name"`
+	KeyPrefix  string    `json:"keyPrefix" bson:"keyPrefix"`
+	KeyHash    string    `json:"-" bson:"keyHash"`
+	Role       AdminRole `json:"role" bson:"role"`
+	LastUsedAt int64     `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	RevokedAt  int64     `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	CreatedAt  int64     `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  int64     `json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAdminRoleCan(t *testing.T) {
+	all := []Permission{
+		PermissionJobsRead, PermissionJobsWrite, PermissionOrdersRead, PermissionOrdersWrite,
+		PermissionOrdersRefund, PermissionAuditRead, PermissionCatalogWrite,
+	}
+	tests := []struct {
+		role AdminRole
+		can  []Permission
+	}{
+		{AdminRoleViewer, []Permission{PermissionJobsRead, PermissionOrdersRead}},
+		{AdminRoleOperator, []Permission{PermissionJobsRead, PermissionJobsWrite, PermissionOrdersRead, PermissionOrdersWrite}},
+		{AdminRoleFinance, []Permission{PermissionJobsRead, PermissionOrdersRead, PermissionOrdersRefund, PermissionCatalogWrite}},
+		{AdminRoleOwner, all},
+		{AdminRole(0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role.String(), func(t *testing.T) {
+			for _, p := range all {
+				if want := slices.Contains(tt.can, p); tt.role.Can(p) != want {
+					t.Errorf("%s.Can(%s) = %v, want %v", tt.role, p, !want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminRoleRefund(t *testing.T) {
+	if AdminRoleOperator.Can(PermissionOrdersRefund) {
+		t.Error("operator can refund orders")
+	}
+	if !AdminRoleFinance.Can(PermissionOrdersRefund) {
+		t.Error("finance cannot refund orders")
+	}
+}
+
+func TestParseAdminRole(t *testing.T) {
+	for r := AdminRoleViewer; r <= AdminRoleOwner; r++ {
+		if got, ok := ParseAdminRole(r.String()); !ok || got != r {
+			t.Errorf("ParseAdminRole(%q) = %s, %v", r.String(), got, ok)
+		}
+	}
+	if got, ok := ParseAdminRole("finance"); !ok || got != AdminRoleFinance {
+		t.Errorf("ParseAdminRole(finance) = %s, %v", got, ok)
+	}
+	if _, ok := ParseAdminRole("root"); ok {
+		t.Error("ParseAdminRole(root) accepted an unknown role")
+	}
+}
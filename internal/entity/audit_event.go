@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditActionJobRetry         AuditAction = "job.retry"
+	AuditActionJobCancel        AuditAction = "job.cancel"
+	AuditActionJobDelete        AuditAction = "job.delete"
+	AuditActionOrderNotesUpdate AuditAction = "order.notes.update"
+	AuditActionOrderRefund      AuditAction = "order.refund"
+	AuditActionOrderRedeliver   AuditAction = "order.redeliver"
+	AuditActionProductCreate    AuditAction = "product.create"
+	AuditActionProductUpdate    AuditAction = "product.update"
+	AuditActionProductDelete    AuditAction = "product.delete"
+	AuditActionPromotionCreate  AuditAction = "promotion.create"
+	AuditActionPromotionUpdate  AuditAction = "promotion.update"
+)
+
+type AuditEntityType string
+
+const (
+	AuditEntityJob       AuditEntityType = "job"
+	AuditEntityOrder     AuditEntityType = "order"
+	AuditEntityProduct   AuditEntityType = "product"
+	AuditEntityPromotion AuditEntityType = "promotion"
+)
+
+// FieldChange is the before and after value of a single changed field.
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditActor is whoever performed a privileged action.
+type AuditActor struct {
+	ID        *uuid.UUID `json:"id,omitempty"`
+	Name      string     `json:"name"`
+	IPAddress string     `json:"ipAddress,omitempty"`
+}
+
+type AuditEvent struct {
+	ID         uuid.UUID              `json:"id" bson:"_id"`
+	ActorID    *uuid.UUID             `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	ActorName  string                 `json:"actorName" bson:"actorName"`
+	IPAddress  string                 `json:"ipAddress,omitempty" bson:"ipAddress,omitempty"`
+	Action     AuditAction            `json:"action" bson:"action"`
+	EntityType AuditEntityType        `json:"entityType" bson:"entityType"`
+	EntityID   string                 `json:"entityId" bson:"entityId"`
+	Changes    map[string]FieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	CreatedAt  int64                  `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type DeliveryStatus uint8
+
+const (
+	DeliveryStatusPending   DeliveryStatus = 1
+	DeliveryStatusDelivered DeliveryStatus = 2
+	DeliveryStatusFailed    DeliveryStatus = 3
+	// DeliveryStatusRevoked ends a delivery whose retention window passed,
+	// its download token no longer works.
+	DeliveryStatusRevoked DeliveryStatus = 4
+	// DeliveryStatusSending marks a delivery a worker claimed and is
+	// sending, it is claimed again when the attempt never got recorded.
+	DeliveryStatusSending DeliveryStatus = 5
+)
+
+func (d DeliveryStatus) String() string {
+	switch d {
+	case DeliveryStatusPending:
+		return "PENDING"
+	case DeliveryStatusDelivered:
+		return "DELIVERED"
+	case DeliveryStatusFailed:
+		return "FAILED"
+	case DeliveryStatusRevoked:
+		return "REVOKED"
+	case DeliveryStatusSending:
+		return "SENDING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Delivery hands the production render of an order to the customer, as a
+// download link, an email or both depending on the order's DeliveryMethod.
+type Delivery struct {
+	OrderID          uuid.UUID      `json:"orderId" bson:"orderId"`
+	Status           DeliveryStatus `json:"status" bson:"status"`
+	Token            string         `json:"-" bson:"token,omitempty"`
+	DownloadCount    int            `json:"downloadCount" bson:"downloadCount"`
+	LastDownloadedAt int64          `json:"lastDownloadedAt,omitempty" bson:"lastDownloadedAt,omitempty"`
+	EmailSentAt      int64          `json:"emailSentAt,omitempty" bson:"emailSentAt,omitempty"`
+	Attempts         int            `json:"attempts" bson:"attempts"`
+	LastError        string         `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	DeliveredAt      int64          `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+	CreatedAt        int64          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        int64          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// SendsEmail reports whether orders delivered this way get an email.
+func (d DeliveryMethod) SendsEmail() bool {
+	return d == DeliveryMethodEmail || d == DeliveryMethodBoth
+}
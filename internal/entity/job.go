@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"strconv"
+
 	"github.com/google/uuid"
 )
 
@@ -43,6 +45,29 @@ func (j JobStatus) String() string {
 	}
 }
 
+// ParseJobStatus accepts either the name returned by String or the numeric
+// value of a status.
+func ParseJobStatus(s string) (JobStatus, bool) {
+	for st := JobStatusReceived; st <= JobStatusCancelled; st++ {
+		if s == st.String() || s == strconv.Itoa(int(st)) {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
+// IsTerminal reports whether a job in this status will not be picked up by
+// the pipeline again.
+func (j JobStatus) IsTerminal() bool {
+	return j == JobStatusCompleted || j == JobStatusFailed || j == JobStatusCancelled
+}
+
+// Jobs are claimed by descending priority, then oldest first.
+const (
+	JobPriorityNormal     = 0
+	JobPriorityProduction = 10
+)
+
 type Job struct {
 	ID                      uuid.UUID   `json:"id" bson:"_id"`
 	UserEmail               string      `json:"userEmail" bson:"userEmail"`
@@ -60,6 +85,9 @@ type Job struct {
 	FinalVideoS3Key         string      `json:"finalVideoS3Key,omitempty" bson:"finalVideoS3Key,omitempty"`
 	FinalVideoDuration      int         `json:"finalVideoDuration,omitempty" bson:"finalVideoDuration,omitempty"`
 	FinalVideoSize          int64       `json:"finalVideoSize,omitempty" bson:"finalVideoSize,omitempty"`
+	Watermarked             bool        `json:"watermarked" bson:"watermarked"` // render keeps the watermark, set up front on production jobs
+	CleanVideoURL           string      `json:"cleanVideoUrl,omitempty" bson:"cleanVideoUrl,omitempty"`
+	CleanVideoS3Key         string      `json:"cleanVideoS3Key,omitempty" bson:"cleanVideoS3Key,omitempty"`
 	SignedURL               string      `json:"signedUrl,omitempty" bson:"signedUrl,omitempty"`
 	SignedURLExpiry         int64       `json:"signedUrlExpiry,omitempty" bson:"signedUrlExpiry,omitempty"`
 	EmailSent               bool        `json:"emailSent" bson:"emailSent"`
@@ -76,6 +104,19 @@ type Job struct {
 	OrderID                 *uuid.UUID  `json:"orderId,omitempty" bson:"orderId,omitempty"`
 	ContentModerated        bool        `json:"contentModerated" bson:"contentModerated"`
 	ContentModerationResult interface{} `json:"contentModerationResult,omitempty" bson:"contentModerationResult,omitempty"`
+	Priority                int         `json:"priority" bson:"priority"`
+	SourceJobID             *uuid.UUID  `json:"sourceJobId,omitempty" bson:"sourceJobId,omitempty"` // sample a production job renders again
+	VideoWidth              int         `json:"videoWidth,omitempty" bson:"videoWidth,omitempty"`
+	VideoHeight             int         `json:"videoHeight,omitempty" bson:"videoHeight,omitempty"`
+	MaxDurationSeconds      int         `json:"maxDurationSeconds,omitempty" bson:"maxDurationSeconds,omitempty"`
+	LeaseOwner              string      `json:"leaseOwner,omitempty" bson:"leaseOwner,omitempty"`
+	LeaseExpiresAt          int64       `json:"leaseExpiresAt,omitempty" bson:"leaseExpiresAt,omitempty"`
 	CreatedAt               int64       `json:"createdAt" bson:"createdAt"`
 	UpdatedAt               int64       `json:"updatedAt" bson:"updatedAt"`
 }
+
+// IsProduction reports whether the job renders a paid order rather than a
+// free sample.
+func (j *Job) IsProduction() bool {
+	return j.SourceJobID != nil
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+// JobStatusEvent is one transition in the status history of a job.
+type JobStatusEvent struct {
+	ID         int64     `json:"id" bson:"_id"`
+	JobID      uuid.UUID `json:"jobId" bson:"jobId"`
+	FromStatus JobStatus `json:"fromStatus,omitempty" bson:"fromStatus,omitempty"`
+	ToStatus   JobStatus `json:"toStatus" bson:"toStatus"`
+	Detail     string    `json:"detail,omitempty" bson:"detail,omitempty"`
+	CreatedAt  int64     `json:"createdAt" bson:"createdAt"`
+}
+
+// StageDuration aggregates the time jobs spent in one status, in seconds.
+type StageDuration struct {
+	Status     JobStatus `json:"status"`
+	Count      int64     `json:"count"`
+	AvgSeconds float64   `json:"avgSeconds"`
+	P50Seconds float64   `json:"p50Seconds"`
+	P95Seconds float64   `json:"p95Seconds"`
+	MaxSeconds int64     `json:"maxSeconds"`
+}
+
+// IsActive reports whether a job in this status is being worked on, as
+// opposed to waiting in the queue or being finished.
+func (j JobStatus) IsActive() bool {
+	return j != JobStatusReceived && !j.IsTerminal()
+}
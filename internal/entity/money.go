@@ -0,0 +1,151 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// currencyExponents holds the number of minor unit digits of every
+// currency we accept, as ISO 4217 and Stripe define them.
+var currencyExponents = map[string]int{
+	"USD": 2, "EUR": 2, "GBP": 2, "CAD": 2, "AUD": 2, "NZD": 2, "CHF": 2,
+	"SEK": 2, "NOK": 2, "DKK": 2, "PLN": 2, "CZK": 2, "HUF": 2, "RON": 2,
+	"SGD": 2, "HKD": 2, "MXN": 2, "BRL": 2, "INR": 2, "ZAR": 2, "AED": 2,
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0, "PYG": 0, "UGX": 0,
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyExponent returns how many digits of currency follow the decimal
+// point, 2 for USD and 0 for JPY. The code is case-insensitive.
+func CurrencyExponent(currency string) (int, bool) {
+	exp, ok := currencyExponents[strings.ToUpper(currency)]
+	return exp, ok
+}
+
+// Money is an amount in the minor unit of its currency, cents for USD and
+// yen for JPY, the way Stripe expects it. Currency is an upper case ISO
+// 4217 code.
+type Money struct {
+	Minor    int64  `json:"minor" bson:"minor"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// NewMoney checks currency and normalizes it to upper case.
+func NewMoney(minor int64, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	if _, ok := currencyExponents[currency]; !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// ParseMoney reads a decimal amount in major units, "12.34" USD or "1500"
+// JPY. More decimals than the currency has are rejected rather than rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	exp, ok := CurrencyExponent(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+
+	s, sign := strings.TrimSpace(amount), ""
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		s, sign = rest, "-"
+	}
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && frac == "") || len(frac) > exp || !isDigits(whole+frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	// parse with the sign so the smallest int64 fits
+	minor, err := strconv.ParseInt(sign+whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return NewMoney(minor, currency)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Major formats the amount in major units without the currency, with as
+// many decimals as the currency has: "12.34", "1500", "1.005".
+func (m Money) Major() string {
+	exp, ok := CurrencyExponent(m.Currency)
+	if !ok {
+		exp = 2
+	}
+	sign, minor := "", uint64(m.Minor)
+	if m.Minor < 0 {
+		// unsigned negation also holds the smallest int64
+		sign, minor = "-", -minor
+	}
+	return sign + formatMinor(minor, exp)
+}
+
+func formatMinor(minor uint64, exp int) string {
+	digits := strconv.FormatUint(minor, 10)
+	if exp == 0 {
+		return digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats m for people and logs, "USD 12.34".
+func (m Money) String() string {
+	return m.Currency + " " + m.Major()
+}
+
+// StripeCurrency is the currency in the lower case form the Stripe API
+// uses.
+func (m Money) StripeCurrency() string {
+	return strings.ToLower(m.Currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	switch {
+	case m.Minor < o.Minor:
+		return -1, nil
+	case m.Minor > o.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		err      error
+	}{
+		{"12.34", "USD", Money{Minor: 1234, Currency: "USD"}, nil},
+		{"12.3", "usd", Money{Minor: 1230, Currency: "USD"}, nil},
+		{"12", "USD", Money{Minor: 1200, Currency: "USD"}, nil},
+		{" 0.05 ", "EUR", Money{Minor: 5, Currency: "EUR"}, nil},
+		{"007.50", "USD", Money{Minor: 750, Currency: "USD"}, nil},
+		{"-12.34", "USD", Money{Minor: -1234, Currency: "USD"}, nil},
+		{"-0", "USD", Money{Minor: 0, Currency: "USD"}, nil},
+		{"1500", "JPY", Money{Minor: 1500, Currency: "JPY"}, nil},
+		{"1.005", "KWD", Money{Minor: 1005, Currency: "KWD"}, nil},
+		{"1.5", "KWD", Money{Minor: 1500, Currency: "KWD"}, nil},
+		{"9223372036854775807", "JPY", Money{Minor: math.MaxInt64, Currency: "JPY"}, nil},
+		{"-9223372036854775808", "JPY", Money{Minor: math.MinInt64, Currency: "JPY"}, nil},
+
+		{"9223372036854775808", "JPY", Money{}, ErrInvalidAmount},
+		{"92233720368547758.08", "USD", Money{}, ErrInvalidAmount},
+		{"12.345", "USD", Money{}, ErrInvalidAmount},
+		{"1500.0", "JPY", Money{}, ErrInvalidAmount},
+		{"1.0005", "KWD", Money{}, ErrInvalidAmount},
+		{"12.", "USD", Money{}, ErrInvalidAmount},
+		{".5", "USD", Money{}, ErrInvalidAmount},
+		{"", "USD", Money{}, ErrInvalidAmount},
+		{"-", "USD", Money{}, ErrInvalidAmount},
+		{"--1", "USD", Money{}, ErrInvalidAmount},
+		{"+1", "USD", Money{}, ErrInvalidAmount},
+		{"1,000", "USD", Money{}, ErrInvalidAmount},
+		{"1e3", "USD", Money{}, ErrInvalidAmount},
+		{"12.34", "XXX", Money{}, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.amount, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseMoney(%q, %q) error = %v, want %v", tt.amount, tt.currency, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q, %q) = %+v, want %+v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMajor(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Minor: 1234, Currency: "USD"}, "12.34"},
+		{Money{Minor: 5, Currency: "USD"}, "0.05"},
+		{Money{Minor: 0, Currency: "USD"}, "0.00"},
+		{Money{Minor: -1234, Currency: "USD"}, "-12.34"},
+		{Money{Minor: -5, Currency: "USD"}, "-0.05"},
+		{Money{Minor: 1500, Currency: "JPY"}, "1500"},
+		{Money{Minor: -1500, Currency: "JPY"}, "-1500"},
+		{Money{Minor: 1005, Currency: "KWD"}, "1.005"},
+		{Money{Minor: 5, Currency: "KWD"}, "0.005"},
+		{Money{Minor: math.MaxInt64, Currency: "USD"}, "92233720368547758.07"},
+		{Money{Minor: math.MinInt64, Currency: "USD"}, "-92233720368547758.08"},
+		{Money{Minor: math.MinInt64, Currency: "JPY"}, "-9223372036854775808"},
+		// unknown currencies fall back to two decimals
+		{Money{Minor: 1234, Currency: "XXX"}, "12.34"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.Major(); got != tt.want {
+			t.Errorf("%+v.Major() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+// TestParseMoneyRoundTrip checks that Major prints what ParseMoney reads.
+func TestParseMoneyRoundTrip(t *testing.T) {
+	for _, m := range []Money{
+		{Minor: 1234, Currency: "USD"},
+		{Minor: -7, Currency: "EUR"},
+		{Minor: 1500, Currency: "JPY"},
+		{Minor: 1005, Currency: "KWD"},
+		{Minor: math.MinInt64, Currency: "USD"},
+	} {
+		got, err := ParseMoney(m.Major(), m.Currency)
+		if err != nil || got != m {
+			t.Errorf("ParseMoney(%q) = %+v, %v, want %+v", m.Major(), got, err, m)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	usd := func(minor int64) Money { return Money{Minor: minor, Currency: "USD"} }
+
+	if got, err := usd(1000).Add(usd(250)); err != nil || got != usd(1250) {
+		t.Errorf("Add = %+v, %v, want USD 12.50", got, err)
+	}
+	if got, err := usd(1000).Sub(usd(1250)); err != nil || got != usd(-250) {
+		t.Errorf("Sub = %+v, %v, want USD -2.50", got, err)
+	}
+	if got, err := usd(1000).Cmp(usd(999)); err != nil || got != 1 {
+		t.Errorf("Cmp = %d, %v, want 1", got, err)
+	}
+
+	eur := Money{Minor: 1000, Currency: "EUR"}
+	if _, err := usd(1000).Add(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add(EUR) error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := usd(1000).Sub(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sub(EUR) error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := usd(1000).Cmp(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp(EUR) error = %v, want ErrCurrencyMismatch", err)
+	}
+}
@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"strconv"
+
 	"github.com/google/uuid"
 )
 
@@ -11,6 +13,9 @@ const (
 	PaymentStatusPaid     PaymentStatus = 2
 	PaymentStatusFailed   PaymentStatus = 3
 	PaymentStatusRefunded PaymentStatus = 4
+	// PaymentStatusExpired is an order left unpaid past its ExpiresAt, its
+	// job may be ordered again.
+	PaymentStatusExpired PaymentStatus = 5
 )
 
 func (p PaymentStatus) String() string {
@@ -23,11 +28,24 @@ func (p PaymentStatus) String() string {
 		return "FAILED"
 	case PaymentStatusRefunded:
 		return "REFUNDED"
+	case PaymentStatusExpired:
+		return "EXPIRED"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// ParsePaymentStatus accepts either the name returned by String or the
+// numeric value of a status.
+func ParsePaymentStatus(s string) (PaymentStatus, bool) {
+	for st := PaymentStatusPending; st <= PaymentStatusExpired; st++ {
+		if s == st.String() || s == strconv.Itoa(int(st)) {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
 type OrderType uint8
 
 const (
@@ -73,6 +91,17 @@ func (p ProductionStatus) String() string {
 	}
 }
 
+// ParseProductionStatus accepts either the name returned by String or the
+// numeric value of a status.
+func ParseProductionStatus(s string) (ProductionStatus, bool) {
+	for st := ProductionStatusPending; st <= ProductionStatusFailed; st++ {
+		if s == st.String() || s == strconv.Itoa(int(st)) {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
 type DeliveryMethod uint8
 
 const (
@@ -101,8 +130,9 @@ type Order struct {
 	UserName              string           `json:"userName" bson:"userName"`
 	StripePaymentIntentID string           `json:"stripePaymentIntentId,omitempty" bson:"stripePaymentIntentId,omitempty"`
 	StripeCustomerID      string           `json:"stripeCustomerId,omitempty" bson:"stripeCustomerId,omitempty"`
-	Amount                float64          `json:"amount" bson:"amount"`
-	Currency              string           `json:"currency" bson:"currency"`
+	Amount                Money            `json:"amount" bson:"amount"` // what is charged, after Discount
+	Discount              Money            `json:"discount" bson:"discount"`
+	PromotionID           *uuid.UUID       `json:"promotionId,omitempty" bson:"promotionId,omitempty"`
 	PaymentStatus         PaymentStatus    `json:"paymentStatus" bson:"paymentStatus"`
 	PaidAt                int64            `json:"paidAt,omitempty" bson:"paidAt,omitempty"`
 	OrderType             OrderType        `json:"orderType" bson:"orderType"`
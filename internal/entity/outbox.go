@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"encoding/json"
+)
+
+const (
+	OutboxTopicJobEvents   = "stream:job-events"
+	OutboxTopicOrderEvents = "stream:order-events"
+
+	OutboxEventJobStatusChanged = "job.status_changed"
+	// OutboxEventOrderRefunded is published for every successful refund,
+	// the customer is emailed by the refund service itself.
+	OutboxEventOrderRefunded = "order.refunded"
+	// OutboxEventOrderAccessRevoked is published once the downloads of an
+	// order were shut, after a refund or at the end of retention.
+	OutboxEventOrderAccessRevoked = "order.access_revoked"
+	// OutboxEventOrderPaid is published once Stripe confirmed the payment.
+	OutboxEventOrderPaid = "order.paid"
+	// OutboxEventOrderProductionChanged follows the production status of an
+	// order as its production job moves through the pipeline.
+	OutboxEventOrderProductionChanged = "order.production_changed"
+	// OutboxEventOrderDelivered carries the download link of a delivered
+	// order, for the storefront to show.
+	OutboxEventOrderDelivered = "order.delivered"
+	// OutboxEventOrderExpired is published when an order was left unpaid
+	// past its payment window.
+	OutboxEventOrderExpired = "order.expired"
+)
+
+// OutboxMessage is a side effect written in the same transaction as the
+// change that caused it and published once that transaction committed.
+type OutboxMessage struct {
+	ID            int64           `json:"id" bson:"_id"`
+	Topic         string          `json:"topic" bson:"topic"`
+	EventType     string          `json:"eventType" bson:"eventType"`
+	AggregateType string          `json:"aggregateType" bson:"aggregateType"`
+	AggregateID   string          `json:"aggregateId" bson:"aggregateId"`
+	Payload       json.RawMessage `json:"payload" bson:"payload"`
+	Attempts      int             `json:"attempts" bson:"attempts"`
+	LastError     string          `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	DeliveredAt   int64           `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+	CreatedAt     int64           `json:"createdAt" bson:"createdAt"`
+}
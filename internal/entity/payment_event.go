@@ -0,0 +1,20 @@
+package entity
+
+// Stripe event types the payment webhook acts on.
+const (
+	PaymentEventIntentSucceeded = "payment_intent.succeeded"
+	PaymentEventIntentFailed    = "payment_intent.payment_failed"
+)
+
+// PaymentEvent is a verified payment provider webhook, reduced to what the
+// services need. OrderID comes from the payment intent metadata, Amount is
+// what was actually received.
+type PaymentEvent struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	PaymentIntentID string `json:"paymentIntentId"`
+	CustomerID      string `json:"customerId,omitempty"`
+	OrderID         string `json:"orderId,omitempty"`
+	Amount          Money  `json:"amount"`
+	CreatedAt       int64  `json:"createdAt"`
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ParseOrderType accepts the name returned by String in any case or the
+// numeric value of a type.
+func ParseOrderType(s string) (OrderType, bool) {
+	for t := OrderTypeBasic; t <= OrderTypeCustom; t++ {
+		if strings.EqualFold(s, t.String()) || s == strconv.Itoa(int(t)) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// ProductPrice is what a product costs in one currency. It is the list
+// price an order starts from, the charge is built from Order.Amount since
+// a promo code can take a discount off it.
+type ProductPrice struct {
+	Price Money `json:"price" bson:"price"`
+}
+
+// Product is the catalog entry behind an OrderType. At most one product per
+// order type is active, checkout prices orders from it.
+type Product struct {
+	ID                 uuid.UUID      `json:"id" bson:"_id"`
+	OrderType          OrderType      `json:"orderType" bson:"orderType"`
+	Name               string         `json:"name" bson:"name"`
+	VideoWidth         int            `json:"videoWidth" bson:"videoWidth"`
+	VideoHeight        int            `json:"videoHeight" bson:"videoHeight"`
+	MaxDurationSeconds int            `json:"maxDurationSeconds" bson:"maxDurationSeconds"`
+	WatermarkFree      bool           `json:"watermarkFree" bson:"watermarkFree"`
+	Active             bool           `json:"active" bson:"active"`
+	Prices             []ProductPrice `json:"prices" bson:"prices"`
+	CreatedAt          int64          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt          int64          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// PriceIn returns the price of p in currency, which is case-insensitive.
+func (p *Product) PriceIn(currency string) (ProductPrice, bool) {
+	for _, price := range p.Prices {
+		if strings.EqualFold(price.Price.Currency, currency) {
+			return price, true
+		}
+	}
+	return ProductPrice{}, false
+}
@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type PromotionKind uint8
+
+const (
+	PromotionKindPercent PromotionKind = 1
+	PromotionKindFixed   PromotionKind = 2
+)
+
+func (k PromotionKind) String() string {
+	switch k {
+	case PromotionKindPercent:
+		return "PERCENT"
+	case PromotionKindFixed:
+		return "FIXED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Promotion is a discount code. Percent codes take PercentOff of the price,
+// fixed codes take AmountOff and only apply to prices in its currency.
+// Zero StartsAt, EndsAt, MaxRedemptions and MaxPerEmail leave that bound
+// open, an empty OrderTypes applies to every order type.
+type Promotion struct {
+	ID             uuid.UUID     `json:"id" bson:"_id"`
+	Code           string        `json:"code" bson:"code"`
+	Kind           PromotionKind `json:"kind" bson:"kind"`
+	PercentOff     int           `json:"percentOff,omitempty" bson:"percentOff,omitempty"`
+	AmountOff      Money         `json:"amountOff" bson:"amountOff"`
+	StartsAt       int64         `json:"startsAt,omitempty" bson:"startsAt,omitempty"`
+	EndsAt         int64         `json:"endsAt,omitempty" bson:"endsAt,omitempty"`
+	MaxRedemptions int           `json:"maxRedemptions,omitempty" bson:"maxRedemptions,omitempty"`
+	MaxPerEmail    int           `json:"maxPerEmail,omitempty" bson:"maxPerEmail,omitempty"`
+	Redemptions    int           `json:"redemptions" bson:"redemptions"`
+	OrderTypes     []OrderType   `json:"orderTypes" bson:"orderTypes"`
+	Active         bool          `json:"active" bson:"active"`
+	CreatedAt      int64         `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      int64         `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ValidAt reports whether p is active and now falls in its window.
+func (p *Promotion) ValidAt(now int64) bool {
+	return p.Active && (p.StartsAt == 0 || now >= p.StartsAt) && (p.EndsAt == 0 || now < p.EndsAt)
+}
+
+func (p *Promotion) AppliesTo(orderType OrderType) bool {
+	return len(p.OrderTypes) == 0 || slices.Contains(p.OrderTypes, orderType)
+}
+
+// Discount is what p takes off price, never more than price itself.
+// Percentages round half up to the minor unit. A fixed code in another
+// currency returns ErrCurrencyMismatch.
+func (p *Promotion) Discount(price Money) (Money, error) {
+	var off int64
+	switch p.Kind {
+	case PromotionKindPercent:
+		off = (price.Minor*int64(p.PercentOff) + 50) / 100
+	case PromotionKindFixed:
+		if p.AmountOff.Currency != price.Currency {
+			return Money{}, ErrCurrencyMismatch
+		}
+		off = p.AmountOff.Minor
+	}
+	return Money{Minor: min(off, price.Minor), Currency: price.Currency}, nil
+}
+
+// PromotionRedemption records one use of a promotion by an order.
+type PromotionRedemption struct {
+	ID          uuid.UUID `json:"id" bson:"_id"`
+	PromotionID uuid.UUID `json:"promotionId" bson:"promotionId"`
+	OrderID     uuid.UUID `json:"orderId" bson:"orderId"`
+	Email       string    `json:"email" bson:"email"`
+	Discount    Money     `json:"discount" bson:"discount"`
+	CreatedAt   int64     `json:"createdAt" bson:"createdAt"`
+}
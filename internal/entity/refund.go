@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+type RefundStatus uint8
+
+const (
+	RefundStatusPending   RefundStatus = 1
+	RefundStatusSucceeded RefundStatus = 2
+	RefundStatusFailed    RefundStatus = 3
+)
+
+func (r RefundStatus) String() string {
+	switch r {
+	case RefundStatusPending:
+		return "PENDING"
+	case RefundStatusSucceeded:
+		return "SUCCEEDED"
+	case RefundStatusFailed:
+		return "FAILED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+type Refund struct {
+	ID             uuid.UUID    `json:"id" bson:"_id"`
+	OrderID        uuid.UUID    `json:"orderId" bson:"orderId"`
+	Amount         Money        `json:"amount" bson:"amount"`
+	Reason         string       `json:"reason" bson:"reason"`
+	ActorID        *uuid.UUID   `json:"actorId,omitempty" bson:"actorId,omitempty"`
+	ActorName      string       `json:"actorName" bson:"actorName"`
+	Status         RefundStatus `json:"status" bson:"status"`
+	StripeRefundID string       `json:"stripeRefundId,omitempty" bson:"stripeRefundId,omitempty"`
+	FailureReason  string       `json:"failureReason,omitempty" bson:"failureReason,omitempty"`
+	CreatedAt      int64        `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      int64        `json:"updatedAt" bson:"updatedAt"`
+}
@@ -36,20 +36,57 @@ type Env struct {
 	AWSCFDistributionID string
 	AWSCFKeyPairID      string
 	AWSCFPrivateKeyPath string
+	// domain signed delivery URLs are served from, e.g. d111111abcdef8.cloudfront.net
+	AWSCFDomain string
 
 	// Email / Postmark
 	PostmarkAPIKey     string
 	PostmarkFromEmail  string
 	PostmarkFromName   string
 	PostmarkTemplateID string
-	SkipEmailSending   string
+	// template id or alias of the email that delivers a paid order
+	PostmarkDeliveryTemplateID string
+	// template id or alias of the email that confirms a refund
+	PostmarkRefundTemplateID string
+	SkipEmailSending         string
 
 	// Database
 	DatabaseURL string
+	// optional, reads that tolerate replication lag go here when set
+	DatabaseReplicaURL string
+	// Connection pool, applied to the primary and the replica alike
+	DBMaxConns                 string
+	DBMinConns                 string
+	DBMaxConnLifetimeSeconds   string
+	DBMaxConnIdleSeconds       string
+	DBHealthCheckPeriodSeconds string
+	DBStatementTimeoutMS       string
+	DBSlowQueryMS              string
+	DBSetupTimeoutSeconds      string
 
 	// Redis
 	RedisURL string
 
+	// Outbox
+	OutboxPollIntervalMS string
+	OutboxBatchSize      string
+	OutboxRetentionHours string
+
+	// Postgres job claiming
+	JobClaimerEnabled      string
+	JobLeaseSeconds        string
+	JobClaimPollIntervalMS string
+	JobRetryDelaySeconds   string
+
+	// Delivery
+	DeliveryBaseURL        string
+	DeliveryRetentionDays  string
+	DeliveryPollIntervalMS string
+
+	// Order expiry
+	OrderPaymentWindowMinutes string
+	OrderSweepIntervalSeconds string
+
 	// Security & Rate Limiting
 	RecaptchaSiteKey             string
 	RecaptchaSecretKey           string
@@ -64,6 +101,11 @@ type Env struct {
 	UploadTimeout    string
 	WatermarkPath    string
 
+	// Image Processing
+	ImageMaxDimension string
+	ImageOutputFormat string
+	ImageJPEGQuality  string
+
 	// Video Processing
 	VideoTargetWidth   string
 	VideoTargetHeight  string
@@ -76,7 +118,6 @@ type Env struct {
 	StripeSecretKey      string
 	StripePublishableKey string
 	StripeWebhookSecret  string
-	StripePriceID        string
 }
 
 func NewEnv() *Env {
@@ -121,20 +162,52 @@ func (e *Env) Load() error {
 	e.AWSCFDistributionID = os.Getenv("AWS_CLOUDFRONT_DISTRIBUTION_ID")
 	e.AWSCFKeyPairID = os.Getenv("AWS_CLOUDFRONT_KEY_PAIR_ID")
 	e.AWSCFPrivateKeyPath = os.Getenv("AWS_CLOUDFRONT_PRIVATE_KEY_PATH")
+	e.AWSCFDomain = os.Getenv("AWS_CLOUDFRONT_DOMAIN")
 
 	// Postmark
 	e.PostmarkAPIKey = os.Getenv("POSTMARK_API_KEY")
 	e.PostmarkFromEmail = os.Getenv("POSTMARK_FROM_EMAIL")
 	e.PostmarkFromName = os.Getenv("POSTMARK_FROM_NAME")
 	e.PostmarkTemplateID = os.Getenv("POSTMARK_TEMPLATE_ID")
+	e.PostmarkDeliveryTemplateID = os.Getenv("POSTMARK_DELIVERY_TEMPLATE_ID")
+	e.PostmarkRefundTemplateID = os.Getenv("POSTMARK_REFUND_TEMPLATE_ID")
 	e.SkipEmailSending = os.Getenv("SKIP_EMAIL_SENDING")
 
 	// Database
 	e.DatabaseURL = os.Getenv("DATABASE_URL")
+	e.DatabaseReplicaURL = os.Getenv("DATABASE_REPLICA_URL")
+	e.DBMaxConns = os.Getenv("DB_MAX_CONNS")
+	e.DBMinConns = os.Getenv("DB_MIN_CONNS")
+	e.DBMaxConnLifetimeSeconds = os.Getenv("DB_MAX_CONN_LIFETIME_SECONDS")
+	e.DBMaxConnIdleSeconds = os.Getenv("DB_MAX_CONN_IDLE_SECONDS")
+	e.DBHealthCheckPeriodSeconds = os.Getenv("DB_HEALTH_CHECK_PERIOD_SECONDS")
+	e.DBStatementTimeoutMS = os.Getenv("DB_STATEMENT_TIMEOUT_MS")
+	e.DBSlowQueryMS = os.Getenv("DB_SLOW_QUERY_MS")
+	e.DBSetupTimeoutSeconds = os.Getenv("DB_SETUP_TIMEOUT_SECONDS")
 
 	// Redis
 	e.RedisURL = os.Getenv("REDIS_URL")
 
+	// Outbox
+	e.OutboxPollIntervalMS = os.Getenv("OUTBOX_POLL_INTERVAL_MS")
+	e.OutboxBatchSize = os.Getenv("OUTBOX_BATCH_SIZE")
+	e.OutboxRetentionHours = os.Getenv("OUTBOX_RETENTION_HOURS")
+
+	// Postgres job claiming
+	e.JobClaimerEnabled = os.Getenv("JOB_CLAIMER_ENABLED")
+	e.JobLeaseSeconds = os.Getenv("JOB_LEASE_SECONDS")
+	e.JobClaimPollIntervalMS = os.Getenv("JOB_CLAIM_POLL_INTERVAL_MS")
+	e.JobRetryDelaySeconds = os.Getenv("JOB_RETRY_DELAY_SECONDS")
+
+	// Delivery
+	e.DeliveryBaseURL = os.Getenv("DELIVERY_BASE_URL")
+	e.DeliveryRetentionDays = os.Getenv("DELIVERY_RETENTION_DAYS")
+	e.DeliveryPollIntervalMS = os.Getenv("DELIVERY_POLL_INTERVAL_MS")
+
+	// Order expiry
+	e.OrderPaymentWindowMinutes = os.Getenv("ORDER_PAYMENT_WINDOW_MINUTES")
+	e.OrderSweepIntervalSeconds = os.Getenv("ORDER_SWEEP_INTERVAL_SECONDS")
+
 	// Security & Rate Limiting
 	e.RecaptchaSiteKey = os.Getenv("RECAPTCHA_SITE_KEY")
 	e.RecaptchaSecretKey = os.Getenv("RECAPTCHA_SECRET_KEY")
@@ -149,6 +222,11 @@ func (e *Env) Load() error {
 	e.UploadTimeout = os.Getenv("UPLOAD_TIMEOUT")
 	e.WatermarkPath = os.Getenv("WATERMARK_PATH")
 
+	// Image Processing
+	e.ImageMaxDimension = os.Getenv("IMAGE_MAX_DIMENSION")
+	e.ImageOutputFormat = os.Getenv("IMAGE_OUTPUT_FORMAT")
+	e.ImageJPEGQuality = os.Getenv("IMAGE_JPEG_QUALITY")
+
 	// Video
 	e.VideoTargetWidth = os.Getenv("VIDEO_TARGET_WIDTH")
 	e.VideoTargetHeight = os.Getenv("VIDEO_TARGET_HEIGHT")
@@ -161,7 +239,6 @@ func (e *Env) Load() error {
 	e.StripeSecretKey = os.Getenv("STRIPE_SECRET_KEY")
 	e.StripePublishableKey = os.Getenv("STRIPE_PUBLISHABLE_KEY")
 	e.StripeWebhookSecret = os.Getenv("STRIPE_WEBHOOK_SECRET")
-	e.StripePriceID = os.Getenv("STRIPE_PRICE_ID")
 
 	return nil
 }
@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Orientation is the value of the EXIF Orientation tag (0x0112).
+type Orientation uint16
+
+const (
+	OrientationNormal         Orientation = 1
+	OrientationFlipHorizontal Orientation = 2
+	OrientationRotate180      Orientation = 3
+	OrientationFlipVertical   Orientation = 4
+	OrientationTranspose      Orientation = 5
+	OrientationRotate90       Orientation = 6
+	OrientationTransverse     Orientation = 7
+	OrientationRotate270      Orientation = 8
+)
+
+const exifOrientationTag = 0x0112
+
+var (
+	exifHeader   = []byte("Exif\x00\x00")
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// readOrientation looks for an EXIF orientation in a JPEG, PNG or WebP
+// payload. Anything it cannot make sense of is treated as OrientationNormal,
+// a broken EXIF block should never fail an upload.
+func readOrientation(data []byte) Orientation {
+	var tiff []byte
+	switch {
+	case len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8:
+		tiff = jpegExif(data)
+	case bytes.HasPrefix(data, pngSignature):
+		tiff = pngExif(data)
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		tiff = webpExif(data)
+	}
+	if tiff == nil {
+		return OrientationNormal
+	}
+
+	o := tiffOrientation(tiff)
+	if o < OrientationNormal || o > OrientationRotate270 {
+		return OrientationNormal
+	}
+	return o
+}
+
+// jpegExif walks the JPEG marker segments up to the start of scan and
+// returns the TIFF body of the first APP1 Exif segment.
+func jpegExif(data []byte) []byte {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return nil
+		}
+		marker := data[i+1]
+		// fill bytes and standalone markers carry no length
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		// start of scan, no metadata follows
+		if marker == 0xDA {
+			return nil
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return nil
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):]
+		}
+		i += 2 + size
+	}
+	return nil
+}
+
+// pngExif returns the payload of the eXIf chunk, which is a bare TIFF body.
+func pngExif(data []byte) []byte {
+	i := len(pngSignature)
+	for i+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		if size < 0 || i+12+size > len(data) {
+			return nil
+		}
+		if kind == "eXIf" {
+			return data[i+8 : i+8+size]
+		}
+		if kind == "IEND" {
+			return nil
+		}
+		i += 12 + size
+	}
+	return nil
+}
+
+// webpExif returns the payload of the RIFF EXIF chunk. Some encoders keep
+// the JPEG style "Exif\0\0" prefix in there, so it is stripped when present.
+func webpExif(data []byte) []byte {
+	i := 12
+	for i+8 <= len(data) {
+		kind := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		if size < 0 || i+8+size > len(data) {
+			return nil
+		}
+		if kind == "EXIF" {
+			return bytes.TrimPrefix(data[i+8:i+8+size], exifHeader)
+		}
+		// chunks are padded to an even size
+		i += 8 + size + size%2
+	}
+	return nil
+}
+
+// tiffOrientation reads the Orientation entry out of IFD0.
+func tiffOrientation(tiff []byte) Orientation {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) != exifOrientationTag {
+			continue
+		}
+		// type SHORT, value stored inline in the first two bytes
+		if order.Uint16(tiff[entry+2:entry+4]) != 3 {
+			return 0
+		}
+		return Orientation(order.Uint16(tiff[entry+8 : entry+10]))
+	}
+	return 0
+}
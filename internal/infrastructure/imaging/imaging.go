@@ -0,0 +1,168 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/utils"
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultMaxDimension = 2048
+	defaultJPEGQuality  = 90
+	// maxSourcePixels guards against decompression bombs, the header is
+	// checked before any pixel data is decoded.
+	maxSourcePixels = 100_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image too large")
+)
+
+type Format string
+
+const (
+	FormatAuto Format = "auto"
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+)
+
+// Result is a normalized image ready to be stored under Job.InputImageS3Key.
+type Result struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	// Orientation is the EXIF orientation that was applied to the pixels.
+	Orientation Orientation
+}
+
+type Imaging struct {
+	Env          *godotenv.Env
+	maxDimension int
+	maxFileSize  int64
+	jpegQuality  int
+	format       Format
+}
+
+func NewImaging(env *godotenv.Env) *Imaging {
+	i := &Imaging{
+		Env:          env,
+		maxDimension: defaultMaxDimension,
+		jpegQuality:  defaultJPEGQuality,
+		format:       FormatAuto,
+	}
+	if v, err := strconv.Atoi(env.ImageMaxDimension); err == nil && v > 0 {
+		i.maxDimension = v
+	}
+	if v, err := strconv.Atoi(env.ImageJPEGQuality); err == nil && v >= 1 && v <= 100 {
+		i.jpegQuality = v
+	}
+	if v, err := strconv.ParseInt(env.MaxFileSize, 10, 64); err == nil && v > 0 {
+		i.maxFileSize = v
+	}
+	switch Format(strings.ToLower(env.ImageOutputFormat)) {
+	case FormatJPEG:
+		i.format = FormatJPEG
+	case FormatPNG:
+		i.format = FormatPNG
+	}
+	return i
+}
+
+// Normalize decodes a customer upload, applies its EXIF orientation, scales
+// it down to fit the configured bounds and re-encodes it. The stdlib encoders
+// never write metadata, so GPS and device EXIF data are dropped on the way.
+func (i *Imaging) Normalize(r io.Reader) (*Result, error) {
+	if i.maxFileSize > 0 {
+		r = io.LimitReader(r, i.maxFileSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, utils.WrapError("read image", err)
+	}
+	if i.maxFileSize > 0 && int64(len(data)) > i.maxFileSize {
+		return nil, ErrImageTooLarge
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.WrapError("decode image config", ErrUnsupportedFormat, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.WrapError("decode image", ErrUnsupportedFormat, err)
+	}
+
+	orientation := readOrientation(data)
+	img := applyOrientation(i.fit(src), orientation)
+
+	format := i.format
+	if format == FormatAuto {
+		format = FormatJPEG
+		if !img.Opaque() {
+			format = FormatPNG
+		}
+	}
+
+	var buf bytes.Buffer
+	res := &Result{
+		Width:       img.Rect.Dx(),
+		Height:      img.Rect.Dy(),
+		Orientation: orientation,
+	}
+	switch format {
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+		res.ContentType, res.Extension = "image/png", ".png"
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: i.jpegQuality})
+		res.ContentType, res.Extension = "image/jpeg", ".jpg"
+	}
+	if err != nil {
+		return nil, utils.WrapError("encode image", err)
+	}
+	res.Data = buf.Bytes()
+
+	return res, nil
+}
+
+// fit converts src to NRGBA, scaling it down so that its longest side is at
+// most maxDimension. Images are never scaled up.
+func (i *Imaging) fit(src image.Image) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > i.maxDimension || h > i.maxDimension {
+		if w >= h {
+			h = max(1, h*i.maxDimension/w)
+			w = i.maxDimension
+		} else {
+			w = max(1, w*i.maxDimension/h)
+			h = i.maxDimension
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Rect, src, b, draw.Src, nil)
+		return dst
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,315 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+)
+
+var (
+	red   = color.NRGBA{R: 255, A: 255}
+	green = color.NRGBA{G: 255, A: 255}
+	blue  = color.NRGBA{B: 255, A: 255}
+	white = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// quadrants returns a w×h image with a red, green, blue and white quadrant
+// in reading order, so every orientation leaves a different color pattern
+// in the corners.
+func quadrants(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			switch {
+			case x < w/2 && y < h/2:
+				img.SetNRGBA(x, y, red)
+			case y < h/2:
+				img.SetNRGBA(x, y, green)
+			case x < w/2:
+				img.SetNRGBA(x, y, blue)
+			default:
+				img.SetNRGBA(x, y, white)
+			}
+		}
+	}
+	return img
+}
+
+// tiffBody builds a TIFF header with a single IFD0 entry.
+func tiffBody(order binary.ByteOrder, tag, kind uint16, value uint16) []byte {
+	b := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(b, "II")
+	} else {
+		copy(b, "MM")
+	}
+	order.PutUint16(b[2:], 42)
+	order.PutUint32(b[4:], 8)
+	order.PutUint16(b[8:], 1)
+	order.PutUint16(b[10:], tag)
+	order.PutUint16(b[12:], kind)
+	order.PutUint32(b[14:], 1)
+	order.PutUint16(b[18:], value)
+	return b
+}
+
+func orientationTIFF(o Orientation) []byte {
+	return tiffBody(binary.LittleEndian, exifOrientationTag, 3, uint16(o))
+}
+
+// withJPEGExif inserts an APP1 Exif segment right after the SOI marker.
+func withJPEGExif(jpg, tiff []byte) []byte {
+	payload := append(append([]byte{}, exifHeader...), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	out = append(out, payload...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	b := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], kind)
+	b = append(b, data...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[4:]))
+}
+
+// pngHeader is a PNG signature and IHDR chunk for an 8 bit RGB image.
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	ihdr[8], ihdr[9] = 8, 2
+	return append(append([]byte{}, pngSignature...), pngChunk("IHDR", ihdr)...)
+}
+
+func webpWithExif(exif []byte) []byte {
+	chunk := binary.LittleEndian.AppendUint32([]byte("EXIF"), uint32(len(exif)))
+	chunk = append(chunk, exif...)
+	if len(exif)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	// a VP8X chunk first, so the EXIF chunk is not the first one
+	vp8x := binary.LittleEndian.AppendUint32([]byte("VP8X"), 10)
+	vp8x = append(vp8x, make([]byte, 10)...)
+	body := append(append([]byte("WEBP"), vp8x...), chunk...)
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+func TestReadOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, quadrants(16, 16))
+	rotate90 := orientationTIFF(OrientationRotate90)
+
+	tests := []struct {
+		name string
+		data []byte
+		want Orientation
+	}{
+		{"jpeg little endian", withJPEGExif(jpg, rotate90), OrientationRotate90},
+		{"jpeg big endian", withJPEGExif(jpg, tiffBody(binary.BigEndian, exifOrientationTag, 3, 8)), OrientationRotate270},
+		{"jpeg without exif", jpg, OrientationNormal},
+		{"jpeg exif without orientation", withJPEGExif(jpg, tiffBody(binary.LittleEndian, 0x010F, 2, 0)), OrientationNormal},
+		{"jpeg orientation out of range", withJPEGExif(jpg, orientationTIFF(9)), OrientationNormal},
+		{"jpeg orientation not a short", withJPEGExif(jpg, tiffBody(binary.LittleEndian, exifOrientationTag, 4, 6)), OrientationNormal},
+		{"jpeg bad byte order", withJPEGExif(jpg, append([]byte("XX"), rotate90[2:]...)), OrientationNormal},
+		{"jpeg bad magic", withJPEGExif(jpg, append([]byte("II\x2B\x00"), rotate90[4:]...)), OrientationNormal},
+		{"jpeg ifd past the end", withJPEGExif(jpg, append(rotate90[:4:4], 0xFF, 0, 0, 0)), OrientationNormal},
+		{"jpeg truncated ifd", withJPEGExif(jpg, rotate90[:14]), OrientationNormal},
+		{"jpeg segment longer than file", withJPEGExif(jpg, rotate90)[:20], OrientationNormal},
+		{"jpeg exif after start of scan", append([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}, withJPEGExif(jpg, rotate90)[2:]...), OrientationNormal},
+		{"png eXIf chunk", append(pngHeader(1, 1), pngChunk("eXIf", orientationTIFF(OrientationTranspose))...), OrientationTranspose},
+		{"png eXIf after IEND", append(append(pngHeader(1, 1), pngChunk("IEND", nil)...), pngChunk("eXIf", rotate90)...), OrientationNormal},
+		{"webp EXIF chunk", webpWithExif(orientationTIFF(OrientationFlipVertical)), OrientationFlipVertical},
+		{"webp EXIF chunk with jpeg prefix", webpWithExif(append(append([]byte{}, exifHeader...), rotate90...)), OrientationRotate90},
+		{"empty", nil, OrientationNormal},
+		{"not an image", []byte("hello, world"), OrientationNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readOrientation(tt.data); got != tt.want {
+				t.Errorf("readOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeOrientation uploads a 32×16 JPEG tagged with each of the 8
+// orientations and checks which color ends up in every corner.
+func TestNormalizeOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, quadrants(32, 16))
+
+	tests := []struct {
+		orientation             Orientation
+		width, height           int
+		topLeft, topRight       color.NRGBA
+		bottomLeft, bottomRight color.NRGBA
+	}{
+		{OrientationNormal, 32, 16, red, green, blue, white},
+		{OrientationFlipHorizontal, 32, 16, green, red, white, blue},
+		{OrientationRotate180, 32, 16, white, blue, green, red},
+		{OrientationFlipVertical, 32, 16, blue, white, red, green},
+		{OrientationTranspose, 16, 32, red, blue, green, white},
+		{OrientationRotate90, 16, 32, blue, red, white, green},
+		{OrientationTransverse, 16, 32, white, green, blue, red},
+		{OrientationRotate270, 16, 32, green, white, red, blue},
+	}
+	im := NewImaging(&godotenv.Env{})
+	for _, tt := range tests {
+		res, err := im.Normalize(bytes.NewReader(withJPEGExif(jpg, orientationTIFF(tt.orientation))))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		if res.Orientation != tt.orientation || res.Width != tt.width || res.Height != tt.height {
+			t.Errorf("orientation %d: got orientation %d, %d×%d, want %d×%d",
+				tt.orientation, res.Orientation, res.Width, res.Height, tt.width, tt.height)
+		}
+		if readOrientation(res.Data) != OrientationNormal {
+			t.Errorf("orientation %d: output still carries an orientation", tt.orientation)
+		}
+
+		out, err := jpeg.Decode(bytes.NewReader(res.Data))
+		if err != nil {
+			t.Fatalf("orientation %d: decode output: %v", tt.orientation, err)
+		}
+		// sample the middle of each quadrant, away from compression noise
+		w, h := tt.width, tt.height
+		corners := []struct {
+			name string
+			x, y int
+			want color.NRGBA
+		}{
+			{"top left", w / 4, h / 4, tt.topLeft},
+			{"top right", 3 * w / 4, h / 4, tt.topRight},
+			{"bottom left", w / 4, 3 * h / 4, tt.bottomLeft},
+			{"bottom right", 3 * w / 4, 3 * h / 4, tt.bottomRight},
+		}
+		for _, c := range corners {
+			if got := out.At(c.x, c.y); !near(got, c.want) {
+				t.Errorf("orientation %d: %s = %v, want %v", tt.orientation, c.name, got, c.want)
+			}
+		}
+	}
+}
+
+func near(c color.Color, want color.NRGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(got uint32, want uint8) bool {
+		d := int(got>>8) - int(want)
+		return d > -48 && d < 48
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+func TestNormalizeBounds(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxDimension  string
+		wantW, wantH  int
+	}{
+		{"landscape scaled down", 64, 32, "16", 16, 8},
+		{"portrait scaled down", 32, 64, "16", 8, 16},
+		{"square at the bound", 16, 16, "16", 16, 16},
+		{"never scaled up", 10, 6, "16", 10, 6},
+		{"thin strip keeps one pixel", 400, 1, "16", 16, 1},
+		{"unset uses the default", 2100, 10, "", defaultMaxDimension, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := NewImaging(&godotenv.Env{ImageMaxDimension: tt.maxDimension})
+			res, err := im.Normalize(bytes.NewReader(encodeJPEG(t, quadrants(tt.width, tt.height))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Width != tt.wantW || res.Height != tt.wantH {
+				t.Errorf("got %d×%d, want %d×%d", res.Width, res.Height, tt.wantW, tt.wantH)
+			}
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(res.Data))
+			if err != nil || cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("encoded %d×%d, %v, want %d×%d", cfg.Width, cfg.Height, err, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	translucent := quadrants(8, 8)
+	translucent.SetNRGBA(0, 0, color.NRGBA{A: 0})
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, translucent); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		data     []byte
+		wantType string
+		wantExt  string
+	}{
+		{"opaque becomes jpeg", "", encodeJPEG(t, quadrants(8, 8)), "image/jpeg", ".jpg"},
+		{"alpha stays png", "", pngData.Bytes(), "image/png", ".png"},
+		{"forced jpeg", "JPEG", pngData.Bytes(), "image/jpeg", ".jpg"},
+		{"forced png", "png", encodeJPEG(t, quadrants(8, 8)), "image/png", ".png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := NewImaging(&godotenv.Env{ImageOutputFormat: tt.format})
+			res, err := im.Normalize(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.ContentType != tt.wantType || res.Extension != tt.wantExt {
+				t.Errorf("got %s %s, want %s %s", res.ContentType, res.Extension, tt.wantType, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	jpg := encodeJPEG(t, quadrants(16, 16))
+
+	tests := []struct {
+		name        string
+		maxFileSize string
+		data        []byte
+		want        error
+	}{
+		{"file over the size limit", "100", jpg, ErrImageTooLarge},
+		{"too many pixels", "", append(pngHeader(20000, 20000), pngChunk("IEND", nil)...), ErrImageTooLarge},
+		{"not an image", "", []byte("hello, world"), ErrUnsupportedFormat},
+		{"truncated jpeg", "", jpg[:len(jpg)/2], ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := NewImaging(&godotenv.Env{MaxFileSize: tt.maxFileSize})
+			if _, err := im.Normalize(bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("Normalize error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	im := NewImaging(&godotenv.Env{MaxFileSize: "100000"})
+	if _, err := im.Normalize(bytes.NewReader(jpg)); err != nil {
+		t.Errorf("file under the size limit: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package postgresql
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+)
+
+const (
+	defaultMaxConns          = 10
+	defaultMinConns          = 5
+	defaultMaxConnLifetime   = time.Hour
+	defaultMaxConnIdleTime   = 30 * time.Minute
+	defaultHealthCheckPeriod = time.Minute
+	defaultStatementTimeout  = 30 * time.Second
+	defaultSlowQuery         = 500 * time.Millisecond
+	defaultSetupTimeout      = 10 * time.Second
+)
+
+// poolConfig holds the pool settings read from the environment, unset or
+// malformed values fall back to the defaults above.
+type poolConfig struct {
+	maxConns          int32
+	minConns          int32
+	maxConnLifetime   time.Duration
+	maxConnIdleTime   time.Duration
+	healthCheckPeriod time.Duration
+	// statementTimeout and slowQuery are disabled by 0
+	statementTimeout time.Duration
+	slowQuery        time.Duration
+}
+
+func newPoolConfig(env *godotenv.Env) poolConfig {
+	return poolConfig{
+		maxConns:          int32(envInt(env.DBMaxConns, defaultMaxConns, 1)),
+		minConns:          int32(envInt(env.DBMinConns, defaultMinConns, 0)),
+		maxConnLifetime:   envDuration(env.DBMaxConnLifetimeSeconds, time.Second, defaultMaxConnLifetime, 1),
+		maxConnIdleTime:   envDuration(env.DBMaxConnIdleSeconds, time.Second, defaultMaxConnIdleTime, 1),
+		healthCheckPeriod: envDuration(env.DBHealthCheckPeriodSeconds, time.Second, defaultHealthCheckPeriod, 1),
+		statementTimeout:  envDuration(env.DBStatementTimeoutMS, time.Millisecond, defaultStatementTimeout, 0),
+		slowQuery:         envDuration(env.DBSlowQueryMS, time.Millisecond, defaultSlowQuery, 0),
+	}
+}
+
+// SetupTimeout bounds connecting to the database at startup.
+func SetupTimeout(env *godotenv.Env) time.Duration {
+	return envDuration(env.DBSetupTimeoutSeconds, time.Second, defaultSetupTimeout, 1)
+}
+
+func envInt(raw string, def, min int) int {
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < min {
+		return def
+	}
+	return v
+}
+
+func envDuration(raw string, unit, def time.Duration, min int) time.Duration {
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < min {
+		return def
+	}
+	return time.Duration(v) * unit
+}
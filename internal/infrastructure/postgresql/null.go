@@ -0,0 +1,12 @@
+package postgresql
+
+// NullIfZero returns nil for the zero value of an optional column, so it is
+// written as NULL rather than as an empty string or 0, and v otherwise.
+// Repositories read such columns back with COALESCE to the zero value.
+func NullIfZero[T comparable](v T) interface{} {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,158 @@
+// Package pgtest runs repository tests against a real Postgres. Every test
+// gets a throwaway schema with all migrations applied, dropped again when the
+// test ends. Tests are skipped unless TEST_DATABASE_URL points at a server,
+// e.g. the one from docker-compose.yml.
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+)
+
+// EnvURL names the variable holding the connection string of the server the
+// tests may create schemas on.
+const EnvURL = "TEST_DATABASE_URL"
+
+const setupTimeout = 30 * time.Second
+
+// New returns a Postgres whose connections only see a fresh schema holding
+// every migration.
+func New(t *testing.T) *postgresql.Postgres {
+	t.Helper()
+
+	dsn := os.Getenv(EnvURL)
+	if dsn == "" {
+		t.Skipf("%s is not set", EnvURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", EnvURL, err)
+	}
+	if err := createExtensions(ctx, admin); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("create extensions: %v", err)
+	}
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+		defer cancel()
+		if _, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		admin.Close(ctx)
+	})
+
+	scoped, err := withSearchPath(dsn, schema)
+	if err != nil {
+		t.Fatalf("parse %s: %v", EnvURL, err)
+	}
+	if err := migrate(ctx, scoped); err != nil {
+		t.Fatalf("migrate schema %s: %v", schema, err)
+	}
+
+	pg := postgresql.NewPostgres(&godotenv.Env{DatabaseURL: scoped}, Logger())
+	if err := pg.Setup(ctx); err != nil {
+		t.Fatalf("setup postgres: %v", err)
+	}
+	// registered after the schema cleanup so it runs first
+	t.Cleanup(func() { pg.Close() })
+
+	return pg
+}
+
+// Logger discards everything, repositories under test log their failures
+// which the assertions already report.
+func Logger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// createExtensions installs the extensions of the migrations into public
+// once, so the per-schema migrations find them instead of racing to create
+// them from parallel test binaries.
+func createExtensions(ctx context.Context, conn *pgx.Conn) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('pgtest'))"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS "uuid-ossp" SCHEMA public`)
+		return err
+	})
+}
+
+// migrate applies every up migration, in file name order, to the schema
+// the connection string points at.
+func migrate(ctx context.Context, dsn string) error {
+	files, err := filepath.Glob(filepath.Join(migrationDir(), "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations in %s", migrationDir())
+	}
+	sort.Strings(files)
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// without arguments Exec uses the simple protocol, which accepts
+		// several statements at once
+		if _, err := conn.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+func migrationDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "migration")
+}
+
+// withSearchPath adds search_path to a URL or keyword/value connection
+// string. public stays on the path for the extensions.
+func withSearchPath(dsn, schema string) (string, error) {
+	path := schema + ",public"
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + path, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("search_path", path)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -3,6 +3,9 @@ package postgresql
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"strconv"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/playture/backend/internal/infrastructure/godotenv"
 	"github.com/playture/backend/utils"
@@ -10,62 +13,134 @@ import (
 
 type Postgres struct {
 	PrimaryConn *pgxpool.Pool
+	// ReplicaConn is nil unless DATABASE_REPLICA_URL is set
+	ReplicaConn *pgxpool.Pool
 	Env         *godotenv.Env
+	logger      *slog.Logger
+}
+
+// PoolStats is a snapshot of one connection pool, for health reporting.
+type PoolStats struct {
+	TotalConns        int32 `json:"totalConns"`
+	IdleConns         int32 `json:"idleConns"`
+	AcquiredConns     int32 `json:"acquiredConns"`
+	MaxConns          int32 `json:"maxConns"`
+	AcquireCount      int64 `json:"acquireCount"`
+	EmptyAcquireCount int64 `json:"emptyAcquireCount"`
+	AcquireDurationMS int64 `json:"acquireDurationMs"`
 }
 
-func NewPostgres(env *godotenv.Env) *Postgres {
+func NewPostgres(env *godotenv.Env, logger *slog.Logger) *Postgres {
 	return &Postgres{
-		Env: env,
+		Env:    env,
+		logger: logger,
 	}
 }
 
 func (p *Postgres) Setup(ctx context.Context) error {
-	if p.PrimaryConn != nil {
-		p.PrimaryConn.Close()
-	}
+	p.Close()
+	p.ReplicaConn = nil
+	cfg := newPoolConfig(p.Env)
 	var err error
 
-	p.PrimaryConn, err = createConnection(ctx, p.Env.DatabaseURL, 10, 5)
+	p.PrimaryConn, err = p.createConnection(ctx, "primary", p.Env.DatabaseURL, cfg)
 	if err != nil {
 		return utils.WrapError("failed to setup primary connection: ", err)
 	}
 
+	if p.Env.DatabaseReplicaURL != "" {
+		p.ReplicaConn, err = p.createConnection(ctx, "replica", p.Env.DatabaseReplicaURL, cfg)
+		if err != nil {
+			return utils.WrapError("failed to setup replica connection: ", err)
+		}
+	}
+
 	return nil
 }
 
+// HealthCheck pings every configured pool.
 func (p *Postgres) HealthCheck(ctx context.Context) error {
 	if p.PrimaryConn == nil {
 		return errors.New("one or both PostgreSQL connection pools are not initialized")
 	}
 
-	query := `SELECT 1;`
-
-	row := p.PrimaryConn.QueryRow(ctx, query)
-	var result int
-	if err := row.Scan(&result); err != nil {
-		return errors.New("health check query failed on primary connection: " + err.Error())
+	if err := checkPool(ctx, p.PrimaryConn); err != nil {
+		return utils.WrapError("primary connection", err)
 	}
-	if result != 1 {
-		return errors.New("unexpected result from health check query on primary connection")
+	if p.ReplicaConn != nil {
+		if err := checkPool(ctx, p.ReplicaConn); err != nil {
+			return utils.WrapError("replica connection", err)
+		}
 	}
 
 	return nil
 }
 
+// Stats reports each configured pool by name, "primary" and "replica".
+func (p *Postgres) Stats() map[string]PoolStats {
+	stats := map[string]PoolStats{}
+	if p.PrimaryConn != nil {
+		stats["primary"] = poolStats(p.PrimaryConn)
+	}
+	if p.ReplicaConn != nil {
+		stats["replica"] = poolStats(p.ReplicaConn)
+	}
+	return stats
+}
+
 func (p *Postgres) Close() error {
 	if p.PrimaryConn != nil {
 		p.PrimaryConn.Close()
 	}
+	if p.ReplicaConn != nil {
+		p.ReplicaConn.Close()
+	}
 	return nil
 }
 
-func createConnection(ctx context.Context, connString string, maxConns, minConns int) (*pgxpool.Pool, error) {
+func checkPool(ctx context.Context, pool *pgxpool.Pool) error {
+	query := `SELECT 1;`
+
+	row := pool.QueryRow(ctx, query)
+	var result int
+	if err := row.Scan(&result); err != nil {
+		return errors.New("health check query failed: " + err.Error())
+	}
+	if result != 1 {
+		return errors.New("unexpected result from health check query")
+	}
+	return nil
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	s := pool.Stat()
+	return PoolStats{
+		TotalConns:        s.TotalConns(),
+		IdleConns:         s.IdleConns(),
+		AcquiredConns:     s.AcquiredConns(),
+		MaxConns:          s.MaxConns(),
+		AcquireCount:      s.AcquireCount(),
+		EmptyAcquireCount: s.EmptyAcquireCount(),
+		AcquireDurationMS: s.AcquireDuration().Milliseconds(),
+	}
+}
+
+func (p *Postgres) createConnection(ctx context.Context, name, connString string, cfg poolConfig) (*pgxpool.Pool, error) {
 	connConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, utils.WrapError("failed to parse connection string: ", err)
 	}
-	connConfig.MaxConns = int32(maxConns)
-	connConfig.MinConns = int32(minConns)
+	connConfig.MaxConns = cfg.maxConns
+	connConfig.MinConns = min(cfg.minConns, cfg.maxConns)
+	connConfig.MaxConnLifetime = cfg.maxConnLifetime
+	connConfig.MaxConnIdleTime = cfg.maxConnIdleTime
+	connConfig.HealthCheckPeriod = cfg.healthCheckPeriod
+	if cfg.statementTimeout > 0 {
+		connConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.statementTimeout.Milliseconds(), 10)
+	}
+	if cfg.slowQuery > 0 {
+		connConfig.ConnConfig.Tracer = newSlowQueryTracer(p.logger, name, cfg.slowQuery)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, connConfig)
 	if err != nil {
@@ -0,0 +1,73 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Querier is what repositories run statements on, satisfied by both a
+// pgx.Tx and the connection pool.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+type (
+	txContextKey      struct{}
+	primaryContextKey struct{}
+)
+
+// WithTx returns a copy of ctx carrying tx. Repositories called with it run
+// their statements inside tx.
+func WithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// TxFromContext returns the transaction stored by WithTx.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(pgx.Tx)
+	return tx, ok && tx != nil
+}
+
+// Querier resolves where a statement runs: the transaction carried by ctx
+// if there is one, the primary pool otherwise.
+func (p *Postgres) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return p.PrimaryConn
+}
+
+// WithPrimary makes Reader use the primary for ctx, for callers that have
+// to see a write they just made, which the replica may not have yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// Reader resolves where a read runs. Inside a transaction that is the
+// transaction, otherwise the replica unless there is none or ctx was marked
+// with WithPrimary. Reads from the replica can lag behind the primary.
+func (p *Postgres) Reader(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return p.ReadPool(ctx)
+}
+
+// ReadPool is the pool Reader uses outside a transaction, for read only
+// transactions that can run on the replica.
+func (p *Postgres) ReadPool(ctx context.Context) *pgxpool.Pool {
+	if p.ReplicaConn == nil {
+		return p.PrimaryConn
+	}
+	if primary, _ := ctx.Value(primaryContextKey{}).(bool); primary {
+		return p.PrimaryConn
+	}
+	return p.ReplicaConn
+}
@@ -0,0 +1,51 @@
+package postgresql
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql string
+	at  time.Time
+}
+
+// slowQueryTracer logs every statement that takes longer than threshold.
+// Arguments are left out of the log, they carry customer data.
+type slowQueryTracer struct {
+	logger    *slog.Logger
+	threshold time.Duration
+}
+
+func newSlowQueryTracer(logger *slog.Logger, pool string, threshold time.Duration) *slowQueryTracer {
+	return &slowQueryTracer{
+		logger:    logger.With("layer", "Postgres", "pool", pool),
+		threshold: threshold,
+	}
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, at: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	if elapsed < t.threshold {
+		return
+	}
+	t.logger.Warn("slow query",
+		"sql", start.sql,
+		"duration", elapsed,
+		"rows", data.CommandTag.RowsAffected(),
+		"err", data.Err,
+	)
+}
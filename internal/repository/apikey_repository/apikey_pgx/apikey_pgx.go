@@ -0,0 +1,146 @@
+package apiKeyPGX
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	apiKeyRepository "github.com/playture/backend/internal/repository/apikey_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO admin_api_keys (
+			name, key_prefix, key_hash, role, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id`
+
+	selectColumns = `
+		id, name, key_prefix, key_hash, role,
+		COALESCE(last_used_at, 0), COALESCE(revoked_at, 0),
+		created_at, updated_at`
+
+	findByHashQuery = `SELECT ` + selectColumns + ` FROM admin_api_keys WHERE key_hash = $1 LIMIT 1`
+
+	listQuery = `SELECT ` + selectColumns + ` FROM admin_api_keys ORDER BY created_at DESC, id DESC`
+
+	revokeQuery = `
+		UPDATE admin_api_keys SET revoked_at = $2, updated_at = $2
+		WHERE id = $1 AND revoked_at IS NULL`
+
+	touchQuery = `UPDATE admin_api_keys SET last_used_at = $2 WHERE id = $1`
+)
+
+type APIKeyPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewAPIKeyPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *APIKeyPgx {
+	return &APIKeyPgx{
+		logger:   logger.With("layer", "APIKeyRepository"),
+		postgres: postgres,
+	}
+}
+
+func (a *APIKeyPgx) Create(ctx context.Context, key *entity.AdminAPIKey) (string, error) {
+	lg := a.logger.With("method", "Create")
+
+	args := []interface{}{
+		key.Name, key.KeyPrefix, key.KeyHash, key.Role, key.CreatedAt, key.UpdatedAt,
+	}
+
+	var (
+		id  string
+		err error
+	)
+	err = a.postgres.Querier(ctx).QueryRow(ctx, createQuery, args...).Scan(&id)
+	if err != nil {
+		lg.Error("Create failed", "err", err)
+		return "", utils.WrapError("create api key", err)
+	}
+	return id, nil
+}
+
+func (a *APIKeyPgx) FindByHash(ctx context.Context, hash string) (*entity.AdminAPIKey, error) {
+	lg := a.logger.With("method", "FindByHash")
+
+	// always the primary, a lagging replica would still accept revoked keys
+	row := a.postgres.Querier(ctx).QueryRow(ctx, findByHashQuery, hash)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apiKeyRepository.ErrAPIKeyNotFound
+		}
+		lg.Error("FindByHash failed", "err", err)
+		return nil, utils.WrapError("find api key", err)
+	}
+	return key, nil
+}
+
+func (a *APIKeyPgx) List(ctx context.Context) ([]*entity.AdminAPIKey, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	rows, err = a.postgres.Reader(ctx).Query(ctx, listQuery)
+	if err != nil {
+		return nil, utils.WrapError("list api keys", err)
+	}
+	defer rows.Close()
+
+	keys := []*entity.AdminAPIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan api key", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list api keys", err)
+	}
+	return keys, nil
+}
+
+func (a *APIKeyPgx) Revoke(ctx context.Context, id string, at int64) error {
+	lg := a.logger.With("method", "Revoke")
+	cmd, err := a.postgres.Querier(ctx).Exec(ctx, revokeQuery, id, at)
+	if err != nil {
+		lg.Error("Revoke failed", "id", id, "err", err)
+		return utils.WrapError("revoke api key", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return apiKeyRepository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (a *APIKeyPgx) Touch(ctx context.Context, id string, at int64) error {
+	if _, err := a.postgres.Querier(ctx).Exec(ctx, touchQuery, id, at); err != nil {
+		return utils.WrapError("touch api key", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*entity.AdminAPIKey, error) {
+	key := &entity.AdminAPIKey{}
+	err := row.Scan(
+		&key.ID, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.Role,
+		&key.LastUsedAt, &key.RevokedAt,
+		&key.CreatedAt, &key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,20 @@
+package apiKeyRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+type Repository interface {
+	Create(ctx context.Context, key *entity.AdminAPIKey) (string, error)
+	FindByHash(ctx context.Context, hash string) (*entity.AdminAPIKey, error)
+	List(ctx context.Context) ([]*entity.AdminAPIKey, error)
+	Revoke(ctx context.Context, id string, at int64) error
+	Touch(ctx context.Context, id string, at int64) error
+}
@@ -0,0 +1,134 @@
+package auditPGX
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	auditRepository "github.com/playture/backend/internal/repository/audit_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO audit_events (
+			actor_id, actor_name, ip_address, action,
+			entity_type, entity_id, changes, created_at
+		) VALUES (
+			$1, $2, $3, $4,
+			$5, $6, $7, $8
+		) RETURNING id`
+
+	listQuery = `
+		SELECT
+			id, actor_id, actor_name, COALESCE(ip_address, ''), action,
+			entity_type, entity_id, changes, created_at
+		FROM audit_events
+		%s
+		ORDER BY %s
+		LIMIT $%d`
+)
+
+type AuditPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewAuditPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *AuditPgx {
+	return &AuditPgx{
+		logger:   logger.With("layer", "AuditRepository"),
+		postgres: postgres,
+	}
+}
+
+func (a *AuditPgx) Create(ctx context.Context, event *entity.AuditEvent) (string, error) {
+	lg := a.logger.With("method", "Create")
+
+	args := []interface{}{
+		event.ActorID, event.ActorName, event.IPAddress, event.Action,
+		event.EntityType, event.EntityID, event.Changes, event.CreatedAt,
+	}
+
+	var (
+		id  string
+		err error
+	)
+	err = a.postgres.Querier(ctx).QueryRow(ctx, createQuery, args...).Scan(&id)
+	if err != nil {
+		lg.Error("Create failed", "action", event.Action, "err", err)
+		return "", utils.WrapError("create audit event", err)
+	}
+	return id, nil
+}
+
+func (a *AuditPgx) List(
+	ctx context.Context,
+	filter auditRepository.Filter,
+	page pagination.Request,
+) (*pagination.Page[*entity.AuditEvent], error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+	if filter.EntityID != "" {
+		args = append(args, filter.EntityID)
+		conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if filter.ActorID != "" {
+		args = append(args, filter.ActorID)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+
+	keyset, err := page.Keyset(len(args) + 1)
+	if err != nil {
+		return nil, utils.WrapError("list audit events", err)
+	}
+	if keyset.Condition != "" {
+		conditions = append(conditions, keyset.Condition)
+		args = append(args, keyset.Args...)
+	}
+	args = append(args, keyset.Limit)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := fmt.Sprintf(listQuery, where, keyset.OrderBy, len(args))
+
+	var rows pgx.Rows
+	rows, err = a.postgres.Reader(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, utils.WrapError("list audit events", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.AuditEvent
+	for rows.Next() {
+		event := &entity.AuditEvent{}
+		if err := rows.Scan(
+			&event.ID, &event.ActorID, &event.ActorName, &event.IPAddress, &event.Action,
+			&event.EntityType, &event.EntityID, &event.Changes, &event.CreatedAt,
+		); err != nil {
+			return nil, utils.WrapError("scan audit event", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list audit events", err)
+	}
+
+	return pagination.Build(keyset, events, func(event *entity.AuditEvent) (int64, uuid.UUID) {
+		return event.CreatedAt, event.ID
+	}), nil
+}
@@ -0,0 +1,21 @@
+package auditRepository
+
+import (
+	"context"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/pagination"
+)
+
+// Filter narrows down List, zero values are ignored.
+type Filter struct {
+	EntityType entity.AuditEntityType
+	EntityID   string
+	ActorID    string
+}
+
+// Repository is append-only, audit events are never updated or deleted.
+type Repository interface {
+	Create(ctx context.Context, event *entity.AuditEvent) (string, error)
+	List(ctx context.Context, filter Filter, page pagination.Request) (*pagination.Page[*entity.AuditEvent], error)
+}
@@ -0,0 +1,62 @@
+package batch
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+)
+
+var (
+	ErrTxRequired = errors.New("batch writes must run inside a unit of work")
+	// ErrAborted marks rows that were not attempted because an earlier row
+	// of the same batch failed and aborted the transaction.
+	ErrAborted = errors.New("not applied, an earlier row of the batch failed")
+)
+
+// Outcome reports what a batch write did with one row. Outcomes come back in
+// the order of the input, Err is nil for rows that were applied.
+type Outcome struct {
+	ID  string `json:"id"`
+	Err error  `json:"-"`
+}
+
+// Failed returns the outcomes of rows that were not applied.
+func Failed(outcomes []Outcome) []Outcome {
+	var failed []Outcome
+	for _, o := range outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
+// RequireTx fails unless ctx carries a transaction. Batch writes touch many
+// rows, running them outside a transaction would leave partial results
+// behind on failure.
+func RequireTx(ctx context.Context) error {
+	if _, ok := postgresql.TxFromContext(ctx); !ok {
+		return ErrTxRequired
+	}
+	return nil
+}
+
+// ParseIDs prepares an outcome per id. Malformed ids cannot match a row, they
+// get notFound right away and are left out of the returned keys.
+func ParseIDs(ids []string, notFound error) ([]Outcome, []uuid.UUID) {
+	outcomes := make([]Outcome, len(ids))
+	keys := make([]uuid.UUID, 0, len(ids))
+	for i, id := range ids {
+		outcomes[i].ID = id
+		key, err := uuid.Parse(id)
+		if err != nil {
+			outcomes[i].Err = notFound
+			continue
+		}
+		outcomes[i].ID = key.String()
+		keys = append(keys, key)
+	}
+	return outcomes, keys
+}
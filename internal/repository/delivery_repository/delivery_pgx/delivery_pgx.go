@@ -0,0 +1,180 @@
+package deliveryPGX
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	queueQuery = `
+		INSERT INTO deliveries (order_id, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (order_id) DO UPDATE
+		SET status = EXCLUDED.status, attempts = 0, last_error = NULL, updated_at = EXCLUDED.updated_at`
+
+	selectColumns = `
+		order_id, status, COALESCE(token, ''), download_count, COALESCE(last_downloaded_at, 0),
+		COALESCE(email_sent_at, 0), attempts, COALESCE(last_error, ''), COALESCE(delivered_at, 0),
+		created_at, updated_at`
+
+	claimPendingQuery = `
+		UPDATE deliveries SET status = $1, attempts = attempts + 1, updated_at = $4
+		WHERE order_id IN (
+			SELECT order_id FROM deliveries
+			WHERE status = $2 OR (status = $1 AND updated_at < $3)
+			ORDER BY updated_at, order_id
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + selectColumns
+
+	updateQuery = `
+		UPDATE deliveries SET
+			status=$2, token=$3, email_sent_at=$4, attempts=$5, last_error=$6, delivered_at=$7, updated_at=$8
+		WHERE order_id=$1`
+
+	listExpiredQuery = `SELECT ` + selectColumns + `
+		FROM deliveries d
+		JOIN orders o ON o.id = d.order_id
+		WHERE d.status = $1 AND o.expires_at < $2
+		ORDER BY o.expires_at, d.order_id
+		LIMIT $3`
+
+	findByOrderQuery = `SELECT ` + selectColumns + ` FROM deliveries WHERE order_id = $1`
+
+	findByTokenQuery = `SELECT ` + selectColumns + ` FROM deliveries WHERE token = $1`
+
+	recordDownloadQuery = `
+		UPDATE deliveries SET download_count = download_count + 1, last_downloaded_at = $2
+		WHERE token = $1`
+)
+
+type DeliveryPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewDeliveryPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *DeliveryPgx {
+	return &DeliveryPgx{
+		logger:   logger.With("layer", "DeliveryRepository"),
+		postgres: postgres,
+	}
+}
+
+func (d *DeliveryPgx) Queue(ctx context.Context, orderID string, at int64) error {
+	if _, err := d.postgres.Querier(ctx).Exec(ctx, queueQuery, orderID, entity.DeliveryStatusPending, at); err != nil {
+		d.logger.Error("Queue failed", "method", "Queue", "orderID", orderID, "err", err)
+		return utils.WrapError("queue delivery", err)
+	}
+	return nil
+}
+
+func (d *DeliveryPgx) ClaimPending(ctx context.Context, staleBefore, at int64, limit int) ([]*entity.Delivery, error) {
+	rows, err := d.postgres.Querier(ctx).Query(ctx, claimPendingQuery,
+		entity.DeliveryStatusSending, entity.DeliveryStatusPending, staleBefore, at, limit)
+	if err != nil {
+		d.logger.Error("ClaimPending failed", "method", "ClaimPending", "err", err)
+		return nil, utils.WrapError("claim deliveries", err)
+	}
+	return collect(rows, "claim deliveries")
+}
+
+func (d *DeliveryPgx) ListExpired(ctx context.Context, before int64, limit int) ([]*entity.Delivery, error) {
+	rows, err := d.postgres.Reader(ctx).Query(ctx, listExpiredQuery, entity.DeliveryStatusDelivered, before, limit)
+	if err != nil {
+		d.logger.Error("ListExpired failed", "method", "ListExpired", "err", err)
+		return nil, utils.WrapError("list expired deliveries", err)
+	}
+	return collect(rows, "list expired deliveries")
+}
+
+// collect scans and closes rows, msg wraps any error.
+func collect(rows pgx.Rows, msg string) ([]*entity.Delivery, error) {
+	defer rows.Close()
+
+	deliveries := []*entity.Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan delivery", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError(msg, err)
+	}
+	return deliveries, nil
+}
+
+func (d *DeliveryPgx) Update(ctx context.Context, delivery *entity.Delivery) error {
+	lg := d.logger.With("method", "Update")
+
+	cmd, err := d.postgres.Querier(ctx).Exec(ctx, updateQuery,
+		delivery.OrderID, delivery.Status, postgresql.NullIfZero(delivery.Token), postgresql.NullIfZero(delivery.EmailSentAt),
+		delivery.Attempts, postgresql.NullIfZero(delivery.LastError), postgresql.NullIfZero(delivery.DeliveredAt),
+		delivery.UpdatedAt,
+	)
+	if err != nil {
+		lg.Error("Update failed", "orderID", delivery.OrderID, "err", err)
+		return utils.WrapError("update delivery", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return deliveryRepository.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (d *DeliveryPgx) FindByOrder(ctx context.Context, orderID string) (*entity.Delivery, error) {
+	return d.find(ctx, "FindByOrder", findByOrderQuery, orderID)
+}
+
+func (d *DeliveryPgx) FindByToken(ctx context.Context, token string) (*entity.Delivery, error) {
+	return d.find(ctx, "FindByToken", findByTokenQuery, token)
+}
+
+func (d *DeliveryPgx) find(ctx context.Context, method, query string, value string) (*entity.Delivery, error) {
+	delivery, err := scanDelivery(d.postgres.Reader(ctx).QueryRow(ctx, query, value))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, deliveryRepository.ErrDeliveryNotFound
+		}
+		d.logger.Error(method+" failed", "method", method, "err", err)
+		return nil, utils.WrapError("find delivery", err)
+	}
+	return delivery, nil
+}
+
+func (d *DeliveryPgx) RecordDownload(ctx context.Context, token string, at int64) error {
+	cmd, err := d.postgres.Querier(ctx).Exec(ctx, recordDownloadQuery, token, at)
+	if err != nil {
+		d.logger.Error("RecordDownload failed", "method", "RecordDownload", "err", err)
+		return utils.WrapError("record download", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return deliveryRepository.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func scanDelivery(row pgx.Row) (*entity.Delivery, error) {
+	delivery := &entity.Delivery{}
+	err := row.Scan(
+		&delivery.OrderID, &delivery.Status, &delivery.Token, &delivery.DownloadCount, &delivery.LastDownloadedAt,
+		&delivery.EmailSentAt, &delivery.Attempts, &delivery.LastError, &delivery.DeliveredAt,
+		&delivery.CreatedAt, &delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,186 @@
+package deliveryPGX
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobPGX "github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_pgx"
+)
+
+var _ deliveryRepository.Repository = (*DeliveryPgx)(nil)
+
+type fixture struct {
+	repo   *DeliveryPgx
+	orders *order_pgx.OrderPgx
+	// newOrderID inserts a job and an order for deliveries to point at.
+	newOrderID func(t *testing.T) string
+}
+
+func newFixture(t *testing.T) fixture {
+	pg := pgtest.New(t)
+	jobs := jobPGX.NewJobPgx(pgtest.Logger(), pg)
+	orders := order_pgx.NewOrderPgx(pgtest.Logger(), pg)
+	return fixture{
+		repo:   NewDeliveryPgx(pgtest.Logger(), pg),
+		orders: orders,
+		newOrderID: func(t *testing.T) string {
+			t.Helper()
+			ctx := context.Background()
+			jobID, err := jobs.Create(ctx, jobContract.NewJob(1_700_000_000))
+			if err != nil {
+				t.Fatalf("create job: %v", err)
+			}
+			order := order_contract.NewOrder(uuid.MustParse(jobID), 1_700_000_000)
+			if _, err := orders.Create(ctx, order); err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+			return order.ID.String()
+		},
+	}
+}
+
+func (f fixture) claim(t *testing.T, staleBefore, at int64) []*entity.Delivery {
+	t.Helper()
+	claimed, err := f.repo.ClaimPending(context.Background(), staleBefore, at, 10)
+	if err != nil {
+		t.Fatalf("ClaimPending: %v", err)
+	}
+	sort.Slice(claimed, func(a, b int) bool { return claimed[a].CreatedAt < claimed[b].CreatedAt })
+	return claimed
+}
+
+func TestQueueClaimAndDeliver(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	first, second := f.newOrderID(t), f.newOrderID(t)
+	for i, id := range []string{second, first} {
+		if err := f.repo.Queue(ctx, id, int64(1_700_000_200-i*100)); err != nil {
+			t.Fatalf("Queue: %v", err)
+		}
+	}
+
+	claimed := f.claim(t, 1_700_000_000, 1_700_000_250)
+	if len(claimed) != 2 || claimed[0].OrderID.String() != first || claimed[1].OrderID.String() != second {
+		t.Fatalf("ClaimPending = %+v, want first and second", claimed)
+	}
+	for _, delivery := range claimed {
+		if delivery.Status != entity.DeliveryStatusSending || delivery.Attempts != 1 || delivery.UpdatedAt != 1_700_000_250 {
+			t.Errorf("claimed delivery = %+v, want sending with one attempt", delivery)
+		}
+	}
+	if claimed := f.claim(t, 1_700_000_000, 1_700_000_260); len(claimed) != 0 {
+		t.Errorf("ClaimPending while sending = %+v, want none", claimed)
+	}
+
+	delivery := claimed[0]
+	delivery.Status, delivery.Token = entity.DeliveryStatusDelivered, "tok-1"
+	delivery.EmailSentAt, delivery.DeliveredAt, delivery.UpdatedAt = 1_700_000_300, 1_700_000_300, 1_700_000_300
+	if err := f.repo.Update(ctx, delivery); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// second was never recorded, once its claim is stale it is claimed again
+	claimed = f.claim(t, 1_700_000_400, 1_700_000_500)
+	if len(claimed) != 1 || claimed[0].OrderID.String() != second || claimed[0].Attempts != 2 {
+		t.Errorf("ClaimPending after delivery = %+v, want second on its second attempt", claimed)
+	}
+
+	got, err := f.repo.FindByToken(ctx, "tok-1")
+	if err != nil {
+		t.Fatalf("FindByToken: %v", err)
+	}
+	if got.OrderID.String() != first || got.Status != entity.DeliveryStatusDelivered || got.EmailSentAt != 1_700_000_300 {
+		t.Errorf("FindByToken = %+v, want the delivered first order", got)
+	}
+}
+
+func TestRequeueKeepsDownloads(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	orderID := f.newOrderID(t)
+	if err := f.repo.Queue(ctx, orderID, 1_700_000_100); err != nil {
+		t.Fatalf("Queue: %v", err)
+	}
+	delivery, err := f.repo.FindByOrder(ctx, orderID)
+	if err != nil {
+		t.Fatalf("FindByOrder: %v", err)
+	}
+	delivery.Status, delivery.Token, delivery.Attempts, delivery.LastError = entity.DeliveryStatusFailed, "tok-1", 5, "smtp down"
+	if err := f.repo.Update(ctx, delivery); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := f.repo.RecordDownload(ctx, "tok-1", 1_700_000_200); err != nil {
+			t.Fatalf("RecordDownload: %v", err)
+		}
+	}
+
+	if err := f.repo.Queue(ctx, orderID, 1_700_000_300); err != nil {
+		t.Fatalf("Queue again: %v", err)
+	}
+	got, err := f.repo.FindByOrder(ctx, orderID)
+	if err != nil {
+		t.Fatalf("FindByOrder: %v", err)
+	}
+	if got.Status != entity.DeliveryStatusPending || got.Attempts != 0 || got.LastError != "" {
+		t.Errorf("requeued delivery = %+v, want pending with attempts reset", got)
+	}
+	if got.DownloadCount != 2 || got.LastDownloadedAt != 1_700_000_200 || got.Token != "tok-1" || got.CreatedAt != 1_700_000_100 {
+		t.Errorf("requeued delivery = %+v, want downloads, token and created_at kept", got)
+	}
+
+	if err := f.repo.RecordDownload(ctx, "unknown", 1_700_000_400); !errors.Is(err, deliveryRepository.ErrDeliveryNotFound) {
+		t.Errorf("RecordDownload(unknown) error = %v, want ErrDeliveryNotFound", err)
+	}
+	if _, err := f.repo.FindByOrder(ctx, uuid.NewString()); !errors.Is(err, deliveryRepository.ErrDeliveryNotFound) {
+		t.Errorf("FindByOrder(unknown) error = %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func TestListExpired(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	deliver := func(expiresAt int64, status entity.DeliveryStatus) string {
+		orderID := f.newOrderID(t)
+		order, err := f.orders.FindByField(ctx, "id", orderID)
+		if err != nil {
+			t.Fatalf("find order: %v", err)
+		}
+		order.ExpiresAt = expiresAt
+		if err := f.orders.UpdateExpiry(ctx, order); err != nil {
+			t.Fatalf("UpdateExpiry: %v", err)
+		}
+		if err := f.repo.Queue(ctx, orderID, 1_700_000_000); err != nil {
+			t.Fatalf("Queue: %v", err)
+		}
+		delivery := &entity.Delivery{OrderID: order.ID, Status: status, Token: uuid.NewString(), UpdatedAt: 1_700_000_100}
+		if err := f.repo.Update(ctx, delivery); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		return orderID
+	}
+	late := deliver(1_700_000_200, entity.DeliveryStatusDelivered)
+	early := deliver(1_700_000_100, entity.DeliveryStatusDelivered)
+	deliver(1_700_000_500, entity.DeliveryStatusDelivered)
+	deliver(1_700_000_100, entity.DeliveryStatusRevoked)
+	deliver(0, entity.DeliveryStatusDelivered)
+
+	expired, err := f.repo.ListExpired(ctx, 1_700_000_300, 10)
+	if err != nil {
+		t.Fatalf("ListExpired: %v", err)
+	}
+	if len(expired) != 2 || expired[0].OrderID.String() != early || expired[1].OrderID.String() != late {
+		t.Errorf("ListExpired = %+v, want early then late", expired)
+	}
+}
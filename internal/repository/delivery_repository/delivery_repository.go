@@ -0,0 +1,33 @@
+package deliveryRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+type Repository interface {
+	// Queue marks the delivery of an order pending, creating it on first
+	// use. A queued delivery keeps its download count and token until the
+	// worker issues a new one.
+	Queue(ctx context.Context, orderID string, at int64) error
+	// ClaimPending moves up to limit deliveries to SENDING at the given unix
+	// second and counts an attempt on each: pending ones longest waiting
+	// first, and sending ones claimed before staleBefore whose worker never
+	// recorded the attempt. The claim needs no transaction, concurrent
+	// callers never get the same delivery.
+	ClaimPending(ctx context.Context, staleBefore, at int64, limit int) ([]*entity.Delivery, error)
+	// Update writes the outcome of a delivery attempt: status, token, email,
+	// attempts and error.
+	Update(ctx context.Context, delivery *entity.Delivery) error
+	// ListExpired returns up to limit delivered deliveries whose order's
+	// access expired before the given unix second, soonest expired first.
+	ListExpired(ctx context.Context, before int64, limit int) ([]*entity.Delivery, error)
+	FindByOrder(ctx context.Context, orderID string) (*entity.Delivery, error)
+	FindByToken(ctx context.Context, token string) (*entity.Delivery, error)
+	// RecordDownload counts one download through token.
+	RecordDownload(ctx context.Context, token string, at int64) error
+}
@@ -0,0 +1,550 @@
+package jobContract
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const txTimeout = 5 * time.Second
+
+// Fixture is one implementation under test, with a unit of work whose
+// transactions its batch writes accept.
+type Fixture struct {
+	Repo jobRepository.Repository
+	UOW  uow.IUOW
+}
+
+// Run checks the behaviour every jobRepository.Repository has to share.
+// newFixture is called for each subtest and must return an empty store.
+func Run(t *testing.T, newFixture func(t *testing.T) Fixture) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f Fixture)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"FindByFieldNotFound", testFindByFieldNotFound},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"ListFilters", testListFilters},
+		{"ListPaginates", testListPaginates},
+		{"ClaimNext", testClaimNext},
+		{"ClaimNextByPriority", testClaimNextByPriority},
+		{"Leases", testLeases},
+		{"TargetedUpdates", testTargetedUpdates},
+		{"BatchRequiresTx", testBatchRequiresTx},
+		{"BatchOutcomes", testBatchOutcomes},
+		{"Rollback", testRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newFixture(t))
+		})
+	}
+}
+
+// NewJob returns a valid job created at the given unix second.
+func NewJob(createdAt int64) *entity.Job {
+	return &entity.Job{
+		UserEmail:       "someone@example.com",
+		UserName:        "Someone",
+		InputImageURL:   "https://example.com/in.jpg",
+		InputImageS3Key: "in/" + uuid.NewString() + ".jpg",
+		Style:           "cinematic",
+		Status:          entity.JobStatusReceived,
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+	}
+}
+
+func create(t *testing.T, repo jobRepository.Repository, job *entity.Job) *entity.Job {
+	t.Helper()
+	id, err := repo.Create(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	found, err := repo.FindByField(context.Background(), "id", id)
+	if err != nil {
+		t.Fatalf("FindByField(id, %s): %v", id, err)
+	}
+	return found
+}
+
+func testCreateAndFind(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	job := NewJob(1_700_000_000)
+	job.UserEmail = "find-me@example.com"
+
+	id, err := f.Repo.Create(ctx, job)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("Create returned %q, want a uuid", id)
+	}
+
+	byID, err := f.Repo.FindByField(ctx, "id", id)
+	if err != nil {
+		t.Fatalf("FindByField(id): %v", err)
+	}
+	if byID.ID.String() != id || byID.UserEmail != job.UserEmail || byID.Status != job.Status || byID.CreatedAt != job.CreatedAt {
+		t.Errorf("FindByField(id) = %+v, want the created job", byID)
+	}
+
+	byEmail, err := f.Repo.FindByField(ctx, "user_email", "find-me@example.com")
+	if err != nil {
+		t.Fatalf("FindByField(user_email): %v", err)
+	}
+	if byEmail.ID != byID.ID {
+		t.Errorf("FindByField(user_email) found %s, want %s", byEmail.ID, byID.ID)
+	}
+}
+
+func testFindByFieldNotFound(t *testing.T, f Fixture) {
+	_, err := f.Repo.FindByField(context.Background(), "id", uuid.NewString())
+	if !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("FindByField(unknown id) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testUpdate(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	job := create(t, f.Repo, NewJob(1_700_000_000))
+
+	job.Status = entity.JobStatusFailed
+	job.ErrorMessage = "boom"
+	job.RetryCount = 2
+	job.UpdatedAt = 1_700_000_100
+	if err := f.Repo.Update(ctx, job); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := f.Repo.FindByField(ctx, "id", job.ID.String())
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if got.Status != entity.JobStatusFailed || got.ErrorMessage != "boom" || got.RetryCount != 2 || got.UpdatedAt != 1_700_000_100 {
+		t.Errorf("after Update got %+v", got)
+	}
+
+	missing := NewJob(1_700_000_000)
+	missing.ID = uuid.New()
+	if err := f.Repo.Update(ctx, missing); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("Update(unknown) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	job := create(t, f.Repo, NewJob(1_700_000_000))
+
+	if err := f.Repo.Delete(ctx, job.ID.String()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", job.ID.String()); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("FindByField after Delete error = %v, want ErrJobNotFound", err)
+	}
+	if err := f.Repo.Delete(ctx, job.ID.String()); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("Delete(deleted) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testListFilters(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	a := NewJob(1_700_000_000)
+	a.UserEmail, a.Style = "Alice@Example.com", "noir"
+	b := NewJob(1_700_000_100)
+	b.UserEmail, b.Status = "bob@example.com", entity.JobStatusFailed
+	c := NewJob(1_700_000_200)
+	c.UserEmail, c.Status = "alice@example.com", entity.JobStatusCompleted
+	for _, job := range []*entity.Job{a, b, c} {
+		create(t, f.Repo, job)
+	}
+
+	tests := []struct {
+		name   string
+		filter jobRepository.Filter
+		want   []string // user emails, newest first
+	}{
+		{"all", jobRepository.Filter{}, []string{"alice@example.com", "bob@example.com", "Alice@Example.com"}},
+		{"statuses", jobRepository.Filter{Statuses: []entity.JobStatus{entity.JobStatusFailed, entity.JobStatusCompleted}}, []string{"alice@example.com", "bob@example.com"}},
+		{"email ignores case", jobRepository.Filter{UserEmail: "ALICE@example.com"}, []string{"alice@example.com", "Alice@Example.com"}},
+		{"style", jobRepository.Filter{Style: "noir"}, []string{"Alice@Example.com"}},
+		{"created range", jobRepository.Filter{CreatedFrom: 1_700_000_100, CreatedTo: 1_700_000_200}, []string{"bob@example.com"}},
+	}
+	for _, tt := range tests {
+		page, err := f.Repo.List(ctx, tt.filter, pagination.Request{})
+		if err != nil {
+			t.Fatalf("%s: List: %v", tt.name, err)
+		}
+		var got []string
+		for _, job := range page.Items {
+			got = append(got, job.UserEmail)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: List = %v, want %v", tt.name, got, tt.want)
+		}
+		if page.HasNext() || page.HasPrev() {
+			t.Errorf("%s: single page has cursors %q %q", tt.name, page.NextCursor, page.PrevCursor)
+		}
+	}
+}
+
+func testListPaginates(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	// three jobs share a second so the id has to break the tie
+	var want []string
+	for _, ts := range []int64{1_700_000_000, 1_700_000_000, 1_700_000_000, 1_700_000_050, 1_700_000_100} {
+		create(t, f.Repo, NewJob(ts))
+	}
+	all, err := f.Repo.List(ctx, jobRepository.Filter{}, pagination.Request{Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for i, job := range all.Items {
+		want = append(want, job.ID.String())
+		if i > 0 {
+			prev := all.Items[i-1]
+			if prev.CreatedAt < job.CreatedAt || prev.CreatedAt == job.CreatedAt && prev.ID.String() < job.ID.String() {
+				t.Fatalf("List is not sorted by (created_at, id) descending at %d", i)
+			}
+		}
+	}
+	if len(want) != 5 {
+		t.Fatalf("List returned %d jobs, want 5", len(want))
+	}
+
+	var (
+		got   []string
+		pages []*pagination.Page[*entity.Job]
+		req   = pagination.Request{Limit: 2}
+	)
+	for {
+		page, err := f.Repo.List(ctx, jobRepository.Filter{}, req)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		pages = append(pages, page)
+		for _, job := range page.Items {
+			got = append(got, job.ID.String())
+		}
+		if !page.HasNext() {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("walking forward got %v, want %v", got, want)
+	}
+	if len(pages) != 3 || pages[0].HasPrev() {
+		t.Fatalf("got %d pages, first has prev %v", len(pages), pages[0].HasPrev())
+	}
+
+	back, err := f.Repo.List(ctx, jobRepository.Filter{}, pagination.Request{Limit: 2, Cursor: pages[2].PrevCursor})
+	if err != nil {
+		t.Fatalf("List(prev): %v", err)
+	}
+	var gotBack []string
+	for _, job := range back.Items {
+		gotBack = append(gotBack, job.ID.String())
+	}
+	if !slices.Equal(gotBack, want[2:4]) {
+		t.Errorf("walking back got %v, want %v", gotBack, want[2:4])
+	}
+	if !back.HasNext() || !back.HasPrev() {
+		t.Errorf("middle page cursors: next %v prev %v, want both", back.HasNext(), back.HasPrev())
+	}
+
+	if _, err := f.Repo.List(ctx, jobRepository.Filter{}, pagination.Request{Cursor: "garbage"}); !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Errorf("List(bad cursor) error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testClaimNext(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	statuses := []entity.JobStatus{entity.JobStatusReceived}
+
+	older := create(t, f.Repo, NewJob(1_700_000_000))
+	newer := create(t, f.Repo, NewJob(1_700_000_100))
+	done := NewJob(1_600_000_000)
+	done.Status = entity.JobStatusCompleted
+	create(t, f.Repo, done)
+
+	first, err := f.Repo.ClaimNext(ctx, statuses, "worker-a", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if first.ID != older.ID || first.LeaseOwner != "worker-a" || first.LeaseExpiresAt <= time.Now().Unix() {
+		t.Errorf("first claim = %s owner %q expires %d, want %s leased to worker-a", first.ID, first.LeaseOwner, first.LeaseExpiresAt, older.ID)
+	}
+
+	second, err := f.Repo.ClaimNext(ctx, statuses, "worker-b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if second.ID != newer.ID {
+		t.Errorf("second claim = %s, want %s", second.ID, newer.ID)
+	}
+
+	if _, err := f.Repo.ClaimNext(ctx, statuses, "worker-c", time.Minute); !errors.Is(err, jobRepository.ErrNoClaimableJob) {
+		t.Errorf("third claim error = %v, want ErrNoClaimableJob", err)
+	}
+}
+
+// testClaimNextByPriority checks that a production job is claimed ahead of
+// older samples and keeps its link and render specs.
+func testClaimNextByPriority(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	statuses := []entity.JobStatus{entity.JobStatusReceived}
+
+	sample := create(t, f.Repo, NewJob(1_700_000_000))
+	production := NewJob(1_700_000_100)
+	production.Priority, production.SourceJobID = entity.JobPriorityProduction, &sample.ID
+	production.VideoWidth, production.VideoHeight, production.MaxDurationSeconds = 1920, 1080, 30
+	production = create(t, f.Repo, production)
+
+	first, err := f.Repo.ClaimNext(ctx, statuses, "worker-a", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if first.ID != production.ID {
+		t.Fatalf("first claim = %s, want production job %s", first.ID, production.ID)
+	}
+	if first.SourceJobID == nil || *first.SourceJobID != sample.ID || first.Priority != entity.JobPriorityProduction ||
+		first.VideoWidth != 1920 || first.VideoHeight != 1080 || first.MaxDurationSeconds != 30 {
+		t.Errorf("claimed production job = %+v, want source %s and its specs", first, sample.ID)
+	}
+
+	second, err := f.Repo.ClaimNext(ctx, statuses, "worker-b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if second.ID != sample.ID || second.SourceJobID != nil {
+		t.Errorf("second claim = %s source %v, want sample %s", second.ID, second.SourceJobID, sample.ID)
+	}
+}
+
+func testLeases(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	statuses := []entity.JobStatus{entity.JobStatusReceived}
+	job := create(t, f.Repo, NewJob(1_700_000_000))
+
+	if _, err := f.Repo.ClaimNext(ctx, statuses, "worker-a", time.Minute); err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if err := f.Repo.RenewLease(ctx, job.ID.String(), "worker-a", time.Minute); err != nil {
+		t.Errorf("RenewLease by owner: %v", err)
+	}
+	if err := f.Repo.RenewLease(ctx, job.ID.String(), "worker-b", time.Minute); !errors.Is(err, jobRepository.ErrLeaseLost) {
+		t.Errorf("RenewLease by stranger error = %v, want ErrLeaseLost", err)
+	}
+	if err := f.Repo.ReleaseLease(ctx, job.ID.String(), "worker-a"); err != nil {
+		t.Fatalf("ReleaseLease: %v", err)
+	}
+
+	// a lease that already ran out is taken over by the next claim
+	if _, err := f.Repo.ClaimNext(ctx, statuses, "worker-a", -time.Minute); err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	taken, err := f.Repo.ClaimNext(ctx, statuses, "worker-b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext of expired lease: %v", err)
+	}
+	if taken.ID != job.ID || taken.LeaseOwner != "worker-b" {
+		t.Errorf("expired lease claimed as %s by %q", taken.ID, taken.LeaseOwner)
+	}
+	if err := f.Repo.RenewLease(ctx, job.ID.String(), "worker-a", time.Minute); !errors.Is(err, jobRepository.ErrLeaseLost) {
+		t.Errorf("RenewLease by previous owner error = %v, want ErrLeaseLost", err)
+	}
+}
+
+func testTargetedUpdates(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	job := create(t, f.Repo, NewJob(1_700_000_000))
+
+	// two writers holding their own copy of the job
+	worker, admin := *job, *job
+
+	worker.QueJobID, worker.QueJobStatus = "que-1", "rendering"
+	if err := f.Repo.SetQueResult(ctx, &worker); err != nil {
+		t.Fatalf("SetQueResult: %v", err)
+	}
+	admin.Status, admin.ErrorMessage = entity.JobStatusCancelled, "cancelled"
+	if err := f.Repo.UpdateStatus(ctx, &admin); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	worker.VeoVideoURL, worker.VeoDuration = "https://example.com/veo.mp4", 8
+	if err := f.Repo.SetVeoResult(ctx, &worker); err != nil {
+		t.Fatalf("SetVeoResult: %v", err)
+	}
+	worker.FinalVideoS3Key, worker.Watermarked = "final.mp4", true
+	if err := f.Repo.SetFinalVideo(ctx, &worker); err != nil {
+		t.Fatalf("SetFinalVideo: %v", err)
+	}
+	if err := f.Repo.MarkEmailSent(ctx, &worker); err != nil {
+		t.Fatalf("MarkEmailSent: %v", err)
+	}
+	orderID := uuid.New()
+	admin.ConvertedToOrder, admin.OrderID = true, &orderID
+	if err := f.Repo.SetOrder(ctx, &admin); err != nil {
+		t.Fatalf("SetOrder: %v", err)
+	}
+
+	got, err := f.Repo.FindByField(ctx, "id", job.ID.String())
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if got.Status != entity.JobStatusCancelled || got.ErrorMessage != "cancelled" {
+		t.Errorf("status fields clobbered: %v %q", got.Status, got.ErrorMessage)
+	}
+	if got.QueJobID != "que-1" || got.QueJobStatus != "rendering" || got.VeoDuration != 8 ||
+		got.FinalVideoS3Key != "final.mp4" || !got.Watermarked || !got.EmailSent || got.EmailSentAt == 0 {
+		t.Errorf("worker fields lost: %+v", got)
+	}
+	if !got.ConvertedToOrder || got.OrderID == nil || *got.OrderID != orderID {
+		t.Errorf("order fields lost: %v %v", got.ConvertedToOrder, got.OrderID)
+	}
+
+	missing := *job
+	missing.ID = uuid.New()
+	if err := f.Repo.UpdateStatus(ctx, &missing); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("UpdateStatus(unknown) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testBatchRequiresTx(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	if _, err := f.Repo.CreateMany(ctx, []*entity.Job{NewJob(1_700_000_000)}); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("CreateMany outside uow error = %v, want ErrTxRequired", err)
+	}
+	if _, err := f.Repo.UpdateStatusMany(ctx, []string{uuid.NewString()}, entity.JobStatusReceived, entity.JobStatusCancelled); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("UpdateStatusMany outside uow error = %v, want ErrTxRequired", err)
+	}
+	if _, err := f.Repo.DeleteMany(ctx, []string{uuid.NewString()}); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("DeleteMany outside uow error = %v, want ErrTxRequired", err)
+	}
+}
+
+func testBatchOutcomes(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	jobs := []*entity.Job{NewJob(1_700_000_000), NewJob(1_700_000_001), NewJob(1_700_000_002)}
+	jobs[1].Status = entity.JobStatusFailed
+	created, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.CreateMany(ctx, jobs)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	for i, o := range created {
+		if o.Err != nil || o.ID != jobs[i].ID.String() {
+			t.Fatalf("CreateMany outcome %d = %+v, want %s", i, o, jobs[i].ID)
+		}
+	}
+
+	ids := []string{jobs[0].ID.String(), jobs[1].ID.String(), uuid.NewString(), "not-a-uuid"}
+	moved, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.UpdateStatusMany(ctx, ids, entity.JobStatusReceived, entity.JobStatusCancelled)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("UpdateStatusMany: %v", err)
+	}
+	wantErrs := []error{nil, jobRepository.ErrStatusConflict, jobRepository.ErrJobNotFound, jobRepository.ErrJobNotFound}
+	checkOutcomes(t, "UpdateStatusMany", moved, ids, wantErrs)
+
+	got, err := f.Repo.FindByField(ctx, "id", jobs[0].ID.String())
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if got.Status != entity.JobStatusCancelled {
+		t.Errorf("moved job has status %v, want CANCELLED", got.Status)
+	}
+
+	ids = []string{jobs[0].ID.String(), uuid.NewString(), jobs[2].ID.String()}
+	deleted, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.DeleteMany(ctx, ids)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	checkOutcomes(t, "DeleteMany", deleted, ids, []error{nil, jobRepository.ErrJobNotFound, nil})
+
+	page, err := f.Repo.List(ctx, jobRepository.Filter{}, pagination.Request{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != jobs[1].ID {
+		t.Errorf("after DeleteMany %d jobs are left, want only %s", len(page.Items), jobs[1].ID)
+	}
+}
+
+func testRollback(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	var kept, dropped string
+	_, err := f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		var err error
+		if kept, err = f.Repo.Create(ctx, NewJob(1_700_000_000)); err != nil {
+			return nil, err
+		}
+		// a failing nested unit rolls back only its own writes
+		_, err = f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+			if dropped, err = f.Repo.Create(ctx, NewJob(1_700_000_001)); err != nil {
+				return nil, err
+			}
+			return nil, errAbort
+		}, txTimeout)
+		if !errors.Is(err, errAbort) {
+			return nil, err
+		}
+		return nil, nil
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", kept); err != nil {
+		t.Errorf("job of the committed unit: %v", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", dropped); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("job of the rolled back nested unit: error = %v, want ErrJobNotFound", err)
+	}
+
+	var rolledBack string
+	_, err = f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		var err error
+		if rolledBack, err = f.Repo.Create(ctx, NewJob(1_700_000_002)); err != nil {
+			return nil, err
+		}
+		return nil, errAbort
+	}, txTimeout)
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Do error = %v, want the error of fn", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", rolledBack); !errors.Is(err, jobRepository.ErrJobNotFound) {
+		t.Errorf("job of the rolled back unit: error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func checkOutcomes(t *testing.T, method string, got []batch.Outcome, ids []string, want []error) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d outcomes, want %d", method, len(got), len(want))
+	}
+	for i, o := range got {
+		if !errors.Is(o.Err, want[i]) {
+			t.Errorf("%s outcome %d (%s) error = %v, want %v", method, i, ids[i], o.Err, want[i])
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package jobMemory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/utils"
+)
+
+// columns maps the column names FindByField accepts to the job field they
+// hold. NULL-able references yield nil so they never match, like in SQL.
+var columns = map[string]func(*entity.Job) interface{}{
+	"id":                 func(j *entity.Job) interface{} { return j.ID },
+	"user_email":         func(j *entity.Job) interface{} { return j.UserEmail },
+	"user_name":          func(j *entity.Job) interface{} { return j.UserName },
+	"input_image_s3_key": func(j *entity.Job) interface{} { return j.InputImageS3Key },
+	"style":              func(j *entity.Job) interface{} { return j.Style },
+	"status":             func(j *entity.Job) interface{} { return j.Status },
+	"veo_video_s3_key":   func(j *entity.Job) interface{} { return j.VeoVideoS3Key },
+	"que_job_id":         func(j *entity.Job) interface{} { return j.QueJobID },
+	"final_video_s3_key": func(j *entity.Job) interface{} { return j.FinalVideoS3Key },
+	"clean_video_s3_key": func(j *entity.Job) interface{} { return j.CleanVideoS3Key },
+	"lease_owner":        func(j *entity.Job) interface{} { return j.LeaseOwner },
+	"order_id": func(j *entity.Job) interface{} {
+		if j.OrderID == nil {
+			return nil
+		}
+		return *j.OrderID
+	},
+}
+
+// JobMemory is a jobRepository.Repository backed by a map, for tests. It
+// follows JobPgx in errors, ordering and pagination. Batch writes need a
+// transaction from uow.MemoryUOW, the way JobPgx needs one from uow.UOW.
+type JobMemory struct {
+	mu   sync.RWMutex
+	jobs map[uuid.UUID]entity.Job
+}
+
+func NewJobMemory() *JobMemory {
+	return &JobMemory{
+		jobs: map[uuid.UUID]entity.Job{},
+	}
+}
+
+// Snapshot implements uow.Snapshotter.
+func (j *JobMemory) Snapshot() func() {
+	j.mu.RLock()
+	saved := make(map[uuid.UUID]entity.Job, len(j.jobs))
+	for id, job := range j.jobs {
+		saved[id] = clone(job)
+	}
+	j.mu.RUnlock()
+
+	return func() {
+		j.mu.Lock()
+		j.jobs = saved
+		j.mu.Unlock()
+	}
+}
+
+func (j *JobMemory) Create(ctx context.Context, job *entity.Job) (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// the id column defaults to a fresh uuid, whatever job.ID holds
+	stored := clone(*job)
+	stored.ID = uuid.New()
+	stored.LeaseOwner, stored.LeaseExpiresAt = "", 0
+	j.jobs[stored.ID] = stored
+	return stored.ID.String(), nil
+}
+
+func (j *JobMemory) Delete(ctx context.Context, id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return utils.WrapError("delete job", invalidUUID(id))
+	}
+	if _, ok := j.jobs[key]; !ok {
+		return jobRepository.ErrJobNotFound
+	}
+	delete(j.jobs, key)
+	return nil
+}
+
+func (j *JobMemory) FindByField(ctx context.Context, field string, value interface{}) (*entity.Job, error) {
+	get, ok := columns[field]
+	if !ok {
+		return nil, utils.WrapError("FindByField job", fmt.Errorf("column %q does not exist", field))
+	}
+
+	want := fmt.Sprint(value)
+	if field == "id" {
+		if _, err := uuid.Parse(want); err != nil {
+			return nil, utils.WrapError("FindByField job", invalidUUID(want))
+		}
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	for _, job := range j.sorted() {
+		if v := get(&job); v != nil && fmt.Sprint(v) == want {
+			found := clone(job)
+			return &found, nil
+		}
+	}
+	return nil, utils.WrapError("FindByFiled", jobRepository.ErrJobNotFound)
+}
+
+// Update overwrites every column JobPgx.Update writes, the lease and
+// created_at are kept.
+func (j *JobMemory) Update(ctx context.Context, job *entity.Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, ok := j.jobs[job.ID]
+	if !ok {
+		return utils.WrapError("update job", jobRepository.ErrJobNotFound)
+	}
+	updated := clone(*job)
+	updated.LeaseOwner, updated.LeaseExpiresAt = stored.LeaseOwner, stored.LeaseExpiresAt
+	updated.CreatedAt = stored.CreatedAt
+	j.jobs[job.ID] = updated
+	return nil
+}
+
+func (j *JobMemory) List(ctx context.Context, filter jobRepository.Filter, page pagination.Request) (*pagination.Page[*entity.Job], error) {
+	keyset, err := page.Keyset(1)
+	if err != nil {
+		return nil, utils.WrapError("list", err)
+	}
+
+	j.mu.RLock()
+	var matched []*entity.Job
+	for _, job := range j.jobs {
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, job.Status) {
+			continue
+		}
+		if filter.UserEmail != "" && !strings.EqualFold(job.UserEmail, filter.UserEmail) {
+			continue
+		}
+		if filter.Style != "" && job.Style != filter.Style {
+			continue
+		}
+		if filter.CreatedFrom > 0 && job.CreatedAt < filter.CreatedFrom {
+			continue
+		}
+		if filter.CreatedTo > 0 && job.CreatedAt >= filter.CreatedTo {
+			continue
+		}
+		found := clone(job)
+		matched = append(matched, &found)
+	}
+	j.mu.RUnlock()
+
+	key := func(job *entity.Job) (int64, uuid.UUID) {
+		return job.CreatedAt, job.ID
+	}
+	return pagination.Build(keyset, pagination.Fetch(keyset, matched, key), key), nil
+}
+
+func (j *JobMemory) ClaimNext(ctx context.Context, statuses []entity.JobStatus, workerID string, lease time.Duration) (*entity.Job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	queue := j.sorted()
+	sort.SliceStable(queue, func(a, b int) bool { return queue[a].Priority > queue[b].Priority })
+	for _, job := range queue {
+		if !slices.Contains(statuses, job.Status) || job.LeaseExpiresAt >= now.Unix() {
+			continue
+		}
+		job.LeaseOwner, job.LeaseExpiresAt = workerID, now.Add(lease).Unix()
+		j.jobs[job.ID] = job
+		claimed := clone(job)
+		return &claimed, nil
+	}
+	return nil, jobRepository.ErrNoClaimableJob
+}
+
+func (j *JobMemory) RenewLease(ctx context.Context, id string, workerID string, lease time.Duration) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	job, ok := j.find(id)
+	if !ok || job.LeaseOwner != workerID || job.LeaseExpiresAt < now.Unix() {
+		return jobRepository.ErrLeaseLost
+	}
+	job.LeaseExpiresAt = now.Add(lease).Unix()
+	j.jobs[job.ID] = job
+	return nil
+}
+
+func (j *JobMemory) ReleaseLease(ctx context.Context, id string, workerID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.find(id)
+	if !ok || job.LeaseOwner != workerID {
+		return jobRepository.ErrLeaseLost
+	}
+	job.LeaseOwner, job.LeaseExpiresAt = "", 0
+	j.jobs[job.ID] = job
+	return nil
+}
+
+// CreateMany is all or nothing like the COPY in JobPgx: a duplicate id
+// fails the whole batch.
+func (j *JobMemory) CreateMany(ctx context.Context, jobs []*entity.Job) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	outcomes := make([]batch.Outcome, len(jobs))
+	for i, job := range jobs {
+		if job.ID == uuid.Nil {
+			job.ID = uuid.New()
+		}
+		outcomes[i].ID = job.ID.String()
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, job := range jobs {
+		if _, exists := j.jobs[job.ID]; exists || seen[job.ID] {
+			err := &pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "jobs_pkey"`}
+			for i := range outcomes {
+				outcomes[i].Err = err
+			}
+			return outcomes, utils.WrapError("create jobs", err)
+		}
+		seen[job.ID] = true
+	}
+
+	for _, job := range jobs {
+		stored := clone(*job)
+		stored.LeaseOwner, stored.LeaseExpiresAt = "", 0
+		j.jobs[job.ID] = stored
+	}
+	return outcomes, nil
+}
+
+func (j *JobMemory) UpdateStatusMany(ctx context.Context, ids []string, from, to entity.JobStatus) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now().Unix()
+	outcomes, _ := batch.ParseIDs(ids, jobRepository.ErrJobNotFound)
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		job, ok := j.find(outcomes[i].ID)
+		switch {
+		case !ok:
+			outcomes[i].Err = jobRepository.ErrJobNotFound
+		case job.Status != from:
+			outcomes[i].Err = jobRepository.ErrStatusConflict
+		default:
+			job.Status, job.UpdatedAt = to, now
+			j.jobs[job.ID] = job
+		}
+	}
+	return outcomes, nil
+}
+
+func (j *JobMemory) DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	outcomes, _ := batch.ParseIDs(ids, jobRepository.ErrJobNotFound)
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		job, ok := j.find(outcomes[i].ID)
+		if !ok {
+			outcomes[i].Err = jobRepository.ErrJobNotFound
+			continue
+		}
+		delete(j.jobs, job.ID)
+	}
+	return outcomes, nil
+}
+
+func (j *JobMemory) UpdateStatus(ctx context.Context, job *entity.Job) error {
+	return j.patch(job, func(stored *entity.Job) {
+		stored.Status = job.Status
+		stored.StartedAt, stored.CompletedAt, stored.TotalProcessingTime = job.StartedAt, job.CompletedAt, job.TotalProcessingTime
+		stored.ErrorMessage, stored.ErrorStack, stored.RetryCount = job.ErrorMessage, job.ErrorStack, job.RetryCount
+	})
+}
+
+func (j *JobMemory) SetVeoResult(ctx context.Context, job *entity.Job) error {
+	return j.patch(job, func(stored *entity.Job) {
+		stored.VeoVideoURL, stored.VeoVideoS3Key, stored.VeoDuration = job.VeoVideoURL, job.VeoVideoS3Key, job.VeoDuration
+	})
+}
+
+func (j *JobMemory) SetQueResult(ctx context.Context, job *entity.Job) error {
+	return j.patch(job, func(stored *entity.Job) {
+		stored.QueJobID, stored.QueJobStatus = job.QueJobID, job.QueJobStatus
+	})
+}
+
+func (j *JobMemory) SetFinalVideo(ctx context.Context, job *entity.Job) error {
+	return j.patch(job, func(stored *entity.Job) {
+		stored.FinalVideoURL, stored.FinalVideoS3Key = job.FinalVideoURL, job.FinalVideoS3Key
+		stored.FinalVideoDuration, stored.FinalVideoSize = job.FinalVideoDuration, job.FinalVideoSize
+		stored.Watermarked, stored.CleanVideoURL, stored.CleanVideoS3Key = job.Watermarked, job.CleanVideoURL, job.CleanVideoS3Key
+		stored.SignedURL, stored.SignedURLExpiry = job.SignedURL, job.SignedURLExpiry
+	})
+}
+
+func (j *JobMemory) MarkEmailSent(ctx context.Context, job *entity.Job) error {
+	job.EmailSent, job.EmailSentAt = true, time.Now().Unix()
+	return j.patch(job, func(stored *entity.Job) {
+		stored.EmailSent, stored.EmailSentAt = true, job.EmailSentAt
+	})
+}
+
+func (j *JobMemory) SetOrder(ctx context.Context, job *entity.Job) error {
+	return j.patch(job, func(stored *entity.Job) {
+		stored.ConvertedToOrder = job.ConvertedToOrder
+		stored.OrderID = clone(*job).OrderID
+	})
+}
+
+// patch applies set to the stored copy of job and bumps updated_at on both.
+func (j *JobMemory) patch(job *entity.Job, set func(stored *entity.Job)) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stored, ok := j.jobs[job.ID]
+	if !ok {
+		return utils.WrapError("patch job", jobRepository.ErrJobNotFound)
+	}
+	job.UpdatedAt = time.Now().Unix()
+	set(&stored)
+	stored.UpdatedAt = job.UpdatedAt
+	j.jobs[job.ID] = stored
+	return nil
+}
+
+func (j *JobMemory) find(id string) (entity.Job, bool) {
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return entity.Job{}, false
+	}
+	job, ok := j.jobs[key]
+	return job, ok
+}
+
+// sorted returns the jobs oldest first, by (created_at, id).
+func (j *JobMemory) sorted() []entity.Job {
+	all := make([]entity.Job, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		all = append(all, job)
+	}
+	sort.Slice(all, func(a, b int) bool {
+		if all[a].CreatedAt != all[b].CreatedAt {
+			return all[a].CreatedAt < all[b].CreatedAt
+		}
+		return bytes.Compare(all[a].ID[:], all[b].ID[:]) < 0
+	})
+	return all
+}
+
+// clone copies job so callers never share pointers with the store.
+func clone(job entity.Job) entity.Job {
+	if job.OrderID != nil {
+		id := *job.OrderID
+		job.OrderID = &id
+	}
+	if job.SourceJobID != nil {
+		id := *job.SourceJobID
+		job.SourceJobID = &id
+	}
+	return job
+}
+
+func invalidUUID(id string) error {
+	return &pgconn.PgError{Code: "22P02", Message: fmt.Sprintf("invalid input syntax for type uuid: %q", id)}
+}
@@ -0,0 +1,51 @@
+package jobMemory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ jobRepository.Repository = (*JobMemory)(nil)
+
+func TestContract(t *testing.T) {
+	jobContract.Run(t, func(t *testing.T) jobContract.Fixture {
+		repo := NewJobMemory()
+		return jobContract.Fixture{Repo: repo, UOW: uow.NewMemoryUOW(repo)}
+	})
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	repo := NewJobMemory()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := repo.Create(ctx, jobContract.NewJob(int64(1_700_000_000+i)))
+			if err != nil {
+				t.Errorf("Create: %v", err)
+				return
+			}
+			if _, err := repo.FindByField(ctx, "id", id); err != nil {
+				t.Errorf("FindByField: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	page, err := repo.List(ctx, jobRepository.Filter{}, pagination.Request{Limit: pagination.MaxLimit})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 50 {
+		t.Errorf("List returned %d jobs, want 50", len(page.Items))
+	}
+}
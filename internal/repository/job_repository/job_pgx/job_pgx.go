@@ -8,12 +8,16 @@ import (
 	"github.com/playture/backend/utils"
 	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/playture/backend/internal/entity"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/repository/batch"
 	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	"github.com/playture/backend/internal/repository/pagination"
 )
 
 const (
@@ -26,6 +30,8 @@ const (
 			email_sent, email_sent_at, error_message, error_stack, retry_count,
 			ip_address, user_agent, started_at, completed_at, total_processing_time,
 			converted_to_order, order_id, content_moderated, content_moderation_result,
+			watermarked, clean_video_url, clean_video_s3_key,
+			priority, source_job_id, video_width, video_height, max_duration_seconds,
 			created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5,
@@ -35,21 +41,29 @@ const (
 			$18, $19, $20, $21, $22,
 			$23, $24, $25, $26, $27,
 			$28, $29, $30, $31,
-			$32, $33
+			$32, $33, $34,
+			$35, $36, $37, $38, $39,
+			$40, $41
 		) RETURNING id`
 
 	deleteQuery = `DELETE FROM jobs WHERE id = $1`
 
-	findByFieldQuery = `SELECT 
-		id, user_email, user_name, input_image_url, input_image_s3_key, style,
-		status, veo_video_url, veo_video_s3_key, veo_duration,
-		que_job_id, que_job_status, final_video_url, final_video_s3_key,
-		final_video_duration, final_video_size, signed_url, signed_url_expiry,
-		email_sent, email_sent_at, error_message, error_stack, retry_count,
-		ip_address, user_agent, started_at, completed_at, total_processing_time,
-		converted_to_order, order_id, content_moderated, content_moderation_result,
-		created_at, updated_at
-		FROM jobs WHERE %s = $1 LIMIT 1`
+	// jobColumns reads every column in the order scanJob expects. Optional
+	// columns come back as their zero value when NULL.
+	jobColumns = `
+		id, user_email, user_name, input_image_url, input_image_s3_key, COALESCE(style, ''),
+		status, COALESCE(veo_video_url, ''), COALESCE(veo_video_s3_key, ''), COALESCE(veo_duration, 0),
+		COALESCE(que_job_id, ''), COALESCE(que_job_status, ''), COALESCE(final_video_url, ''), COALESCE(final_video_s3_key, ''),
+		COALESCE(final_video_duration, 0), COALESCE(final_video_size, 0), COALESCE(signed_url, ''), COALESCE(signed_url_expiry, 0),
+		COALESCE(email_sent, FALSE), COALESCE(email_sent_at, 0), COALESCE(error_message, ''), COALESCE(error_stack, ''), COALESCE(retry_count, 0),
+		COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(started_at, 0), COALESCE(completed_at, 0), COALESCE(total_processing_time, 0),
+		COALESCE(converted_to_order, FALSE), order_id, COALESCE(content_moderated, FALSE), content_moderation_result,
+		COALESCE(watermarked, FALSE), COALESCE(clean_video_url, ''), COALESCE(clean_video_s3_key, ''),
+		priority, source_job_id, COALESCE(video_width, 0), COALESCE(video_height, 0), COALESCE(max_duration_seconds, 0),
+		lease_owner, lease_expires_at,
+		created_at, updated_at`
+
+	findByFieldQuery = `SELECT ` + jobColumns + ` FROM jobs WHERE %s = $1 LIMIT 1`
 
 	updateQuery = `
 		UPDATE jobs SET
@@ -60,23 +74,55 @@ const (
 			email_sent=$19, email_sent_at=$20, error_message=$21, error_stack=$22, retry_count=$23,
 			ip_address=$24, user_agent=$25, started_at=$26, completed_at=$27, total_processing_time=$28,
 			converted_to_order=$29, order_id=$30, content_moderated=$31, content_moderation_result=$32,
-			updated_at=$33
+			watermarked=$33, clean_video_url=$34, clean_video_s3_key=$35,
+			priority=$36, source_job_id=$37, video_width=$38, video_height=$39, max_duration_seconds=$40,
+			updated_at=$41
 		WHERE id=$1`
 
-	listQuery = `
-		SELECT 
-			id, user_email, user_name, input_image_url, input_image_s3_key, style,
-			status, veo_video_url, veo_video_s3_key, veo_duration,
-			que_job_id, que_job_status, final_video_url, final_video_s3_key,
-			final_video_duration, final_video_size, signed_url, signed_url_expiry,
-			email_sent, email_sent_at, error_message, error_stack, retry_count,
-			ip_address, user_agent, started_at, completed_at, total_processing_time,
-			converted_to_order, order_id, content_moderated, content_moderation_result,
-			created_at, updated_at
+	listQuery = `SELECT ` + jobColumns + `
 		FROM jobs
 		%s
-		ORDER BY %s %s
-		LIMIT $%d OFFSET $%d`
+		ORDER BY %s
+		LIMIT $%d`
+
+	// claimNextQuery takes the job with the highest priority, oldest first,
+	// in one of the given statuses that is not leased or whose lease ran
+	// out. Rows locked by a concurrent claim are skipped, so workers never
+	// wait on each other.
+	claimNextQuery = `
+		UPDATE jobs SET lease_owner = $2, lease_expires_at = $3
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ANY($1::smallint[]) AND lease_expires_at < $4
+			ORDER BY priority DESC, created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	renewLeaseQuery = `
+		UPDATE jobs SET lease_expires_at = $3
+		WHERE id = $1 AND lease_owner = $2 AND lease_expires_at >= $4`
+
+	// updateStatusManyQuery locks every requested job, moves the ones still
+	// in the expected status and reports for each locked job whether it moved.
+	updateStatusManyQuery = `
+		WITH target AS (
+			SELECT id, status FROM jobs WHERE id = ANY($1) FOR UPDATE
+		), moved AS (
+			UPDATE jobs SET status = $3, updated_at = $4
+			FROM target
+			WHERE jobs.id = target.id AND target.status = $2
+			RETURNING jobs.id
+		)
+		SELECT target.id, moved.id IS NOT NULL
+		FROM target LEFT JOIN moved ON moved.id = target.id`
+
+	deleteManyQuery = `DELETE FROM jobs WHERE id = ANY($1) RETURNING id`
+
+	releaseLeaseQuery = `
+		UPDATE jobs SET lease_owner = '', lease_expires_at = 0
+		WHERE id = $1 AND lease_owner = $2`
 )
 
 type JobPgx struct {
@@ -97,28 +143,13 @@ func NewJobPgx(
 func (j *JobPgx) Create(
 	ctx context.Context,
 	job *entity.Job,
-	tx pgx.Tx,
 ) (string, error) {
 	lg := j.logger.With("method", "Create")
 	var id string
 
-	args := []interface{}{
-		job.UserEmail, job.UserName, job.InputImageURL, job.InputImageS3Key, job.Style,
-		job.Status, job.VeoVideoURL, job.VeoVideoS3Key, job.VeoDuration,
-		job.QueJobID, job.QueJobStatus, job.FinalVideoURL, job.FinalVideoS3Key,
-		job.FinalVideoDuration, job.FinalVideoSize, job.SignedURL, job.SignedURLExpiry,
-		job.EmailSent, job.EmailSentAt, job.ErrorMessage, job.ErrorStack, job.RetryCount,
-		job.IPAddress, job.UserAgent, job.StartedAt, job.CompletedAt, job.TotalProcessingTime,
-		job.ConvertedToOrder, job.OrderID, job.ContentModerated, job.ContentModerationResult,
-		job.CreatedAt, job.UpdatedAt,
-	}
+	args := append(writeValues(job), job.CreatedAt, job.UpdatedAt)
 
-	var err error
-	if tx != nil {
-		err = tx.QueryRow(ctx, CreateQuery, args...).Scan(&id)
-	} else {
-		err = j.postgres.PrimaryConn.QueryRow(ctx, CreateQuery, args...).Scan(&id)
-	}
+	err := j.postgres.Querier(ctx).QueryRow(ctx, CreateQuery, args...).Scan(&id)
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok {
 			lg.Error("Create failed", "pgErr", pgErr.Message)
@@ -131,22 +162,17 @@ func (j *JobPgx) Create(
 func (j *JobPgx) Delete(
 	ctx context.Context,
 	id string,
-	tx pgx.Tx,
 ) error {
 	lg := j.logger.With("method", "Delete")
-	var err error
-	if tx != nil {
-		_, err = tx.Exec(ctx, deleteQuery, id)
-	} else {
-		_, err = j.postgres.PrimaryConn.Exec(ctx, deleteQuery, id)
-	}
+	cmd, err := j.postgres.Querier(ctx).Exec(ctx, deleteQuery, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return jobRepository.ErrJobNotFound
-		}
 		lg.Error("Delete failed", "id", id, "err", err)
 		return utils.WrapError("delete job", err)
 	}
+	// Exec never reports sql.ErrNoRows, a missing job deletes nothing
+	if cmd.RowsAffected() == 0 {
+		return utils.WrapError("delete job", jobRepository.ErrJobNotFound)
+	}
 	return nil
 }
 
@@ -154,29 +180,13 @@ func (j *JobPgx) FindByField(
 	ctx context.Context,
 	field string,
 	value interface{},
-	tx pgx.Tx,
 ) (*entity.Job, error) {
 	lg := j.logger.With("method", "FindByField")
 	query := fmt.Sprintf(findByFieldQuery, field)
 
-	var row pgx.Row
-	if tx != nil {
-		row = tx.QueryRow(ctx, query, value)
-	} else {
-		row = j.postgres.PrimaryConn.QueryRow(ctx, query, value)
-	}
+	row := j.postgres.Reader(ctx).QueryRow(ctx, query, value)
 
-	job := &entity.Job{}
-	err := row.Scan(
-		&job.ID, &job.UserEmail, &job.UserName, &job.InputImageURL, &job.InputImageS3Key, &job.Style,
-		&job.Status, &job.VeoVideoURL, &job.VeoVideoS3Key, &job.VeoDuration,
-		&job.QueJobID, &job.QueJobStatus, &job.FinalVideoURL, &job.FinalVideoS3Key,
-		&job.FinalVideoDuration, &job.FinalVideoSize, &job.SignedURL, &job.SignedURLExpiry,
-		&job.EmailSent, &job.EmailSentAt, &job.ErrorMessage, &job.ErrorStack, &job.RetryCount,
-		&job.IPAddress, &job.UserAgent, &job.StartedAt, &job.CompletedAt, &job.TotalProcessingTime,
-		&job.ConvertedToOrder, &job.OrderID, &job.ContentModerated, &job.ContentModerationResult,
-		&job.CreatedAt, &job.UpdatedAt,
-	)
+	job, err := scanJob(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, utils.WrapError("FindByFiled", jobRepository.ErrJobNotFound)
@@ -189,31 +199,18 @@ func (j *JobPgx) FindByField(
 func (j *JobPgx) Update(
 	ctx context.Context,
 	job *entity.Job,
-	tx pgx.Tx,
 ) error {
 	lg := j.logger.With("method", "Update")
 
-	args := []interface{}{
-		job.ID, job.UserEmail, job.UserName, job.InputImageURL, job.InputImageS3Key, job.Style,
-		job.Status, job.VeoVideoURL, job.VeoVideoS3Key, job.VeoDuration,
-		job.QueJobID, job.QueJobStatus, job.FinalVideoURL, job.FinalVideoS3Key,
-		job.FinalVideoDuration, job.FinalVideoSize, job.SignedURL, job.SignedURLExpiry,
-		job.EmailSent, job.EmailSentAt, job.ErrorMessage, job.ErrorStack, job.RetryCount,
-		job.IPAddress, job.UserAgent, job.StartedAt, job.CompletedAt, job.TotalProcessingTime,
-		job.ConvertedToOrder, job.OrderID, job.ContentModerated, job.ContentModerationResult,
-		job.UpdatedAt,
-	}
+	args := append([]interface{}{job.ID}, writeValues(job)...)
+	args = append(args, job.UpdatedAt)
 
 	var (
 		cmd pgconn.CommandTag
 		err error
 	)
 
-	if tx != nil {
-		cmd, err = tx.Exec(ctx, updateQuery, args...)
-	} else {
-		cmd, err = j.postgres.PrimaryConn.Exec(ctx, updateQuery, args...)
-	}
+	cmd, err = j.postgres.Querier(ctx).Exec(ctx, updateQuery, args...)
 	if err != nil {
 		lg.Error("Update failed", "id", job.ID, "err", err)
 		return utils.WrapError("update job", err)
@@ -226,46 +223,56 @@ func (j *JobPgx) Update(
 }
 
 func (j *JobPgx) List(ctx context.Context,
-	statuses []entity.JobStatus,
-	orderBy string,
-	ascending bool,
-	limit,
-	page int,
-	tx pgx.Tx,
-) ([]*entity.Job, error) {
+	filter jobRepository.Filter,
+	page pagination.Request,
+) (*pagination.Page[*entity.Job], error) {
 	lg := j.logger.With("method", "List")
 
-	if orderBy == "" {
-		orderBy = "created_at"
-	}
-	direction := "ASC"
-	if !ascending {
-		direction = "DESC"
-	}
-
-	where := ""
+	conditions := []string{}
 	args := []interface{}{}
-	if len(statuses) > 0 {
+	if len(filter.Statuses) > 0 {
 		placeholders := []string{}
-		for i, st := range statuses {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		for _, st := range filter.Statuses {
 			args = append(args, st)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
 		}
-		where = fmt.Sprintf("WHERE status IN (%s)", strings.Join(placeholders, ", "))
+		conditions = append(conditions, fmt.Sprintf("status IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if filter.UserEmail != "" {
+		args = append(args, filter.UserEmail)
+		conditions = append(conditions, fmt.Sprintf("lower(user_email) = lower($%d)", len(args)))
+	}
+	if filter.Style != "" {
+		args = append(args, filter.Style)
+		conditions = append(conditions, fmt.Sprintf("style = $%d", len(args)))
+	}
+	if filter.CreatedFrom > 0 {
+		args = append(args, filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo > 0 {
+		args = append(args, filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
 	}
 
-	offset := (page - 1) * limit
-	args = append(args, limit, offset)
+	keyset, err := page.Keyset(len(args) + 1)
+	if err != nil {
+		return nil, utils.WrapError("list", err)
+	}
+	if keyset.Condition != "" {
+		conditions = append(conditions, keyset.Condition)
+		args = append(args, keyset.Args...)
+	}
+	args = append(args, keyset.Limit)
 
-	query := fmt.Sprintf(listQuery, where, orderBy, direction, len(args)-1, len(args))
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := fmt.Sprintf(listQuery, where, keyset.OrderBy, len(args))
 
 	var rows pgx.Rows
-	var err error
-	if tx != nil {
-		rows, err = tx.Query(ctx, query, args...)
-	} else {
-		rows, err = j.postgres.PrimaryConn.Query(ctx, query, args...)
-	}
+	rows, err = j.postgres.Reader(ctx).Query(ctx, query, args...)
 	if err != nil {
 		return nil, utils.WrapError("failed to Query against database", err)
 	}
@@ -273,27 +280,334 @@ func (j *JobPgx) List(ctx context.Context,
 
 	var jobs []*entity.Job
 	for rows.Next() {
-		job := &entity.Job{}
-		err = rows.Scan(
-			&job.ID, &job.UserEmail, &job.UserName, &job.InputImageURL, &job.InputImageS3Key, &job.Style,
-			&job.Status, &job.VeoVideoURL, &job.VeoVideoS3Key, &job.VeoDuration,
-			&job.QueJobID, &job.QueJobStatus, &job.FinalVideoURL, &job.FinalVideoS3Key,
-			&job.FinalVideoDuration, &job.FinalVideoSize, &job.SignedURL, &job.SignedURLExpiry,
-			&job.EmailSent, &job.EmailSentAt, &job.ErrorMessage, &job.ErrorStack, &job.RetryCount,
-			&job.IPAddress, &job.UserAgent, &job.StartedAt, &job.CompletedAt, &job.TotalProcessingTime,
-			&job.ConvertedToOrder, &job.OrderID, &job.ContentModerated, &job.ContentModerationResult,
-			&job.CreatedAt, &job.UpdatedAt,
-		)
+		job, err := scanJob(rows)
 		if err != nil {
 			return nil, utils.WrapError("failed to scan row", err)
 		}
 		jobs = append(jobs, job)
 	}
-
-	if len(jobs) == 0 {
-		return nil, utils.WrapError("list", jobRepository.ErrJobNotFound)
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("failed to iterate rows", err)
 	}
 
 	lg.Info("fetched jobs", "count", len(jobs))
-	return jobs, nil
+	return pagination.Build(keyset, jobs, func(job *entity.Job) (int64, uuid.UUID) {
+		return job.CreatedAt, job.ID
+	}), nil
+}
+
+func (j *JobPgx) ClaimNext(
+	ctx context.Context,
+	statuses []entity.JobStatus,
+	workerID string,
+	lease time.Duration,
+) (*entity.Job, error) {
+	lg := j.logger.With("method", "ClaimNext")
+
+	codes := make([]int16, len(statuses))
+	for i, st := range statuses {
+		codes[i] = int16(st)
+	}
+	now := time.Now()
+	row := j.postgres.Querier(ctx).QueryRow(ctx, claimNextQuery, codes, workerID, now.Add(lease).Unix(), now.Unix())
+
+	job, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jobRepository.ErrNoClaimableJob
+		}
+		lg.Error("ClaimNext failed", "workerID", workerID, "err", err)
+		return nil, utils.WrapError("claim job", err)
+	}
+	return job, nil
+}
+
+func (j *JobPgx) RenewLease(ctx context.Context, id string, workerID string, lease time.Duration) error {
+	now := time.Now()
+	cmd, err := j.postgres.Querier(ctx).Exec(ctx, renewLeaseQuery, id, workerID, now.Add(lease).Unix(), now.Unix())
+	if err != nil {
+		return utils.WrapError("renew job lease", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return jobRepository.ErrLeaseLost
+	}
+	return nil
+}
+
+func (j *JobPgx) ReleaseLease(ctx context.Context, id string, workerID string) error {
+	cmd, err := j.postgres.Querier(ctx).Exec(ctx, releaseLeaseQuery, id, workerID)
+	if err != nil {
+		return utils.WrapError("release job lease", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return jobRepository.ErrLeaseLost
+	}
+	return nil
+}
+
+// copyColumns are the columns CreateMany fills, in the order of copyRow.
+var copyColumns = []string{
+	"id", "user_email", "user_name", "input_image_url", "input_image_s3_key", "style",
+	"status", "veo_video_url", "veo_video_s3_key", "veo_duration",
+	"que_job_id", "que_job_status", "final_video_url", "final_video_s3_key",
+	"final_video_duration", "final_video_size", "signed_url", "signed_url_expiry",
+	"email_sent", "email_sent_at", "error_message", "error_stack", "retry_count",
+	"ip_address", "user_agent", "started_at", "completed_at", "total_processing_time",
+	"converted_to_order", "order_id", "content_moderated", "content_moderation_result",
+	"watermarked", "clean_video_url", "clean_video_s3_key",
+	"priority", "source_job_id", "video_width", "video_height", "max_duration_seconds",
+	"created_at", "updated_at",
+}
+
+func copyRow(job *entity.Job) []interface{} {
+	row := append([]interface{}{job.ID}, writeValues(job)...)
+	return append(row, job.CreatedAt, job.UpdatedAt)
+}
+
+// CreateMany streams jobs in with COPY. COPY does not hand back generated
+// keys, so jobs without an id get one assigned here. It is all or nothing:
+// on error no job was inserted.
+func (j *JobPgx) CreateMany(ctx context.Context, jobs []*entity.Job) ([]batch.Outcome, error) {
+	lg := j.logger.With("method", "CreateMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]batch.Outcome, len(jobs))
+	for i, job := range jobs {
+		if job.ID == uuid.Nil {
+			job.ID = uuid.New()
+		}
+		outcomes[i].ID = job.ID.String()
+	}
+
+	_, err := j.postgres.Querier(ctx).CopyFrom(ctx, pgx.Identifier{"jobs"}, copyColumns,
+		pgx.CopyFromSlice(len(jobs), func(i int) ([]interface{}, error) {
+			return copyRow(jobs[i]), nil
+		}),
+	)
+	if err != nil {
+		lg.Error("CreateMany failed", "count", len(jobs), "err", err)
+		for i := range outcomes {
+			outcomes[i].Err = err
+		}
+		return outcomes, utils.WrapError("create jobs", err)
+	}
+	return outcomes, nil
+}
+
+// UpdateStatusMany moves the given jobs from one status to another in a
+// single statement. Jobs that do not exist or are no longer in from are
+// reported in their outcome and left untouched. No status history is
+// written, callers record the transitions themselves.
+func (j *JobPgx) UpdateStatusMany(
+	ctx context.Context,
+	ids []string,
+	from, to entity.JobStatus,
+) ([]batch.Outcome, error) {
+	lg := j.logger.With("method", "UpdateStatusMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	outcomes, keys := batch.ParseIDs(ids, jobRepository.ErrJobNotFound)
+	rows, err := j.postgres.Querier(ctx).Query(ctx, updateStatusManyQuery, keys, from, to, time.Now().Unix())
+	if err != nil {
+		lg.Error("UpdateStatusMany failed", "err", err)
+		return nil, utils.WrapError("update job statuses", err)
+	}
+	defer rows.Close()
+
+	moved := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var (
+			id uuid.UUID
+			ok bool
+		)
+		if err := rows.Scan(&id, &ok); err != nil {
+			return nil, utils.WrapError("update job statuses", err)
+		}
+		moved[id] = ok
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("update job statuses", err)
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		ok, found := moved[uuid.MustParse(outcomes[i].ID)]
+		switch {
+		case !found:
+			outcomes[i].Err = jobRepository.ErrJobNotFound
+		case !ok:
+			outcomes[i].Err = jobRepository.ErrStatusConflict
+		}
+	}
+	return outcomes, nil
+}
+
+// DeleteMany deletes the given jobs in a single statement, ids that match no
+// job are reported in their outcome.
+func (j *JobPgx) DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error) {
+	lg := j.logger.With("method", "DeleteMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	outcomes, keys := batch.ParseIDs(ids, jobRepository.ErrJobNotFound)
+	rows, err := j.postgres.Querier(ctx).Query(ctx, deleteManyQuery, keys)
+	if err != nil {
+		lg.Error("DeleteMany failed", "err", err)
+		return nil, utils.WrapError("delete jobs", err)
+	}
+	defer rows.Close()
+
+	deleted := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.WrapError("delete jobs", err)
+		}
+		deleted[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("delete jobs", err)
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Err == nil && !deleted[uuid.MustParse(outcomes[i].ID)] {
+			outcomes[i].Err = jobRepository.ErrJobNotFound
+		}
+	}
+	return outcomes, nil
+}
+
+// UpdateStatus writes the status together with the columns that move with
+// it: timing, the error of a failed run and the retry count.
+func (j *JobPgx) UpdateStatus(ctx context.Context, job *entity.Job) error {
+	return j.patch(ctx, "UpdateStatus", job,
+		[]string{"status", "started_at", "completed_at", "total_processing_time", "error_message", "error_stack", "retry_count"},
+		job.Status,
+		postgresql.NullIfZero(job.StartedAt), postgresql.NullIfZero(job.CompletedAt), postgresql.NullIfZero(job.TotalProcessingTime),
+		postgresql.NullIfZero(job.ErrorMessage), postgresql.NullIfZero(job.ErrorStack), job.RetryCount,
+	)
+}
+
+func (j *JobPgx) SetVeoResult(ctx context.Context, job *entity.Job) error {
+	return j.patch(ctx, "SetVeoResult", job,
+		[]string{"veo_video_url", "veo_video_s3_key", "veo_duration"},
+		postgresql.NullIfZero(job.VeoVideoURL), postgresql.NullIfZero(job.VeoVideoS3Key), postgresql.NullIfZero(job.VeoDuration),
+	)
+}
+
+func (j *JobPgx) SetQueResult(ctx context.Context, job *entity.Job) error {
+	return j.patch(ctx, "SetQueResult", job,
+		[]string{"que_job_id", "que_job_status"},
+		postgresql.NullIfZero(job.QueJobID), postgresql.NullIfZero(job.QueJobStatus),
+	)
+}
+
+// SetFinalVideo writes the rendered videos, which variant is final and the
+// signed URL handed out for it.
+func (j *JobPgx) SetFinalVideo(ctx context.Context, job *entity.Job) error {
+	return j.patch(ctx, "SetFinalVideo", job,
+		[]string{
+			"final_video_url", "final_video_s3_key", "final_video_duration", "final_video_size",
+			"watermarked", "clean_video_url", "clean_video_s3_key", "signed_url", "signed_url_expiry",
+		},
+		postgresql.NullIfZero(job.FinalVideoURL), postgresql.NullIfZero(job.FinalVideoS3Key),
+		postgresql.NullIfZero(job.FinalVideoDuration), postgresql.NullIfZero(job.FinalVideoSize),
+		job.Watermarked, postgresql.NullIfZero(job.CleanVideoURL), postgresql.NullIfZero(job.CleanVideoS3Key),
+		postgresql.NullIfZero(job.SignedURL), postgresql.NullIfZero(job.SignedURLExpiry),
+	)
+}
+
+func (j *JobPgx) MarkEmailSent(ctx context.Context, job *entity.Job) error {
+	job.EmailSent, job.EmailSentAt = true, time.Now().Unix()
+	return j.patch(ctx, "MarkEmailSent", job,
+		[]string{"email_sent", "email_sent_at"},
+		true, job.EmailSentAt,
+	)
+}
+
+func (j *JobPgx) SetOrder(ctx context.Context, job *entity.Job) error {
+	return j.patch(ctx, "SetOrder", job,
+		[]string{"converted_to_order", "order_id"},
+		job.ConvertedToOrder, job.OrderID,
+	)
+}
+
+// patch writes only columns of one job, plus updated_at which it sets on
+// job as well. Column names never come from user input.
+func (j *JobPgx) patch(ctx context.Context, method string, job *entity.Job, columns []string, values ...interface{}) error {
+	lg := j.logger.With("method", method)
+
+	job.UpdatedAt = time.Now().Unix()
+	args := append([]interface{}{job.ID}, values...)
+	args = append(args, job.UpdatedAt)
+
+	set := make([]string, 0, len(columns)+1)
+	for i, col := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", col, i+2))
+	}
+	set = append(set, fmt.Sprintf("updated_at = $%d", len(args)))
+	query := fmt.Sprintf("UPDATE jobs SET %s WHERE id = $1", strings.Join(set, ", "))
+
+	cmd, err := j.postgres.Querier(ctx).Exec(ctx, query, args...)
+	if err != nil {
+		lg.Error(method+" failed", "id", job.ID, "err", err)
+		return utils.WrapError(method, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return utils.WrapError(method, jobRepository.ErrJobNotFound)
+	}
+	return nil
+}
+
+// scanJob maps one row selected with jobColumns. FindByField, List and
+// ClaimNext all read through it so NULL handling lives in one place.
+func scanJob(row pgx.Row) (*entity.Job, error) {
+	job := &entity.Job{}
+	err := row.Scan(
+		&job.ID, &job.UserEmail, &job.UserName, &job.InputImageURL, &job.InputImageS3Key, &job.Style,
+		&job.Status, &job.VeoVideoURL, &job.VeoVideoS3Key, &job.VeoDuration,
+		&job.QueJobID, &job.QueJobStatus, &job.FinalVideoURL, &job.FinalVideoS3Key,
+		&job.FinalVideoDuration, &job.FinalVideoSize, &job.SignedURL, &job.SignedURLExpiry,
+		&job.EmailSent, &job.EmailSentAt, &job.ErrorMessage, &job.ErrorStack, &job.RetryCount,
+		&job.IPAddress, &job.UserAgent, &job.StartedAt, &job.CompletedAt, &job.TotalProcessingTime,
+		&job.ConvertedToOrder, &job.OrderID, &job.ContentModerated, &job.ContentModerationResult,
+		&job.Watermarked, &job.CleanVideoURL, &job.CleanVideoS3Key,
+		&job.Priority, &job.SourceJobID, &job.VideoWidth, &job.VideoHeight, &job.MaxDurationSeconds,
+		&job.LeaseOwner, &job.LeaseExpiresAt,
+		&job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// writeValues returns the columns from user_email to max_duration_seconds that
+// Create, Update and CreateMany write. Optional columns that are not set are
+// written as NULL, flags and the retry count keep their value.
+func writeValues(job *entity.Job) []interface{} {
+	return []interface{}{
+		job.UserEmail, job.UserName, job.InputImageURL, job.InputImageS3Key, job.Style,
+		job.Status,
+		postgresql.NullIfZero(job.VeoVideoURL), postgresql.NullIfZero(job.VeoVideoS3Key), postgresql.NullIfZero(job.VeoDuration),
+		postgresql.NullIfZero(job.QueJobID), postgresql.NullIfZero(job.QueJobStatus),
+		postgresql.NullIfZero(job.FinalVideoURL), postgresql.NullIfZero(job.FinalVideoS3Key),
+		postgresql.NullIfZero(job.FinalVideoDuration), postgresql.NullIfZero(job.FinalVideoSize),
+		postgresql.NullIfZero(job.SignedURL), postgresql.NullIfZero(job.SignedURLExpiry),
+		job.EmailSent, postgresql.NullIfZero(job.EmailSentAt),
+		postgresql.NullIfZero(job.ErrorMessage), postgresql.NullIfZero(job.ErrorStack), job.RetryCount,
+		postgresql.NullIfZero(job.IPAddress), postgresql.NullIfZero(job.UserAgent),
+		postgresql.NullIfZero(job.StartedAt), postgresql.NullIfZero(job.CompletedAt), postgresql.NullIfZero(job.TotalProcessingTime),
+		job.ConvertedToOrder, job.OrderID, job.ContentModerated, job.ContentModerationResult,
+		job.Watermarked, postgresql.NullIfZero(job.CleanVideoURL), postgresql.NullIfZero(job.CleanVideoS3Key),
+		job.Priority, job.SourceJobID,
+		postgresql.NullIfZero(job.VideoWidth), postgresql.NullIfZero(job.VideoHeight), postgresql.NullIfZero(job.MaxDurationSeconds),
+	}
 }
@@ -0,0 +1,209 @@
+package jobPGX
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ jobRepository.Repository = (*JobPgx)(nil)
+
+func newTestRepo(t *testing.T) (*JobPgx, uow.IUOW) {
+	repo, u, _ := newTestRepoWithDB(t)
+	return repo, u
+}
+
+func newTestRepoWithDB(t *testing.T) (*JobPgx, uow.IUOW, *postgresql.Postgres) {
+	pg := pgtest.New(t)
+	return NewJobPgx(pgtest.Logger(), pg), uow.NewUOW(pg), pg
+}
+
+func TestContract(t *testing.T) {
+	jobContract.Run(t, func(t *testing.T) jobContract.Fixture {
+		repo, u := newTestRepo(t)
+		return jobContract.Fixture{Repo: repo, UOW: u}
+	})
+}
+
+func TestOptionalReferencesRoundTrip(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	id, err := repo.Create(ctx, jobContract.NewJob(1_700_000_000))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	job, err := repo.FindByField(ctx, "id", id)
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if job.OrderID != nil || job.ContentModerationResult != nil {
+		t.Fatalf("unset order_id and moderation result read back as %v and %v, want NULL", job.OrderID, job.ContentModerationResult)
+	}
+
+	orderID := uuid.New()
+	job.OrderID, job.ConvertedToOrder = &orderID, true
+	job.ContentModerationResult = map[string]interface{}{"safe": true}
+	if err := repo.Update(ctx, job); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repo.FindByField(ctx, "id", id)
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if got.OrderID == nil || *got.OrderID != orderID || !got.ConvertedToOrder {
+		t.Errorf("order_id = %v, want %s", got.OrderID, orderID)
+	}
+	if result, ok := got.ContentModerationResult.(map[string]interface{}); !ok || result["safe"] != true {
+		t.Errorf("content_moderation_result = %#v, want {safe: true}", got.ContentModerationResult)
+	}
+
+	got.OrderID, got.ContentModerationResult = nil, nil
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	cleared, err := repo.FindByField(ctx, "id", id)
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if cleared.OrderID != nil || cleared.ContentModerationResult != nil {
+		t.Errorf("cleared references read back as %v and %v, want NULL", cleared.OrderID, cleared.ContentModerationResult)
+	}
+}
+
+func TestFindByFieldRejectsMalformedID(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	_, err := repo.FindByField(context.Background(), "id", "not-a-uuid")
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "22P02" {
+		t.Errorf("FindByField(malformed id) error = %v, want invalid_text_representation", err)
+	}
+}
+
+// TestSparseRowsScan reads a job holding only the NOT NULL columns, the way
+// rows written by other tools or older code look.
+func TestSparseRowsScan(t *testing.T) {
+	repo, _, pg := newTestRepoWithDB(t)
+	ctx := context.Background()
+
+	var id string
+	err := pg.PrimaryConn.QueryRow(ctx, `
+		INSERT INTO jobs (user_email, user_name, input_image_url, input_image_s3_key, style, created_at, updated_at)
+		VALUES ('sparse@example.com', 'Sparse', 'https://example.com/in.jpg', 'in.jpg', NULL, 1700000000, 1700000000)
+		RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert sparse job: %v", err)
+	}
+	if _, err := pg.PrimaryConn.Exec(ctx, `
+		UPDATE jobs SET email_sent = NULL, retry_count = NULL, converted_to_order = NULL,
+			content_moderated = NULL, watermarked = NULL
+		WHERE id = $1`, id); err != nil {
+		t.Fatalf("null defaulted columns: %v", err)
+	}
+
+	job, err := repo.FindByField(ctx, "id", id)
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if job.Style != "" || job.VeoVideoURL != "" || job.VeoDuration != 0 || job.EmailSent || job.RetryCount != 0 || job.OrderID != nil {
+		t.Errorf("NULL columns read back as %+v, want zero values", job)
+	}
+
+	page, err := repo.List(ctx, jobRepository.Filter{}, pagination.Request{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID.String() != id {
+		t.Fatalf("List returned %d jobs, want the sparse one", len(page.Items))
+	}
+
+	claimed, err := repo.ClaimNext(ctx, []entity.JobStatus{entity.JobStatusReceived}, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimNext: %v", err)
+	}
+	if claimed.ID.String() != id {
+		t.Errorf("ClaimNext claimed %s, want %s", claimed.ID, id)
+	}
+}
+
+// TestZeroValuesWriteNull checks that optional columns left unset are
+// stored as NULL and set ones keep their value, through every write path.
+func TestZeroValuesWriteNull(t *testing.T) {
+	repo, u, pg := newTestRepoWithDB(t)
+	ctx := context.Background()
+
+	isNull := func(id string, column string) bool {
+		t.Helper()
+		var null bool
+		if err := pg.PrimaryConn.QueryRow(ctx, "SELECT "+column+" IS NULL FROM jobs WHERE id = $1", id).Scan(&null); err != nil {
+			t.Fatalf("read %s: %v", column, err)
+		}
+		return null
+	}
+	optional := []string{"veo_video_url", "que_job_id", "final_video_size", "signed_url_expiry", "email_sent_at", "error_stack", "started_at"}
+
+	created, err := repo.Create(ctx, jobContract.NewJob(1_700_000_000))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	batched := jobContract.NewJob(1_700_000_001)
+	if _, err := uow.Do(ctx, u, func(ctx context.Context) (interface{}, error) {
+		return repo.CreateMany(ctx, []*entity.Job{batched})
+	}, 5*time.Second); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	for _, id := range []string{created, batched.ID.String()} {
+		for _, column := range optional {
+			if !isNull(id, column) {
+				t.Errorf("job %s: unset %s is not NULL", id, column)
+			}
+		}
+		if isNull(id, "retry_count") || isNull(id, "email_sent") {
+			t.Errorf("job %s: retry_count or email_sent written as NULL", id)
+		}
+	}
+
+	job, err := repo.FindByField(ctx, "id", created)
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	job.Status, job.ErrorStack, job.StartedAt = entity.JobStatusFailed, "stack", 1_700_000_010
+	if err := repo.UpdateStatus(ctx, job); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if isNull(created, "error_stack") || isNull(created, "started_at") {
+		t.Errorf("set error_stack or started_at stored as NULL")
+	}
+
+	// retrying clears the error again
+	job.Status, job.ErrorStack = entity.JobStatusReceived, ""
+	if err := repo.UpdateStatus(ctx, job); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if !isNull(created, "error_stack") {
+		t.Errorf("cleared error_stack is not NULL")
+	}
+	job.ErrorStack = "stack"
+	if err := repo.Update(ctx, job); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	job.ErrorStack = ""
+	if err := repo.Update(ctx, job); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !isNull(created, "error_stack") {
+		t.Errorf("error_stack cleared by Update is not NULL")
+	}
+}
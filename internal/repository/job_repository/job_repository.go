@@ -3,18 +3,61 @@ package jobRepository
 import (
 	"context"
 	"errors"
-	"github.com/jackc/pgx/v5"
+	"time"
+
 	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	"github.com/playture/backend/internal/repository/pagination"
 )
 
 var (
-	ErrJobNotFound = errors.New("job not found")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrNoClaimableJob = errors.New("no job to claim")
+	ErrLeaseLost      = errors.New("job lease is held by another worker or expired")
+	ErrStatusConflict = errors.New("job is not in the expected status")
 )
 
+// Filter narrows down List, zero values are ignored.
+type Filter struct {
+	Statuses    []entity.JobStatus
+	UserEmail   string
+	Style       string
+	CreatedFrom int64 // unix seconds, inclusive
+	CreatedTo   int64 // unix seconds, exclusive
+}
+
 type Repository interface {
-	Create(ctx context.Context, job *entity.Job, tx pgx.Tx) (string, error) // return id
-	FindByField(ctx context.Context, field string, value interface{}, tx pgx.Tx) (*entity.Job, error)
-	List(ctx context.Context, statuses []entity.JobStatus, orderBy string, ascending bool, limit, page int, tx pgx.Tx) ([]*entity.Job, error)
-	Delete(ctx context.Context, id string, tx pgx.Tx) error
-	Update(ctx context.Context, job *entity.Job, tx pgx.Tx) error
+	Create(ctx context.Context, job *entity.Job) (string, error) // return id
+	FindByField(ctx context.Context, field string, value interface{}) (*entity.Job, error)
+	List(ctx context.Context, filter Filter, page pagination.Request) (*pagination.Page[*entity.Job], error)
+	Delete(ctx context.Context, id string) error
+	// Update overwrites every column, prefer the targeted writes below so
+	// concurrent writers do not clobber each other's fields.
+	Update(ctx context.Context, job *entity.Job) error
+
+	// The targeted writes touch only their own columns and updated_at,
+	// taking the values from job.
+	UpdateStatus(ctx context.Context, job *entity.Job) error
+	SetVeoResult(ctx context.Context, job *entity.Job) error
+	SetQueResult(ctx context.Context, job *entity.Job) error
+	SetFinalVideo(ctx context.Context, job *entity.Job) error
+	MarkEmailSent(ctx context.Context, job *entity.Job) error
+	// SetOrder writes ConvertedToOrder and OrderID, which point a preview
+	// job at the open order placed for it.
+	SetOrder(ctx context.Context, job *entity.Job) error
+
+	// The batch writes below must run inside a uow transaction and report
+	// one outcome per input row, in input order.
+	CreateMany(ctx context.Context, jobs []*entity.Job) ([]batch.Outcome, error)
+	UpdateStatusMany(ctx context.Context, ids []string, from, to entity.JobStatus) ([]batch.Outcome, error)
+	DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error)
+
+	// ClaimNext leases the job with the highest priority, oldest first, in
+	// one of statuses to workerID for lease, taking over jobs whose lease
+	// expired. It returns ErrNoClaimableJob when there is nothing to do.
+	ClaimNext(ctx context.Context, statuses []entity.JobStatus, workerID string, lease time.Duration) (*entity.Job, error)
+	// RenewLease extends a lease workerID still holds, ErrLeaseLost means
+	// the job was taken over and the worker must stop working on it.
+	RenewLease(ctx context.Context, id string, workerID string, lease time.Duration) error
+	ReleaseLease(ctx context.Context, id string, workerID string) error
 }
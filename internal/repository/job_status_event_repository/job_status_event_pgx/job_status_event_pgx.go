@@ -0,0 +1,138 @@
+package jobStatusEventPGX
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO job_status_events (
+			job_id, from_status, to_status, detail, created_at
+		) VALUES (
+			$1, NULLIF($2::smallint, 0), $3, NULLIF($4, ''), $5
+		) RETURNING id`
+
+	listByJobQuery = `
+		SELECT id, job_id, COALESCE(from_status, 0), to_status, COALESCE(detail, ''), created_at
+		FROM job_status_events
+		WHERE job_id = $1
+		ORDER BY created_at, id`
+
+	// each event opens a span that the next event of the same job closes,
+	// the span of the current status is still open and left out
+	stageDurationsQuery = `
+		WITH spans AS (
+			SELECT
+				to_status AS status,
+				created_at,
+				LEAD(created_at) OVER (PARTITION BY job_id ORDER BY created_at, id) - created_at AS duration
+			FROM job_status_events
+		)
+		SELECT
+			status,
+			COUNT(*),
+			AVG(duration)::float8,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY duration),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY duration),
+			MAX(duration)
+		FROM spans
+		WHERE duration IS NOT NULL
+			AND ($1::bigint = 0 OR created_at >= $1::bigint)
+			AND ($2::bigint = 0 OR created_at < $2::bigint)
+		GROUP BY status
+		ORDER BY status`
+)
+
+type JobStatusEventPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewJobStatusEventPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *JobStatusEventPgx {
+	return &JobStatusEventPgx{
+		logger:   logger.With("layer", "JobStatusEventRepository"),
+		postgres: postgres,
+	}
+}
+
+func (j *JobStatusEventPgx) Create(ctx context.Context, event *entity.JobStatusEvent) (int64, error) {
+	lg := j.logger.With("method", "Create")
+
+	args := []interface{}{
+		event.JobID, event.FromStatus, event.ToStatus, event.Detail, event.CreatedAt,
+	}
+
+	var (
+		id  int64
+		err error
+	)
+	err = j.postgres.Querier(ctx).QueryRow(ctx, createQuery, args...).Scan(&id)
+	if err != nil {
+		lg.Error("Create failed", "jobID", event.JobID, "err", err)
+		return 0, utils.WrapError("create job status event", err)
+	}
+	return id, nil
+}
+
+func (j *JobStatusEventPgx) ListByJob(ctx context.Context, jobID string) ([]*entity.JobStatusEvent, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	rows, err = j.postgres.Reader(ctx).Query(ctx, listByJobQuery, jobID)
+	if err != nil {
+		return nil, utils.WrapError("list job status events", err)
+	}
+	defer rows.Close()
+
+	events := []*entity.JobStatusEvent{}
+	for rows.Next() {
+		event := &entity.JobStatusEvent{}
+		if err := rows.Scan(
+			&event.ID, &event.JobID, &event.FromStatus, &event.ToStatus, &event.Detail, &event.CreatedAt,
+		); err != nil {
+			return nil, utils.WrapError("scan job status event", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list job status events", err)
+	}
+	return events, nil
+}
+
+func (j *JobStatusEventPgx) StageDurations(ctx context.Context, from, to int64) ([]*entity.StageDuration, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	rows, err = j.postgres.Reader(ctx).Query(ctx, stageDurationsQuery, from, to)
+	if err != nil {
+		return nil, utils.WrapError("aggregate stage durations", err)
+	}
+	defer rows.Close()
+
+	stages := []*entity.StageDuration{}
+	for rows.Next() {
+		s := &entity.StageDuration{}
+		if err := rows.Scan(
+			&s.Status, &s.Count, &s.AvgSeconds, &s.P50Seconds, &s.P95Seconds, &s.MaxSeconds,
+		); err != nil {
+			return nil, utils.WrapError("scan stage duration", err)
+		}
+		stages = append(stages, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("aggregate stage durations", err)
+	}
+	return stages, nil
+}
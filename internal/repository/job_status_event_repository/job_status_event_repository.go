@@ -0,0 +1,15 @@
+package jobStatusEventRepository
+
+import (
+	"context"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+type Repository interface {
+	Create(ctx context.Context, event *entity.JobStatusEvent) (int64, error)
+	ListByJob(ctx context.Context, jobID string) ([]*entity.JobStatusEvent, error) // oldest first
+	// StageDurations aggregates how long jobs stayed in each status, for
+	// transitions into the status within [from, to). Zero bounds are open.
+	StageDurations(ctx context.Context, from, to int64) ([]*entity.StageDuration, error)
+}
@@ -0,0 +1,110 @@
+package mailPostmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	apiURL         = "https://api.postmarkapp.com"
+	requestTimeout = 30 * time.Second
+)
+
+// MailPostmark sends template emails through the Postmark REST API.
+type MailPostmark struct {
+	logger  *slog.Logger
+	apiKey  string
+	from    string
+	skip    bool
+	baseURL string
+	client  *http.Client
+}
+
+func NewMailPostmark(
+	logger *slog.Logger,
+	env *godotenv.Env,
+) *MailPostmark {
+	skip, _ := strconv.ParseBool(env.SkipEmailSending)
+	return &MailPostmark{
+		logger:  logger.With("layer", "MailRepository"),
+		apiKey:  env.PostmarkAPIKey,
+		from:    (&mail.Address{Name: env.PostmarkFromName, Address: env.PostmarkFromEmail}).String(),
+		skip:    skip,
+		baseURL: apiURL,
+		client:  &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type templateEmail struct {
+	From          string                 `json:"From"`
+	To            string                 `json:"To"`
+	TemplateID    int64                  `json:"TemplateId,omitempty"`
+	TemplateAlias string                 `json:"TemplateAlias,omitempty"`
+	TemplateModel map[string]interface{} `json:"TemplateModel"`
+}
+
+type postmarkResponse struct {
+	ErrorCode int    `json:"ErrorCode"`
+	Message   string `json:"Message"`
+}
+
+func (m *MailPostmark) SendTemplate(ctx context.Context, toEmail, toName, template string, model map[string]interface{}) error {
+	lg := m.logger.With("method", "SendTemplate", "template", template)
+
+	if m.skip {
+		lg.Info("email sending is disabled, skipped", "to", toEmail)
+		return nil
+	}
+
+	email := templateEmail{
+		From:          m.from,
+		To:            (&mail.Address{Name: toName, Address: toEmail}).String(),
+		TemplateModel: model,
+	}
+	// Postmark addresses templates by numeric id or by alias
+	if id, err := strconv.ParseInt(template, 10, 64); err == nil {
+		email.TemplateID = id
+	} else {
+		email.TemplateAlias = template
+	}
+	body, err := json.Marshal(email)
+	if err != nil {
+		return utils.WrapError("encode email", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/email/withTemplate", bytes.NewReader(body))
+	if err != nil {
+		return utils.WrapError("build postmark request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", m.apiKey)
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		lg.Error("SendTemplate failed", "err", err)
+		return utils.WrapError("call postmark", err)
+	}
+	defer res.Body.Close()
+
+	out := &postmarkResponse{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(out); err != nil {
+		return utils.WrapError(fmt.Sprintf("decode postmark response (status %d)", res.StatusCode), err)
+	}
+	if res.StatusCode >= 300 || out.ErrorCode != 0 {
+		lg.Error("SendTemplate rejected", "status", res.StatusCode, "errorCode", out.ErrorCode, "message", out.Message)
+		return fmt.Errorf("postmark answered with status %d, error %d: %s", res.StatusCode, out.ErrorCode, out.Message)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mailPostmark
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	mailRepository "github.com/playture/backend/internal/repository/mail_repository"
+)
+
+var _ mailRepository.Repository = (*MailPostmark)(nil)
+
+func newTestPostmark(t *testing.T, env *godotenv.Env, handler http.HandlerFunc) *MailPostmark {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	env.PostmarkAPIKey, env.PostmarkFromEmail, env.PostmarkFromName = "pm_test", "orders@example.com", "Playture"
+	m := NewMailPostmark(slog.New(slog.NewTextHandler(io.Discard, nil)), env)
+	m.baseURL = srv.URL
+	return m
+}
+
+func TestSendTemplate(t *testing.T) {
+	tests := []struct {
+		template  string
+		wantID    int64
+		wantAlias string
+	}{
+		{"1234", 1234, ""},
+		{"order-delivery", 0, "order-delivery"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			m := newTestPostmark(t, &godotenv.Env{}, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/email/withTemplate" || r.Header.Get("X-Postmark-Server-Token") != "pm_test" {
+					t.Errorf("request to %s with token %q", r.URL.Path, r.Header.Get("X-Postmark-Server-Token"))
+				}
+				var got templateEmail
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if got.TemplateID != tt.wantID || got.TemplateAlias != tt.wantAlias {
+					t.Errorf("template = %d %q, want %d %q", got.TemplateID, got.TemplateAlias, tt.wantID, tt.wantAlias)
+				}
+				if got.From != `"Playture" <orders@example.com>` || got.To != `"Someone" <someone@example.com>` || got.TemplateModel["link"] != "https://x" {
+					t.Errorf("email = %+v", got)
+				}
+				w.Write([]byte(`{"ErrorCode": 0, "Message": "OK"}`))
+			})
+
+			err := m.SendTemplate(context.Background(), "someone@example.com", "Someone", tt.template, map[string]interface{}{"link": "https://x"})
+			if err != nil {
+				t.Fatalf("SendTemplate: %v", err)
+			}
+		})
+	}
+}
+
+func TestSendTemplateErrors(t *testing.T) {
+	m := newTestPostmark(t, &godotenv.Env{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"ErrorCode": 1101, "Message": "template not found"}`))
+	})
+	if err := m.SendTemplate(context.Background(), "someone@example.com", "", "missing", nil); err == nil {
+		t.Error("SendTemplate succeeded on a rejected email")
+	}
+}
+
+func TestSendTemplateSkipped(t *testing.T) {
+	m := newTestPostmark(t, &godotenv.Env{SkipEmailSending: "true"}, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("email sent although sending is disabled")
+	})
+	if err := m.SendTemplate(context.Background(), "someone@example.com", "", "1234", nil); err != nil {
+		t.Errorf("SendTemplate: %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package mailRepository
+
+import (
+	"context"
+)
+
+// Repository is the transactional email provider.
+type Repository interface {
+	// SendTemplate sends the provider template template, an id or an alias,
+	// to one recipient, filled in with model.
+	SendTemplate(ctx context.Context, toEmail, toName, template string, model map[string]interface{}) error
+}
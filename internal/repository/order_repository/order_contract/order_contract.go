@@ -0,0 +1,506 @@
+package order_contract
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const txTimeout = 5 * time.Second
+
+// Fixture is one implementation under test, with a unit of work whose
+// transactions its batch writes accept. NewJobID returns the id of a job
+// an order may reference, stores with a foreign key have to insert it.
+type Fixture struct {
+	Repo     orderRepository.Repository
+	UOW      uow.IUOW
+	NewJobID func(t *testing.T) uuid.UUID
+}
+
+// Run checks the behaviour every orderRepository.Repository has to share.
+// newFixture is called for each subtest and must return an empty store.
+func Run(t *testing.T, newFixture func(t *testing.T) Fixture) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f Fixture)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"ListFilters", testListFilters},
+		{"ListPaginates", testListPaginates},
+		{"ListExpired", testListExpired},
+		{"TargetedUpdates", testTargetedUpdates},
+		{"Lock", testLock},
+		{"BatchRequiresTx", testBatchRequiresTx},
+		{"BatchOutcomes", testBatchOutcomes},
+		{"CreateManyAborts", testCreateManyAborts},
+		{"Rollback", testRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newFixture(t))
+		})
+	}
+}
+
+// NewOrder returns a valid pending order for jobID created at the given
+// unix second.
+func NewOrder(jobID uuid.UUID, createdAt int64) *entity.Order {
+	return &entity.Order{
+		ID:               uuid.New(),
+		JobID:            jobID,
+		UserEmail:        "someone@example.com",
+		UserName:         "Someone",
+		Amount:           entity.Money{Minor: 4999, Currency: "USD"},
+		PaymentStatus:    entity.PaymentStatusPending,
+		OrderType:        entity.OrderTypeBasic,
+		ProductionStatus: entity.ProductionStatusPending,
+		DeliveryMethod:   entity.DeliveryMethodDownload,
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+	}
+}
+
+func (f Fixture) create(t *testing.T, createdAt int64) *entity.Order {
+	t.Helper()
+	order := NewOrder(f.NewJobID(t), createdAt)
+	if _, err := f.Repo.Create(context.Background(), order); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return order
+}
+
+func (f Fixture) find(t *testing.T, id uuid.UUID) *entity.Order {
+	t.Helper()
+	order, err := f.Repo.FindByField(context.Background(), "id", id.String())
+	if err != nil {
+		t.Fatalf("FindByField(id, %s): %v", id, err)
+	}
+	return order
+}
+
+func testCreateAndFind(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	order := NewOrder(f.NewJobID(t), 1_700_000_000)
+	order.StripePaymentIntentID = "pi_123"
+
+	id, err := f.Repo.Create(ctx, order)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if id != order.ID.String() {
+		t.Fatalf("Create returned %q, want %s", id, order.ID)
+	}
+
+	got := f.find(t, order.ID)
+	if got.JobID != order.JobID || got.Amount != order.Amount || got.PaymentStatus != order.PaymentStatus ||
+		got.ProductionJobID != nil || got.CreatedAt != order.CreatedAt {
+		t.Errorf("FindByField(id) = %+v, want the created order", got)
+	}
+
+	for field, value := range map[string]interface{}{
+		"job_id":                   order.JobID.String(),
+		"stripe_payment_intent_id": "pi_123",
+	} {
+		found, err := f.Repo.FindByField(ctx, field, value)
+		if err != nil {
+			t.Fatalf("FindByField(%s): %v", field, err)
+		}
+		if found.ID != order.ID {
+			t.Errorf("FindByField(%s) found %s, want %s", field, found.ID, order.ID)
+		}
+	}
+
+	if _, err := f.Repo.FindByField(ctx, "id", uuid.NewString()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("FindByField(unknown id) error = %v, want ErrOrderNotFound", err)
+	}
+	if _, err := f.Repo.Create(ctx, order); err == nil {
+		t.Errorf("Create with a taken id succeeded")
+	}
+}
+
+func testUpdate(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	order := f.create(t, 1_700_000_000)
+
+	productionJobID := f.NewJobID(t)
+	order.PaymentStatus = entity.PaymentStatusPaid
+	order.PaidAt = 1_700_000_050
+	order.ProductionJobID = &productionJobID
+	order.UpdatedAt = 1_700_000_100
+	if err := f.Repo.Update(ctx, order); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got := f.find(t, order.ID)
+	if got.PaymentStatus != entity.PaymentStatusPaid || got.PaidAt != 1_700_000_050 || got.UpdatedAt != 1_700_000_100 ||
+		got.ProductionJobID == nil || *got.ProductionJobID != productionJobID {
+		t.Errorf("after Update got %+v", got)
+	}
+
+	missing := NewOrder(order.JobID, 1_700_000_000)
+	if err := f.Repo.Update(ctx, missing); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("Update(unknown) error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	order := f.create(t, 1_700_000_000)
+
+	if err := f.Repo.Delete(ctx, order.ID.String()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", order.ID.String()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("FindByField after Delete error = %v, want ErrOrderNotFound", err)
+	}
+	if err := f.Repo.Delete(ctx, order.ID.String()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("Delete(deleted) error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func testListFilters(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	pending := f.create(t, 1_700_000_000)
+	paid := NewOrder(f.NewJobID(t), 1_700_000_100)
+	paid.PaymentStatus = entity.PaymentStatusPaid
+	producing := NewOrder(f.NewJobID(t), 1_700_000_200)
+	producing.PaymentStatus, producing.ProductionStatus = entity.PaymentStatusPaid, entity.ProductionStatusProcessing
+	for _, order := range []*entity.Order{paid, producing} {
+		if _, err := f.Repo.Create(ctx, order); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	paidStatus, processing := entity.PaymentStatusPaid, entity.ProductionStatusProcessing
+	tests := []struct {
+		name       string
+		payment    *entity.PaymentStatus
+		production *entity.ProductionStatus
+		want       []uuid.UUID // newest first
+	}{
+		{"all", nil, nil, []uuid.UUID{producing.ID, paid.ID, pending.ID}},
+		{"payment", &paidStatus, nil, []uuid.UUID{producing.ID, paid.ID}},
+		{"production", nil, &processing, []uuid.UUID{producing.ID}},
+		{"both", &paidStatus, &processing, []uuid.UUID{producing.ID}},
+	}
+	for _, tt := range tests {
+		page, err := f.Repo.List(ctx, tt.payment, tt.production, pagination.Request{})
+		if err != nil {
+			t.Fatalf("%s: List: %v", tt.name, err)
+		}
+		if got := orderIDs(page.Items); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: List = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testListPaginates(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	// two orders share a second so the id has to break the tie
+	for _, ts := range []int64{1_700_000_000, 1_700_000_000, 1_700_000_100} {
+		f.create(t, ts)
+	}
+	all, err := f.Repo.List(ctx, nil, nil, pagination.Request{Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := orderIDs(all.Items)
+
+	first, err := f.Repo.List(ctx, nil, nil, pagination.Request{Limit: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	second, err := f.Repo.List(ctx, nil, nil, pagination.Request{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("List(next): %v", err)
+	}
+	if got := append(orderIDs(first.Items), orderIDs(second.Items)...); !slices.Equal(got, want) {
+		t.Errorf("walking forward got %v, want %v", got, want)
+	}
+	if !first.HasNext() || second.HasNext() || !second.HasPrev() {
+		t.Errorf("cursors: first next %v, second next %v prev %v", first.HasNext(), second.HasNext(), second.HasPrev())
+	}
+
+	back, err := f.Repo.List(ctx, nil, nil, pagination.Request{Limit: 2, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("List(prev): %v", err)
+	}
+	if got := orderIDs(back.Items); !slices.Equal(got, want[:2]) {
+		t.Errorf("walking back got %v, want %v", got, want[:2])
+	}
+}
+
+func testListExpired(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	newOrder := func(status entity.PaymentStatus, expiresAt int64) *entity.Order {
+		order := NewOrder(f.NewJobID(t), 1_700_000_000)
+		order.PaymentStatus, order.ExpiresAt = status, expiresAt
+		if _, err := f.Repo.Create(ctx, order); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return order
+	}
+	late := newOrder(entity.PaymentStatusPending, 1_700_000_200)
+	early := newOrder(entity.PaymentStatusFailed, 1_700_000_100)
+	newOrder(entity.PaymentStatusPaid, 1_700_000_100)
+	newOrder(entity.PaymentStatusPending, 1_700_000_500)
+	newOrder(entity.PaymentStatusPending, 0)
+
+	unpaid := []entity.PaymentStatus{entity.PaymentStatusPending, entity.PaymentStatusFailed}
+	got, err := f.Repo.ListExpired(ctx, unpaid, 1_700_000_300, 10)
+	if err != nil {
+		t.Fatalf("ListExpired: %v", err)
+	}
+	if ids := orderIDs(got); !slices.Equal(ids, []uuid.UUID{early.ID, late.ID}) {
+		t.Errorf("ListExpired = %v, want %v", ids, []uuid.UUID{early.ID, late.ID})
+	}
+
+	got, err = f.Repo.ListExpired(ctx, unpaid, 1_700_000_300, 1)
+	if err != nil {
+		t.Fatalf("ListExpired: %v", err)
+	}
+	if ids := orderIDs(got); !slices.Equal(ids, []uuid.UUID{early.ID}) {
+		t.Errorf("ListExpired(limit 1) = %v, want %v", ids, []uuid.UUID{early.ID})
+	}
+}
+
+func testTargetedUpdates(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	order := f.create(t, 1_700_000_000)
+
+	// the payment webhook and the production pipeline hold their own copy
+	webhook, pipeline := *order, *order
+
+	webhook.PaymentStatus, webhook.PaidAt, webhook.StripeCustomerID = entity.PaymentStatusPaid, 1_700_000_010, "cus_1"
+	if err := f.Repo.UpdatePayment(ctx, &webhook); err != nil {
+		t.Fatalf("UpdatePayment: %v", err)
+	}
+	productionJobID := f.NewJobID(t)
+	pipeline.ProductionStatus, pipeline.ProductionJobID = entity.ProductionStatusCompleted, &productionJobID
+	if err := f.Repo.UpdateProduction(ctx, &pipeline); err != nil {
+		t.Fatalf("UpdateProduction: %v", err)
+	}
+	pipeline.DeliveryMethod, pipeline.DeliveredAt = entity.DeliveryMethodEmail, 1_700_000_020
+	if err := f.Repo.MarkDelivered(ctx, &pipeline); err != nil {
+		t.Fatalf("MarkDelivered: %v", err)
+	}
+	webhook.CustomerNotes = "gift"
+	if err := f.Repo.UpdateCustomerNotes(ctx, &webhook); err != nil {
+		t.Fatalf("UpdateCustomerNotes: %v", err)
+	}
+	pipeline.ExpiresAt = 1_700_000_030
+	if err := f.Repo.UpdateExpiry(ctx, &pipeline); err != nil {
+		t.Fatalf("UpdateExpiry: %v", err)
+	}
+
+	got := f.find(t, order.ID)
+	if got.PaymentStatus != entity.PaymentStatusPaid || got.PaidAt != 1_700_000_010 || got.StripeCustomerID != "cus_1" || got.CustomerNotes != "gift" {
+		t.Errorf("payment fields lost: %+v", got)
+	}
+	if got.ProductionStatus != entity.ProductionStatusCompleted || got.ProductionJobID == nil || *got.ProductionJobID != productionJobID ||
+		got.DeliveryMethod != entity.DeliveryMethodEmail || got.DeliveredAt != 1_700_000_020 || got.ExpiresAt != 1_700_000_030 {
+		t.Errorf("production fields lost: %+v", got)
+	}
+
+	missing := *order
+	missing.ID = uuid.New()
+	if err := f.Repo.UpdatePayment(ctx, &missing); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("UpdatePayment(unknown) error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func testLock(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	order := f.create(t, 1_700_000_000)
+
+	_, err := f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		got, err := f.Repo.Lock(ctx, order.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		if got.ID != order.ID || got.Amount != order.Amount {
+			t.Errorf("Lock = %+v, want %+v", got, order)
+		}
+		if _, err := f.Repo.Lock(ctx, uuid.NewString()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+			t.Errorf("Lock(unknown) error = %v, want ErrOrderNotFound", err)
+		}
+		return nil, nil
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+}
+
+func testBatchRequiresTx(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	orders := []*entity.Order{NewOrder(f.NewJobID(t), 1_700_000_000)}
+	if _, err := f.Repo.CreateMany(ctx, orders); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("CreateMany outside uow error = %v, want ErrTxRequired", err)
+	}
+	if _, err := f.Repo.UpdateStatusMany(ctx, []string{uuid.NewString()}, entity.ProductionStatusPending, entity.ProductionStatusProcessing); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("UpdateStatusMany outside uow error = %v, want ErrTxRequired", err)
+	}
+	if _, err := f.Repo.DeleteMany(ctx, []string{uuid.NewString()}); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("DeleteMany outside uow error = %v, want ErrTxRequired", err)
+	}
+	if _, err := f.Repo.Lock(ctx, uuid.NewString()); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("Lock outside uow error = %v, want ErrTxRequired", err)
+	}
+}
+
+func testBatchOutcomes(t *testing.T, f Fixture) {
+	ctx := context.Background()
+
+	orders := []*entity.Order{
+		NewOrder(f.NewJobID(t), 1_700_000_000),
+		NewOrder(f.NewJobID(t), 1_700_000_001),
+		NewOrder(f.NewJobID(t), 1_700_000_002),
+	}
+	orders[1].ProductionStatus = entity.ProductionStatusFailed
+	created, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.CreateMany(ctx, orders)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	for i, o := range created {
+		if o.Err != nil || o.ID != orders[i].ID.String() {
+			t.Fatalf("CreateMany outcome %d = %+v, want %s", i, o, orders[i].ID)
+		}
+	}
+
+	ids := []string{orders[0].ID.String(), orders[1].ID.String(), uuid.NewString(), "not-a-uuid"}
+	moved, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.UpdateStatusMany(ctx, ids, entity.ProductionStatusPending, entity.ProductionStatusProcessing)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("UpdateStatusMany: %v", err)
+	}
+	checkOutcomes(t, "UpdateStatusMany", moved, ids, []error{nil, orderRepository.ErrStatusConflict, orderRepository.ErrOrderNotFound, orderRepository.ErrOrderNotFound})
+	if got := f.find(t, orders[0].ID); got.ProductionStatus != entity.ProductionStatusProcessing {
+		t.Errorf("moved order has production status %v, want PROCESSING", got.ProductionStatus)
+	}
+
+	ids = []string{orders[0].ID.String(), uuid.NewString(), orders[2].ID.String()}
+	deleted, err := uow.Do(ctx, f.UOW, func(ctx context.Context) ([]batch.Outcome, error) {
+		return f.Repo.DeleteMany(ctx, ids)
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	checkOutcomes(t, "DeleteMany", deleted, ids, []error{nil, orderRepository.ErrOrderNotFound, nil})
+
+	page, err := f.Repo.List(ctx, nil, nil, pagination.Request{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != orders[1].ID {
+		t.Errorf("after DeleteMany %d orders are left, want only %s", len(page.Items), orders[1].ID)
+	}
+}
+
+func testCreateManyAborts(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	taken := f.create(t, 1_700_000_000)
+
+	orders := []*entity.Order{
+		NewOrder(f.NewJobID(t), 1_700_000_001),
+		NewOrder(f.NewJobID(t), 1_700_000_002),
+		NewOrder(f.NewJobID(t), 1_700_000_003),
+	}
+	orders[1].ID = taken.ID
+
+	var outcomes []batch.Outcome
+	_, err := f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		var err error
+		outcomes, err = f.Repo.CreateMany(ctx, orders)
+		return nil, err
+	}, txTimeout)
+	if err == nil {
+		t.Fatalf("CreateMany with a taken id succeeded")
+	}
+	if len(outcomes) != 3 || outcomes[1].Err == nil || !errors.Is(outcomes[2].Err, batch.ErrAborted) {
+		t.Fatalf("CreateMany outcomes = %+v, want the second failed and the third aborted", outcomes)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", orders[0].ID.String()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("order before the failing one survived the rollback: error = %v", err)
+	}
+}
+
+func testRollback(t *testing.T, f Fixture) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	kept, dropped := NewOrder(f.NewJobID(t), 1_700_000_000), NewOrder(f.NewJobID(t), 1_700_000_001)
+	_, err := f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		if _, err := f.Repo.Create(ctx, kept); err != nil {
+			return nil, err
+		}
+		// a failing nested unit rolls back only its own writes
+		_, err := f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+			if _, err := f.Repo.Create(ctx, dropped); err != nil {
+				return nil, err
+			}
+			return nil, errAbort
+		}, txTimeout)
+		if !errors.Is(err, errAbort) {
+			return nil, err
+		}
+		return nil, nil
+	}, txTimeout)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	f.find(t, kept.ID)
+	if _, err := f.Repo.FindByField(ctx, "id", dropped.ID.String()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("order of the rolled back nested unit: error = %v, want ErrOrderNotFound", err)
+	}
+
+	rolledBack := NewOrder(f.NewJobID(t), 1_700_000_002)
+	_, err = f.UOW.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		if _, err := f.Repo.Create(ctx, rolledBack); err != nil {
+			return nil, err
+		}
+		return nil, errAbort
+	}, txTimeout)
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Do error = %v, want the error of fn", err)
+	}
+	if _, err := f.Repo.FindByField(ctx, "id", rolledBack.ID.String()); !errors.Is(err, orderRepository.ErrOrderNotFound) {
+		t.Errorf("order of the rolled back unit: error = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func orderIDs(orders []*entity.Order) []uuid.UUID {
+	out := make([]uuid.UUID, len(orders))
+	for i, order := range orders {
+		out[i] = order.ID
+	}
+	return out
+}
+
+func checkOutcomes(t *testing.T, method string, got []batch.Outcome, ids []string, want []error) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d outcomes, want %d", method, len(got), len(want))
+	}
+	for i, o := range got {
+		if !errors.Is(o.Err, want[i]) {
+			t.Errorf("%s outcome %d (%s) error = %v, want %v", method, i, ids[i], o.Err, want[i])
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package order_memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/utils"
+)
+
+// columns maps the column names FindByField accepts to the order field they
+// hold. NULL-able references yield nil so they never match, like in SQL.
+var columns = map[string]func(*entity.Order) interface{}{
+	"id":                       func(o *entity.Order) interface{} { return o.ID },
+	"job_id":                   func(o *entity.Order) interface{} { return o.JobID },
+	"user_email":               func(o *entity.Order) interface{} { return o.UserEmail },
+	"stripe_payment_intent_id": func(o *entity.Order) interface{} { return o.StripePaymentIntentID },
+	"stripe_customer_id":       func(o *entity.Order) interface{} { return o.StripeCustomerID },
+	"payment_status":           func(o *entity.Order) interface{} { return o.PaymentStatus },
+	"production_status":        func(o *entity.Order) interface{} { return o.ProductionStatus },
+	"support_ticket_id":        func(o *entity.Order) interface{} { return o.SupportTicketID },
+	"production_job_id": func(o *entity.Order) interface{} {
+		if o.ProductionJobID == nil {
+			return nil
+		}
+		return *o.ProductionJobID
+	},
+}
+
+// uuidColumns reject malformed values the way Postgres does.
+var uuidColumns = map[string]bool{"id": true, "job_id": true, "production_job_id": true}
+
+// OrderMemory is an orderRepository.Repository backed by a map, for tests.
+// It follows OrderPgx in errors, ordering and pagination, except that the
+// job_id foreign key and the currency check are not enforced. Batch writes
+// need a transaction from uow.MemoryUOW, the way OrderPgx needs one from
+// uow.UOW.
+type OrderMemory struct {
+	mu     sync.RWMutex
+	orders map[uuid.UUID]entity.Order
+}
+
+func NewOrderMemory() *OrderMemory {
+	return &OrderMemory{
+		orders: map[uuid.UUID]entity.Order{},
+	}
+}
+
+// Snapshot implements uow.Snapshotter.
+func (o *OrderMemory) Snapshot() func() {
+	o.mu.RLock()
+	saved := make(map[uuid.UUID]entity.Order, len(o.orders))
+	for id, order := range o.orders {
+		saved[id] = clone(order)
+	}
+	o.mu.RUnlock()
+
+	return func() {
+		o.mu.Lock()
+		o.orders = saved
+		o.mu.Unlock()
+	}
+}
+
+func (o *OrderMemory) Create(ctx context.Context, order *entity.Order) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.insert(order); err != nil {
+		return "", utils.WrapError("insert order", err)
+	}
+	return order.ID.String(), nil
+}
+
+func (o *OrderMemory) FindByField(ctx context.Context, field string, value interface{}) (*entity.Order, error) {
+	get, ok := columns[field]
+	if !ok {
+		return nil, utils.WrapError("find order by field", fmt.Errorf("column %q does not exist", field))
+	}
+
+	want := fmt.Sprint(value)
+	if uuidColumns[field] {
+		if _, err := uuid.Parse(want); err != nil {
+			return nil, utils.WrapError("find order by field", invalidUUID(want))
+		}
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, order := range o.sorted() {
+		if v := get(&order); v != nil && fmt.Sprint(v) == want {
+			found := clone(order)
+			return &found, nil
+		}
+	}
+	return nil, orderRepository.ErrOrderNotFound
+}
+
+// Lock is FindByField by id. MemoryUOW serializes transactions, so there is
+// no row to hold.
+func (o *OrderMemory) Lock(ctx context.Context, id string) (*entity.Order, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+	return o.FindByField(ctx, "id", id)
+}
+
+func (o *OrderMemory) List(ctx context.Context, paymentStatus *entity.PaymentStatus, productionStatus *entity.ProductionStatus, page pagination.Request) (*pagination.Page[*entity.Order], error) {
+	keyset, err := page.Keyset(1)
+	if err != nil {
+		return nil, utils.WrapError("list orders", err)
+	}
+
+	o.mu.RLock()
+	var matched []*entity.Order
+	for _, order := range o.orders {
+		if paymentStatus != nil && order.PaymentStatus != *paymentStatus {
+			continue
+		}
+		if productionStatus != nil && order.ProductionStatus != *productionStatus {
+			continue
+		}
+		found := clone(order)
+		matched = append(matched, &found)
+	}
+	o.mu.RUnlock()
+
+	key := func(order *entity.Order) (int64, uuid.UUID) {
+		return order.CreatedAt, order.ID
+	}
+	return pagination.Build(keyset, pagination.Fetch(keyset, matched, key), key), nil
+}
+
+func (o *OrderMemory) ListExpired(ctx context.Context, statuses []entity.PaymentStatus, before int64, limit int) ([]*entity.Order, error) {
+	o.mu.RLock()
+	var matched []*entity.Order
+	for _, order := range o.orders {
+		if order.ExpiresAt == 0 || order.ExpiresAt >= before || !slices.Contains(statuses, order.PaymentStatus) {
+			continue
+		}
+		found := clone(order)
+		matched = append(matched, &found)
+	}
+	o.mu.RUnlock()
+
+	sort.Slice(matched, func(a, b int) bool {
+		if matched[a].ExpiresAt != matched[b].ExpiresAt {
+			return matched[a].ExpiresAt < matched[b].ExpiresAt
+		}
+		return bytes.Compare(matched[a].ID[:], matched[b].ID[:]) < 0
+	})
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+func (o *OrderMemory) Delete(ctx context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return utils.WrapError("delete order", invalidUUID(id))
+	}
+	if _, ok := o.orders[key]; !ok {
+		return orderRepository.ErrOrderNotFound
+	}
+	delete(o.orders, key)
+	return nil
+}
+
+// Update overwrites every column but created_at, like OrderPgx.Update.
+func (o *OrderMemory) Update(ctx context.Context, order *entity.Order) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stored, ok := o.orders[order.ID]
+	if !ok {
+		return orderRepository.ErrOrderNotFound
+	}
+	updated := clone(*order)
+	updated.CreatedAt = stored.CreatedAt
+	o.orders[order.ID] = updated
+	return nil
+}
+
+// CreateMany stops at the first failing order like the batch in OrderPgx,
+// the orders after it are reported as batch.ErrAborted.
+func (o *OrderMemory) CreateMany(ctx context.Context, orders []*entity.Order) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	outcomes := make([]batch.Outcome, len(orders))
+	var firstErr error
+	for i, order := range orders {
+		outcomes[i].ID = order.ID.String()
+		if firstErr != nil {
+			outcomes[i].Err = batch.ErrAborted
+			continue
+		}
+		if err := o.insert(order); err != nil {
+			outcomes[i].Err = err
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return outcomes, utils.WrapError("create orders", firstErr)
+	}
+	return outcomes, nil
+}
+
+func (o *OrderMemory) UpdateStatusMany(ctx context.Context, ids []string, from, to entity.ProductionStatus) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now().Unix()
+	outcomes, _ := batch.ParseIDs(ids, orderRepository.ErrOrderNotFound)
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		order, ok := o.orders[uuid.MustParse(outcomes[i].ID)]
+		switch {
+		case !ok:
+			outcomes[i].Err = orderRepository.ErrOrderNotFound
+		case order.ProductionStatus != from:
+			outcomes[i].Err = orderRepository.ErrStatusConflict
+		default:
+			order.ProductionStatus, order.UpdatedAt = to, now
+			o.orders[order.ID] = order
+		}
+	}
+	return outcomes, nil
+}
+
+func (o *OrderMemory) DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error) {
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	outcomes, _ := batch.ParseIDs(ids, orderRepository.ErrOrderNotFound)
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		key := uuid.MustParse(outcomes[i].ID)
+		if _, ok := o.orders[key]; !ok {
+			outcomes[i].Err = orderRepository.ErrOrderNotFound
+			continue
+		}
+		delete(o.orders, key)
+	}
+	return outcomes, nil
+}
+
+func (o *OrderMemory) UpdatePayment(ctx context.Context, order *entity.Order) error {
+	return o.patch(order, func(stored *entity.Order) {
+		stored.PaymentStatus, stored.PaidAt = order.PaymentStatus, order.PaidAt
+		stored.StripePaymentIntentID, stored.StripeCustomerID = order.StripePaymentIntentID, order.StripeCustomerID
+	})
+}
+
+func (o *OrderMemory) UpdateProduction(ctx context.Context, order *entity.Order) error {
+	return o.patch(order, func(stored *entity.Order) {
+		stored.ProductionStatus = order.ProductionStatus
+		stored.ProductionJobID = order.ProductionJobID
+	})
+}
+
+func (o *OrderMemory) MarkDelivered(ctx context.Context, order *entity.Order) error {
+	return o.patch(order, func(stored *entity.Order) {
+		stored.DeliveryMethod, stored.DeliveredAt = order.DeliveryMethod, order.DeliveredAt
+	})
+}
+
+func (o *OrderMemory) UpdateCustomerNotes(ctx context.Context, order *entity.Order) error {
+	return o.patch(order, func(stored *entity.Order) {
+		stored.CustomerNotes = order.CustomerNotes
+	})
+}
+
+func (o *OrderMemory) UpdateExpiry(ctx context.Context, order *entity.Order) error {
+	return o.patch(order, func(stored *entity.Order) {
+		stored.ExpiresAt = order.ExpiresAt
+	})
+}
+
+// patch applies set to the stored copy of order and bumps updated_at on
+// both.
+
+func (o *OrderMemory) patch(order *entity.Order, set func(stored *entity.Order)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stored, ok := o.orders[order.ID]
+	if !ok {
+		return orderRepository.ErrOrderNotFound
+	}
+	order.UpdatedAt = time.Now().Unix()
+	set(&stored)
+	stored.ProductionJobID = clone(stored).ProductionJobID
+	stored.UpdatedAt = order.UpdatedAt
+	o.orders[order.ID] = stored
+	return nil
+}
+
+// insert stores order under its own id, which the caller chooses.
+func (o *OrderMemory) insert(order *entity.Order) error {
+	if _, exists := o.orders[order.ID]; exists {
+		return &pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "orders_pkey"`}
+	}
+	o.orders[order.ID] = clone(*order)
+	return nil
+}
+
+// sorted returns the orders oldest first, by (created_at, id).
+func (o *OrderMemory) sorted() []entity.Order {
+	all := make([]entity.Order, 0, len(o.orders))
+	for _, order := range o.orders {
+		all = append(all, order)
+	}
+	sort.Slice(all, func(a, b int) bool {
+		if all[a].CreatedAt != all[b].CreatedAt {
+			return all[a].CreatedAt < all[b].CreatedAt
+		}
+		return bytes.Compare(all[a].ID[:], all[b].ID[:]) < 0
+	})
+	return all
+}
+
+// clone copies order so callers never share pointers with the store.
+func clone(order entity.Order) entity.Order {
+	if order.ProductionJobID != nil {
+		id := *order.ProductionJobID
+		order.ProductionJobID = &id
+	}
+	return order
+}
+
+func invalidUUID(id string) error {
+	return &pgconn.PgError{Code: "22P02", Message: fmt.Sprintf("invalid input syntax for type uuid: %q", id)}
+}
@@ -0,0 +1,23 @@
+package order_memory
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ orderRepository.Repository = (*OrderMemory)(nil)
+
+func TestContract(t *testing.T) {
+	order_contract.Run(t, func(t *testing.T) order_contract.Fixture {
+		repo := NewOrderMemory()
+		return order_contract.Fixture{
+			Repo:     repo,
+			UOW:      uow.NewMemoryUOW(repo),
+			NewJobID: func(*testing.T) uuid.UUID { return uuid.New() },
+		}
+	})
+}
@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/playture/backend/internal/entity"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/repository/batch"
 	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/pagination"
 	"github.com/playture/backend/utils"
 )
 
@@ -17,27 +22,62 @@ const (
 	createOrder = `
 		INSERT INTO orders (
 			id, job_id, user_email, user_name, stripe_payment_intent_id, stripe_customer_id,
-			amount, currency, payment_status, paid_at, order_type, requirements,
+			amount_minor, currency, payment_status, paid_at, order_type, requirements,
 			production_job_id, production_status, delivery_method, delivered_at,
 			customer_notes, support_ticket_id, ip_address, user_agent, expires_at,
+			promotion_id, discount_minor,
 			created_at, updated_at
 		) VALUES (
 			$1,$2,$3,$4,$5,$6,
 			$7,$8,$9,$10,$11,$12,
 			$13,$14,$15,$16,
 			$17,$18,$19,$20,$21,
-			$22,$23
+			$22,$23,
+			$24,$25
 		) RETURNING id`
 
+	// orderColumns reads every column in the order scanOrder expects, so
+	// columns added by later migrations do not shift them. Optional columns
+	// come back as their zero value when NULL.
+	orderColumns = `
+		id, job_id, user_email, user_name, COALESCE(stripe_payment_intent_id, ''), COALESCE(stripe_customer_id, ''),
+		amount_minor, currency, payment_status, COALESCE(paid_at, 0), order_type, COALESCE(requirements, ''),
+		production_job_id, production_status, delivery_method, COALESCE(delivered_at, 0),
+		COALESCE(customer_notes, ''), COALESCE(support_ticket_id, ''), COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(expires_at, 0),
+		promotion_id, discount_minor, currency,
+		created_at, updated_at`
+
 	deleteOrder = "DELETE FROM orders WHERE id = $1"
 
+	listExpiredQuery = `SELECT ` + orderColumns + ` FROM orders
+		WHERE payment_status = ANY($1) AND expires_at < $2
+		ORDER BY expires_at, id LIMIT $3`
+
+	// updateProductionStatusMany locks every requested order, moves the ones
+	// still in the expected production status and reports for each locked
+	// order whether it moved.
+	updateProductionStatusMany = `
+		WITH target AS (
+			SELECT id, production_status FROM orders WHERE id = ANY($1) FOR UPDATE
+		), moved AS (
+			UPDATE orders SET production_status = $3, updated_at = $4
+			FROM target
+			WHERE orders.id = target.id AND target.production_status = $2
+			RETURNING orders.id
+		)
+		SELECT target.id, moved.id IS NOT NULL
+		FROM target LEFT JOIN moved ON moved.id = target.id`
+
+	deleteManyOrders = "DELETE FROM orders WHERE id = ANY($1) RETURNING id"
+
 	updateQuery = `
 		UPDATE orders
 		SET job_id=$2, user_email=$3, user_name=$4, stripe_payment_intent_id=$5, stripe_customer_id=$6,
-			amount=$7, currency=$8, payment_status=$9, paid_at=$10, order_type=$11, requirements=$12,
+			amount_minor=$7, currency=$8, payment_status=$9, paid_at=$10, order_type=$11, requirements=$12,
 			production_job_id=$13, production_status=$14, delivery_method=$15, delivered_at=$16,
 			customer_notes=$17, support_ticket_id=$18, ip_address=$19, user_agent=$20, expires_at=$21,
-			updated_at=$22
+			promotion_id=$22, discount_minor=$23,
+			updated_at=$24
 		WHERE id=$1`
 )
 
@@ -59,18 +99,11 @@ func NewOrderPgx(
 func (o *OrderPgx) Create(
 	ctx context.Context,
 	order *entity.Order,
-	tx pgx.Tx,
 ) (string, error) {
 	lg := o.logger.With("method", "Create")
 
 	var id string
-	err := tx.QueryRow(ctx, createOrder,
-		order.ID, order.JobID, order.UserEmail, order.UserName, order.StripePaymentIntentID, order.StripeCustomerID,
-		order.Amount, order.Currency, order.PaymentStatus, order.PaidAt, order.OrderType, order.Requirements,
-		order.ProductionJobID, order.ProductionStatus, order.DeliveryMethod, order.DeliveredAt,
-		order.CustomerNotes, order.SupportTicketID, order.IPAddress, order.UserAgent, order.ExpiresAt,
-		order.CreatedAt, order.UpdatedAt,
-	).Scan(&id)
+	err := o.postgres.Querier(ctx).QueryRow(ctx, createOrder, createValues(order)...).Scan(&id)
 
 	if err != nil {
 		lg.Error("failed to insert order", "err", err)
@@ -80,20 +113,14 @@ func (o *OrderPgx) Create(
 	return id, nil
 }
 
-func (o *OrderPgx) FindByField(ctx context.Context, field string, value interface{}, tx pgx.Tx) (*entity.Order, error) {
+func (o *OrderPgx) FindByField(ctx context.Context, field string, value interface{}) (*entity.Order, error) {
 	lg := o.logger.With("method", "FindByField")
 
-	query := fmt.Sprintf("SELECT * FROM orders WHERE %s = $1 LIMIT 1", field)
+	query := fmt.Sprintf("SELECT %s FROM orders WHERE %s = $1 LIMIT 1", orderColumns, field)
 
-	row := tx.QueryRow(ctx, query, value)
-	var order entity.Order
-	if err := row.Scan(
-		&order.ID, &order.JobID, &order.UserEmail, &order.UserName, &order.StripePaymentIntentID, &order.StripeCustomerID,
-		&order.Amount, &order.Currency, &order.PaymentStatus, &order.PaidAt, &order.OrderType, &order.Requirements,
-		&order.ProductionJobID, &order.ProductionStatus, &order.DeliveryMethod, &order.DeliveredAt,
-		&order.CustomerNotes, &order.SupportTicketID, &order.IPAddress, &order.UserAgent, &order.ExpiresAt,
-		&order.CreatedAt, &order.UpdatedAt,
-	); err != nil {
+	row := o.postgres.Reader(ctx).QueryRow(ctx, query, value)
+	order, err := scanOrder(row)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, orderRepository.ErrOrderNotFound
 		}
@@ -101,13 +128,31 @@ func (o *OrderPgx) FindByField(ctx context.Context, field string, value interfac
 		return nil, utils.WrapError("find order by field", err)
 	}
 
-	return &order, nil
+	return order, nil
+}
+
+func (o *OrderPgx) Lock(ctx context.Context, id string) (*entity.Order, error) {
+	lg := o.logger.With("method", "Lock")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM orders WHERE id = $1 FOR UPDATE"
+	order, err := scanOrder(o.postgres.Querier(ctx).QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, orderRepository.ErrOrderNotFound
+		}
+		lg.Error("failed to lock order", "id", id, "err", err)
+		return nil, utils.WrapError("lock order", err)
+	}
+	return order, nil
 }
 
-func (o *OrderPgx) List(ctx context.Context, paymentStatus *entity.PaymentStatus, productionStatus *entity.ProductionStatus, limit, offset int, tx pgx.Tx) ([]*entity.Order, error) {
+func (o *OrderPgx) List(ctx context.Context, paymentStatus *entity.PaymentStatus, productionStatus *entity.ProductionStatus, page pagination.Request) (*pagination.Page[*entity.Order], error) {
 	lg := o.logger.With("method", "List")
 
-	query := "SELECT * FROM orders WHERE 1=1"
+	query := "SELECT " + orderColumns + " FROM orders WHERE 1=1"
 	args := []interface{}{}
 	argIdx := 1
 
@@ -123,10 +168,20 @@ func (o *OrderPgx) List(ctx context.Context, paymentStatus *entity.PaymentStatus
 		argIdx++
 	}
 
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, limit, offset)
+	keyset, err := page.Keyset(argIdx)
+	if err != nil {
+		return nil, utils.WrapError("list orders", err)
+	}
+	if keyset.Condition != "" {
+		query += " AND " + keyset.Condition
+		args = append(args, keyset.Args...)
+		argIdx += len(keyset.Args)
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", keyset.OrderBy, argIdx)
+	args = append(args, keyset.Limit)
 
-	rows, err := tx.Query(ctx, query, args...)
+	rows, err := o.postgres.Reader(ctx).Query(ctx, query, args...)
 	if err != nil {
 		lg.Error("failed to list orders", "err", err)
 		return nil, utils.WrapError("list orders", err)
@@ -135,27 +190,58 @@ func (o *OrderPgx) List(ctx context.Context, paymentStatus *entity.PaymentStatus
 
 	var orders []*entity.Order
 	for rows.Next() {
-		var order entity.Order
-		if err := rows.Scan(
-			&order.ID, &order.JobID, &order.UserEmail, &order.UserName, &order.StripePaymentIntentID, &order.StripeCustomerID,
-			&order.Amount, &order.Currency, &order.PaymentStatus, &order.PaidAt, &order.OrderType, &order.Requirements,
-			&order.ProductionJobID, &order.ProductionStatus, &order.DeliveryMethod, &order.DeliveredAt,
-			&order.CustomerNotes, &order.SupportTicketID, &order.IPAddress, &order.UserAgent, &order.ExpiresAt,
-			&order.CreatedAt, &order.UpdatedAt,
-		); err != nil {
+		order, err := scanOrder(rows)
+		if err != nil {
 			lg.Error("failed to scan order", "err", err)
 			return nil, utils.WrapError("scan order row", err)
 		}
-		orders = append(orders, &order)
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		lg.Error("failed to iterate orders", "err", err)
+		return nil, utils.WrapError("list orders", err)
+	}
+
+	return pagination.Build(keyset, orders, func(order *entity.Order) (int64, uuid.UUID) {
+		return order.CreatedAt, order.ID
+	}), nil
+}
+
+func (o *OrderPgx) ListExpired(ctx context.Context, statuses []entity.PaymentStatus, before int64, limit int) ([]*entity.Order, error) {
+	lg := o.logger.With("method", "ListExpired")
+
+	codes := make([]int16, len(statuses))
+	for i, status := range statuses {
+		codes[i] = int16(status)
 	}
 
+	rows, err := o.postgres.Reader(ctx).Query(ctx, listExpiredQuery, codes, before, limit)
+	if err != nil {
+		lg.Error("failed to list expired orders", "err", err)
+		return nil, utils.WrapError("list expired orders", err)
+	}
+	defer rows.Close()
+
+	orders := []*entity.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			lg.Error("failed to scan order", "err", err)
+			return nil, utils.WrapError("scan order row", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		lg.Error("failed to iterate orders", "err", err)
+		return nil, utils.WrapError("list expired orders", err)
+	}
 	return orders, nil
 }
 
-func (o *OrderPgx) Delete(ctx context.Context, id string, tx pgx.Tx) error {
+func (o *OrderPgx) Delete(ctx context.Context, id string) error {
 	lg := o.logger.With("method", "Delete")
 
-	cmd, err := tx.Exec(ctx, deleteOrder, id)
+	cmd, err := o.postgres.Querier(ctx).Exec(ctx, deleteOrder, id)
 	if err != nil {
 		lg.Error("failed to delete order", "id", id, "err", err)
 		return utils.WrapError("delete order", err)
@@ -166,18 +252,13 @@ func (o *OrderPgx) Delete(ctx context.Context, id string, tx pgx.Tx) error {
 	return nil
 }
 
-func (o *OrderPgx) Update(ctx context.Context, order *entity.Order, tx pgx.Tx) error {
+func (o *OrderPgx) Update(ctx context.Context, order *entity.Order) error {
 	lg := o.logger.With("method", "Update")
 
 	query := updateQuery
 
-	cmd, err := tx.Exec(ctx, query,
-		order.ID, order.JobID, order.UserEmail, order.UserName, order.StripePaymentIntentID, order.StripeCustomerID,
-		order.Amount, order.Currency, order.PaymentStatus, order.PaidAt, order.OrderType, order.Requirements,
-		order.ProductionJobID, order.ProductionStatus, order.DeliveryMethod, order.DeliveredAt,
-		order.CustomerNotes, order.SupportTicketID, order.IPAddress, order.UserAgent, order.ExpiresAt,
-		order.UpdatedAt,
-	)
+	args := append(writeValues(order), order.UpdatedAt)
+	cmd, err := o.postgres.Querier(ctx).Exec(ctx, query, args...)
 	if err != nil {
 		lg.Error("failed to update order", "id", order.ID, "err", err)
 		return utils.WrapError("update order", err)
@@ -188,3 +269,232 @@ func (o *OrderPgx) Update(ctx context.Context, order *entity.Order, tx pgx.Tx) e
 
 	return nil
 }
+
+// CreateMany sends one insert per order in a single round trip. The first
+// failing insert aborts the transaction: its outcome carries the error, the
+// orders after it get batch.ErrAborted and the error is returned as well.
+func (o *OrderPgx) CreateMany(ctx context.Context, orders []*entity.Order) ([]batch.Outcome, error) {
+	lg := o.logger.With("method", "CreateMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	b := &pgx.Batch{}
+	for _, order := range orders {
+		b.Queue(createOrder, createValues(order)...)
+	}
+
+	results := o.postgres.Querier(ctx).SendBatch(ctx, b)
+	outcomes := make([]batch.Outcome, len(orders))
+	var firstErr error
+	for i, order := range orders {
+		outcomes[i].ID = order.ID.String()
+		if firstErr != nil {
+			outcomes[i].Err = batch.ErrAborted
+			continue
+		}
+		if err := results.QueryRow().Scan(&outcomes[i].ID); err != nil {
+			lg.Error("failed to insert order", "index", i, "err", err)
+			outcomes[i].Err = err
+			firstErr = err
+		}
+	}
+	if err := results.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if firstErr != nil {
+		return outcomes, utils.WrapError("create orders", firstErr)
+	}
+	return outcomes, nil
+}
+
+// UpdateStatusMany moves the production status of the given orders in a
+// single statement. Orders that do not exist or are no longer in from are
+// reported in their outcome and left untouched.
+func (o *OrderPgx) UpdateStatusMany(
+	ctx context.Context,
+	ids []string,
+	from, to entity.ProductionStatus,
+) ([]batch.Outcome, error) {
+	lg := o.logger.With("method", "UpdateStatusMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	outcomes, keys := batch.ParseIDs(ids, orderRepository.ErrOrderNotFound)
+	rows, err := o.postgres.Querier(ctx).Query(ctx, updateProductionStatusMany, keys, from, to, time.Now().Unix())
+	if err != nil {
+		lg.Error("failed to update production statuses", "err", err)
+		return nil, utils.WrapError("update order statuses", err)
+	}
+	defer rows.Close()
+
+	moved := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var (
+			id uuid.UUID
+			ok bool
+		)
+		if err := rows.Scan(&id, &ok); err != nil {
+			return nil, utils.WrapError("update order statuses", err)
+		}
+		moved[id] = ok
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("update order statuses", err)
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Err != nil {
+			continue
+		}
+		ok, found := moved[uuid.MustParse(outcomes[i].ID)]
+		switch {
+		case !found:
+			outcomes[i].Err = orderRepository.ErrOrderNotFound
+		case !ok:
+			outcomes[i].Err = orderRepository.ErrStatusConflict
+		}
+	}
+	return outcomes, nil
+}
+
+// DeleteMany deletes the given orders in a single statement, ids that match
+// no order are reported in their outcome.
+func (o *OrderPgx) DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error) {
+	lg := o.logger.With("method", "DeleteMany")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	outcomes, keys := batch.ParseIDs(ids, orderRepository.ErrOrderNotFound)
+	rows, err := o.postgres.Querier(ctx).Query(ctx, deleteManyOrders, keys)
+	if err != nil {
+		lg.Error("failed to delete orders", "err", err)
+		return nil, utils.WrapError("delete orders", err)
+	}
+	defer rows.Close()
+
+	deleted := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.WrapError("delete orders", err)
+		}
+		deleted[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("delete orders", err)
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Err == nil && !deleted[uuid.MustParse(outcomes[i].ID)] {
+			outcomes[i].Err = orderRepository.ErrOrderNotFound
+		}
+	}
+	return outcomes, nil
+}
+
+// UpdatePayment writes the payment status and the Stripe references that
+// come with it.
+func (o *OrderPgx) UpdatePayment(ctx context.Context, order *entity.Order) error {
+	return o.patch(ctx, "UpdatePayment", order,
+		[]string{"payment_status", "paid_at", "stripe_payment_intent_id", "stripe_customer_id"},
+		order.PaymentStatus, postgresql.NullIfZero(order.PaidAt),
+		postgresql.NullIfZero(order.StripePaymentIntentID), postgresql.NullIfZero(order.StripeCustomerID),
+	)
+}
+
+func (o *OrderPgx) UpdateProduction(ctx context.Context, order *entity.Order) error {
+	return o.patch(ctx, "UpdateProduction", order,
+		[]string{"production_status", "production_job_id"},
+		order.ProductionStatus, order.ProductionJobID,
+	)
+}
+
+func (o *OrderPgx) MarkDelivered(ctx context.Context, order *entity.Order) error {
+	return o.patch(ctx, "MarkDelivered", order,
+		[]string{"delivery_method", "delivered_at"},
+		order.DeliveryMethod, postgresql.NullIfZero(order.DeliveredAt),
+	)
+}
+
+func (o *OrderPgx) UpdateCustomerNotes(ctx context.Context, order *entity.Order) error {
+	return o.patch(ctx, "UpdateCustomerNotes", order,
+		[]string{"customer_notes"},
+		postgresql.NullIfZero(order.CustomerNotes),
+	)
+}
+
+// patch writes only columns of one order, plus updated_at which it sets on
+// order as well. Column names never come from user input.
+func (o *OrderPgx) patch(ctx context.Context, method string, order *entity.Order, columns []string, values ...interface{}) error {
+	lg := o.logger.With("method", method)
+
+	order.UpdatedAt = time.Now().Unix()
+	args := append([]interface{}{order.ID}, values...)
+	args = append(args, order.UpdatedAt)
+
+	set := make([]string, 0, len(columns)+1)
+	for i, col := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", col, i+2))
+	}
+	set = append(set, fmt.Sprintf("updated_at = $%d", len(args)))
+	query := fmt.Sprintf("UPDATE orders SET %s WHERE id = $1", strings.Join(set, ", "))
+
+	cmd, err := o.postgres.Querier(ctx).Exec(ctx, query, args...)
+	if err != nil {
+		lg.Error("failed to update order", "id", order.ID, "err", err)
+		return utils.WrapError(method, err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return orderRepository.ErrOrderNotFound
+	}
+	return nil
+}
+
+func (o *OrderPgx) UpdateExpiry(ctx context.Context, order *entity.Order) error {
+	return o.patch(ctx, "UpdateExpiry", order,
+		[]string{"expires_at"},
+		postgresql.NullIfZero(order.ExpiresAt),
+	)
+}
+
+// scanOrder maps one row selected with orderColumns. FindByField and List
+// both read through it so NULL handling lives in one place.
+func scanOrder(row pgx.Row) (*entity.Order, error) {
+	order := &entity.Order{}
+	err := row.Scan(
+		&order.ID, &order.JobID, &order.UserEmail, &order.UserName, &order.StripePaymentIntentID, &order.StripeCustomerID,
+		&order.Amount.Minor, &order.Amount.Currency, &order.PaymentStatus, &order.PaidAt, &order.OrderType, &order.Requirements,
+		&order.ProductionJobID, &order.ProductionStatus, &order.DeliveryMethod, &order.DeliveredAt,
+		&order.CustomerNotes, &order.SupportTicketID, &order.IPAddress, &order.UserAgent, &order.ExpiresAt,
+		&order.PromotionID, &order.Discount.Minor, &order.Discount.Currency,
+		&order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// writeValues returns id and the columns up to discount_minor that Create,
+// Update and CreateMany write. The discount shares the order currency.
+// Optional columns that are not set are written as NULL.
+func writeValues(order *entity.Order) []interface{} {
+	return []interface{}{
+		order.ID, order.JobID, order.UserEmail, order.UserName,
+		postgresql.NullIfZero(order.StripePaymentIntentID), postgresql.NullIfZero(order.StripeCustomerID),
+		order.Amount.Minor, order.Amount.Currency, order.PaymentStatus, postgresql.NullIfZero(order.PaidAt),
+		order.OrderType, postgresql.NullIfZero(order.Requirements),
+		order.ProductionJobID, order.ProductionStatus, order.DeliveryMethod, postgresql.NullIfZero(order.DeliveredAt),
+		postgresql.NullIfZero(order.CustomerNotes), postgresql.NullIfZero(order.SupportTicketID),
+		postgresql.NullIfZero(order.IPAddress), postgresql.NullIfZero(order.UserAgent), postgresql.NullIfZero(order.ExpiresAt),
+		order.PromotionID, order.Discount.Minor,
+	}
+}
+
+// createValues adds the timestamps Create and CreateMany insert.
+func createValues(order *entity.Order) []interface{} {
+	return append(writeValues(order), order.CreatedAt, order.UpdatedAt)
+}
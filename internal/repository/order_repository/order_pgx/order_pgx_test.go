@@ -0,0 +1,167 @@
+package order_pgx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobPGX "github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ orderRepository.Repository = (*OrderPgx)(nil)
+
+// newFixture wires the order repository to a fresh schema. Orders reference
+// jobs, so NewJobID inserts one through JobPgx.
+func newFixture(t *testing.T) order_contract.Fixture {
+	f, _ := newFixtureWithDB(t)
+	return f
+}
+
+func newFixtureWithDB(t *testing.T) (order_contract.Fixture, *postgresql.Postgres) {
+	pg := pgtest.New(t)
+	jobs := jobPGX.NewJobPgx(pgtest.Logger(), pg)
+	return order_contract.Fixture{
+		Repo:     NewOrderPgx(pgtest.Logger(), pg),
+		UOW:      uow.NewUOW(pg),
+		NewJobID: func(t *testing.T) uuid.UUID { return createJob(t, jobs) },
+	}, pg
+}
+
+func createJob(t *testing.T, jobs jobRepository.Repository) uuid.UUID {
+	t.Helper()
+	id, err := jobs.Create(context.Background(), jobContract.NewJob(1_700_000_000))
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	return uuid.MustParse(id)
+}
+
+func TestContract(t *testing.T) {
+	order_contract.Run(t, newFixture)
+}
+
+func TestJobForeignKey(t *testing.T) {
+	f := newFixture(t)
+
+	order := order_contract.NewOrder(uuid.New(), 1_700_000_000)
+	if _, err := f.Repo.Create(context.Background(), order); err == nil {
+		t.Errorf("Create for a missing job succeeded")
+	}
+}
+
+func TestProductionJobIDRoundTrip(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	order := order_contract.NewOrder(f.NewJobID(t), 1_700_000_000)
+	if _, err := f.Repo.Create(ctx, order); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	productionJobID := f.NewJobID(t)
+	order.ProductionJobID = &productionJobID
+	if err := f.Repo.UpdateProduction(ctx, order); err != nil {
+		t.Fatalf("UpdateProduction: %v", err)
+	}
+	got, err := f.Repo.FindByField(ctx, "production_job_id", productionJobID.String())
+	if err != nil {
+		t.Fatalf("FindByField(production_job_id): %v", err)
+	}
+	if got.ID != order.ID {
+		t.Errorf("FindByField(production_job_id) found %s, want %s", got.ID, order.ID)
+	}
+
+	order.ProductionJobID = nil
+	if err := f.Repo.UpdateProduction(ctx, order); err != nil {
+		t.Fatalf("UpdateProduction: %v", err)
+	}
+	page, err := f.Repo.List(ctx, nil, nil, pagination.Request{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("List returned %d orders, want 1", len(page.Items))
+	}
+	if page.Items[0].ProductionJobID != nil {
+		t.Errorf("cleared production_job_id reads back as %v, want NULL", page.Items[0].ProductionJobID)
+	}
+}
+
+// TestSparseRowsScan reads an order holding only the NOT NULL columns.
+func TestSparseRowsScan(t *testing.T) {
+	f, pg := newFixtureWithDB(t)
+	ctx := context.Background()
+
+	id := uuid.New()
+	if _, err := pg.PrimaryConn.Exec(ctx, `
+		INSERT INTO orders (id, job_id, user_email, user_name, amount_minor, created_at, updated_at)
+		VALUES ($1, $2, 'sparse@example.com', 'Sparse', 1000, 1700000000, 1700000000)`,
+		id, f.NewJobID(t)); err != nil {
+		t.Fatalf("insert sparse order: %v", err)
+	}
+
+	order, err := f.Repo.FindByField(ctx, "id", id.String())
+	if err != nil {
+		t.Fatalf("FindByField: %v", err)
+	}
+	if order.StripeCustomerID != "" || order.PaidAt != 0 || order.CustomerNotes != "" || order.ProductionJobID != nil {
+		t.Errorf("NULL columns read back as %+v, want zero values", order)
+	}
+
+	page, err := f.Repo.List(ctx, nil, nil, pagination.Request{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != id {
+		t.Errorf("List returned %d orders, want the sparse one", len(page.Items))
+	}
+}
+
+// TestZeroValuesWriteNull checks that optional columns left unset are
+// stored as NULL and that clearing one writes NULL again.
+func TestZeroValuesWriteNull(t *testing.T) {
+	f, pg := newFixtureWithDB(t)
+	ctx := context.Background()
+
+	isNull := func(id uuid.UUID, column string) bool {
+		t.Helper()
+		var null bool
+		if err := pg.PrimaryConn.QueryRow(ctx, "SELECT "+column+" IS NULL FROM orders WHERE id = $1", id).Scan(&null); err != nil {
+			t.Fatalf("read %s: %v", column, err)
+		}
+		return null
+	}
+
+	order := order_contract.NewOrder(f.NewJobID(t), 1_700_000_000)
+	if _, err := f.Repo.Create(ctx, order); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, column := range []string{"stripe_payment_intent_id", "stripe_customer_id", "paid_at", "customer_notes", "delivered_at", "expires_at"} {
+		if !isNull(order.ID, column) {
+			t.Errorf("unset %s is not NULL", column)
+		}
+	}
+
+	order.CustomerNotes = "gift"
+	if err := f.Repo.UpdateCustomerNotes(ctx, order); err != nil {
+		t.Fatalf("UpdateCustomerNotes: %v", err)
+	}
+	if isNull(order.ID, "customer_notes") {
+		t.Errorf("set customer_notes stored as NULL")
+	}
+	order.CustomerNotes = ""
+	if err := f.Repo.UpdateCustomerNotes(ctx, order); err != nil {
+		t.Fatalf("UpdateCustomerNotes: %v", err)
+	}
+	if !isNull(order.ID, "customer_notes") {
+		t.Errorf("cleared customer_notes is not NULL")
+	}
+}
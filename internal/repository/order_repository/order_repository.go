@@ -4,18 +4,44 @@ import (
 	"context"
 	"errors"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/batch"
+	"github.com/playture/backend/internal/repository/pagination"
 )
 
 var (
-	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrStatusConflict = errors.New("order is not in the expected status")
 )
 
 type Repository interface {
-	Create(ctx context.Context, order *entity.Order, tx pgx.Tx) (string, error)
-	FindByField(ctx context.Context, field string, value interface{}, tx pgx.Tx) (*entity.Order, error)
-	List(ctx context.Context, paymentStatus *entity.PaymentStatus, productionStatus *entity.ProductionStatus, limit, offset int, tx pgx.Tx) ([]*entity.Order, error)
-	Delete(ctx context.Context, id string, tx pgx.Tx) error
-	Update(ctx context.Context, order *entity.Order, tx pgx.Tx) error
+	Create(ctx context.Context, order *entity.Order) (string, error)
+	FindByField(ctx context.Context, field string, value interface{}) (*entity.Order, error)
+	List(ctx context.Context, paymentStatus *entity.PaymentStatus, productionStatus *entity.ProductionStatus, page pagination.Request) (*pagination.Page[*entity.Order], error)
+	// ListExpired returns up to limit orders in one of statuses whose
+	// expires_at is set and before the given unix second, soonest expired
+	// first.
+	ListExpired(ctx context.Context, statuses []entity.PaymentStatus, before int64, limit int) ([]*entity.Order, error)
+	Delete(ctx context.Context, id string) error
+	// Lock reads an order and holds its row until the surrounding
+	// transaction ends. It must run inside a uow transaction.
+	Lock(ctx context.Context, id string) (*entity.Order, error)
+	// Update overwrites every column, prefer the targeted writes below so
+	// concurrent writers do not clobber each other's fields.
+	Update(ctx context.Context, order *entity.Order) error
+
+	// The targeted writes touch only their own columns and updated_at,
+	// taking the values from order.
+	UpdatePayment(ctx context.Context, order *entity.Order) error
+	UpdateProduction(ctx context.Context, order *entity.Order) error
+	MarkDelivered(ctx context.Context, order *entity.Order) error
+	UpdateCustomerNotes(ctx context.Context, order *entity.Order) error
+	UpdateExpiry(ctx context.Context, order *entity.Order) error
+
+	// The batch writes below must run inside a uow transaction and report
+	// one outcome per input row, in input order. UpdateStatusMany moves the
+	// production status.
+	CreateMany(ctx context.Context, orders []*entity.Order) ([]batch.Outcome, error)
+	UpdateStatusMany(ctx context.Context, ids []string, from, to entity.ProductionStatus) ([]batch.Outcome, error)
+	DeleteMany(ctx context.Context, ids []string) ([]batch.Outcome, error)
 }
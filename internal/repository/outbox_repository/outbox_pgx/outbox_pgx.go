@@ -0,0 +1,120 @@
+package outboxPGX
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO outbox (
+			topic, event_type, aggregate_type, aggregate_id, payload, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id`
+
+	claimPendingQuery = `
+		SELECT
+			id, topic, event_type, aggregate_type, aggregate_id, payload,
+			attempts, COALESCE(last_error, ''), created_at
+		FROM outbox
+		WHERE delivered_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	markDeliveredQuery = `UPDATE outbox SET delivered_at = $2, attempts = attempts + 1 WHERE id = ANY($1)`
+
+	markFailedQuery = `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
+
+	pruneQuery = `DELETE FROM outbox WHERE delivered_at IS NOT NULL AND delivered_at < $1`
+)
+
+type OutboxPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewOutboxPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *OutboxPgx {
+	return &OutboxPgx{
+		logger:   logger.With("layer", "OutboxRepository"),
+		postgres: postgres,
+	}
+}
+
+func (o *OutboxPgx) Create(ctx context.Context, msg *entity.OutboxMessage) (int64, error) {
+	lg := o.logger.With("method", "Create")
+
+	args := []interface{}{
+		msg.Topic, msg.EventType, msg.AggregateType, msg.AggregateID, msg.Payload, msg.CreatedAt,
+	}
+
+	var (
+		id  int64
+		err error
+	)
+	err = o.postgres.Querier(ctx).QueryRow(ctx, createQuery, args...).Scan(&id)
+	if err != nil {
+		lg.Error("Create failed", "eventType", msg.EventType, "err", err)
+		return 0, utils.WrapError("create outbox message", err)
+	}
+	return id, nil
+}
+
+func (o *OutboxPgx) ClaimPending(ctx context.Context, limit int) ([]*entity.OutboxMessage, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	rows, err = o.postgres.Querier(ctx).Query(ctx, claimPendingQuery, limit)
+	if err != nil {
+		return nil, utils.WrapError("claim outbox messages", err)
+	}
+	defer rows.Close()
+
+	msgs := []*entity.OutboxMessage{}
+	for rows.Next() {
+		msg := &entity.OutboxMessage{}
+		if err := rows.Scan(
+			&msg.ID, &msg.Topic, &msg.EventType, &msg.AggregateType, &msg.AggregateID, &msg.Payload,
+			&msg.Attempts, &msg.LastError, &msg.CreatedAt,
+		); err != nil {
+			return nil, utils.WrapError("scan outbox message", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("claim outbox messages", err)
+	}
+	return msgs, nil
+}
+
+func (o *OutboxPgx) MarkDelivered(ctx context.Context, ids []int64, at int64) error {
+	if _, err := o.postgres.Querier(ctx).Exec(ctx, markDeliveredQuery, ids, at); err != nil {
+		return utils.WrapError("mark outbox messages delivered", err)
+	}
+	return nil
+}
+
+func (o *OutboxPgx) MarkFailed(ctx context.Context, id int64, reason string) error {
+	if _, err := o.postgres.Querier(ctx).Exec(ctx, markFailedQuery, id, reason); err != nil {
+		return utils.WrapError("mark outbox message failed", err)
+	}
+	return nil
+}
+
+func (o *OutboxPgx) Prune(ctx context.Context, before int64) (int64, error) {
+	cmd, err := o.postgres.Querier(ctx).Exec(ctx, pruneQuery, before)
+	if err != nil {
+		return 0, utils.WrapError("prune outbox", err)
+	}
+	return cmd.RowsAffected(), nil
+}
@@ -0,0 +1,19 @@
+package outboxRepository
+
+import (
+	"context"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+type Repository interface {
+	Create(ctx context.Context, msg *entity.OutboxMessage) (int64, error)
+	// ClaimPending locks up to limit undelivered messages, oldest first. Rows
+	// locked by another relay are skipped, so the transaction must stay open until the
+	// messages are marked.
+	ClaimPending(ctx context.Context, limit int) ([]*entity.OutboxMessage, error)
+	MarkDelivered(ctx context.Context, ids []int64, at int64) error
+	MarkFailed(ctx context.Context, id int64, reason string) error
+	// Prune deletes messages delivered before the given time.
+	Prune(ctx context.Context, before int64) (int64, error)
+}
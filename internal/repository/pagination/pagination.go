@@ -0,0 +1,220 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Direction string
+
+const (
+	DirectionNext Direction = "n"
+	DirectionPrev Direction = "p"
+)
+
+// Cursor points at a row by its (created_at, id) sort key. Listings are
+// always sorted newest first, the id breaks ties between rows created in the
+// same second so the order is stable.
+type Cursor struct {
+	CreatedAt int64     `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Direction Direction `json:"d"`
+}
+
+// Encode returns the opaque form handed out to API clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func Decode(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Direction != DirectionNext && c.Direction != DirectionPrev {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Request is what a caller asks for: an optional cursor taken from a
+// previous Page and a page size.
+type Request struct {
+	Cursor string
+	Limit  int
+}
+
+func (r Request) limit() int {
+	switch {
+	case r.Limit <= 0:
+		return DefaultLimit
+	case r.Limit > MaxLimit:
+		return MaxLimit
+	default:
+		return r.Limit
+	}
+}
+
+// Keyset is the SQL fragment set for a Request, ready to be spliced into a
+// listing query.
+type Keyset struct {
+	// Condition is empty on the first page, otherwise a row comparison
+	// against the cursor using the placeholders returned in Args.
+	Condition string
+	Args      []interface{}
+	OrderBy   string
+	// Limit asks for one extra row to find out whether another page exists.
+	Limit int
+
+	cursor *Cursor
+	size   int
+}
+
+// Keyset decodes the request cursor. argIdx is the number of the first free
+// positional placeholder in the surrounding query.
+func (r Request) Keyset(argIdx int) (*Keyset, error) {
+	k := &Keyset{
+		OrderBy: "created_at DESC, id DESC",
+		size:    r.limit(),
+	}
+	k.Limit = k.size + 1
+
+	if r.Cursor == "" {
+		return k, nil
+	}
+
+	c, err := Decode(r.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	k.cursor = c
+	k.Args = []interface{}{c.CreatedAt, c.ID}
+
+	if c.Direction == DirectionPrev {
+		// walk backwards and flip the rows afterwards
+		k.Condition = fmt.Sprintf("(created_at, id) > ($%d, $%d)", argIdx, argIdx+1)
+		k.OrderBy = "created_at ASC, id ASC"
+	} else {
+		k.Condition = fmt.Sprintf("(created_at, id) < ($%d, $%d)", argIdx, argIdx+1)
+	}
+
+	return k, nil
+}
+
+// Page is one slice of a listing with the cursors to move around it. An empty
+// page is a valid result and not an error.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+}
+
+func (p *Page[T]) HasNext() bool {
+	return p.NextCursor != ""
+}
+
+func (p *Page[T]) HasPrev() bool {
+	return p.PrevCursor != ""
+}
+
+// Build turns the rows fetched with the Keyset into a Page. key extracts the
+// sort key of a row.
+func Build[T any](k *Keyset, rows []T, key func(T) (int64, uuid.UUID)) *Page[T] {
+	more := len(rows) > k.size
+	if more {
+		rows = rows[:k.size]
+	}
+
+	backwards := k.cursor != nil && k.cursor.Direction == DirectionPrev
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := &Page[T]{Items: rows}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) == 0 {
+		return page
+	}
+
+	if more || backwards {
+		t, id := key(rows[len(rows)-1])
+		page.NextCursor = Cursor{CreatedAt: t, ID: id, Direction: DirectionNext}.Encode()
+	}
+	if (backwards && more) || (k.cursor != nil && !backwards) {
+		t, id := key(rows[0])
+		page.PrevCursor = Cursor{CreatedAt: t, ID: id, Direction: DirectionPrev}.Encode()
+	}
+
+	return page
+}
+
+// Fetch applies the Keyset to rows held in memory: it drops rows on the far
+// side of the cursor, sorts like OrderBy and cuts at Limit. The result is
+// what the SQL query would have returned, ready for Build.
+func Fetch[T any](k *Keyset, rows []T, key func(T) (int64, uuid.UUID)) []T {
+	backwards := k.cursor != nil && k.cursor.Direction == DirectionPrev
+
+	out := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if k.cursor != nil {
+			t, id := key(row)
+			c := compareKeys(t, id, k.cursor.CreatedAt, k.cursor.ID)
+			if backwards && c <= 0 || !backwards && c >= 0 {
+				continue
+			}
+		}
+		out = append(out, row)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		it, iid := key(out[i])
+		jt, jid := key(out[j])
+		c := compareKeys(it, iid, jt, jid)
+		if backwards {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	if len(out) > k.Limit {
+		out = out[:k.Limit]
+	}
+	return out
+}
+
+// compareKeys orders (created_at, id) pairs the way Postgres compares the
+// row values, uuids byte by byte.
+func compareKeys(at int64, aid uuid.UUID, bt int64, bid uuid.UUID) int {
+	switch {
+	case at < bt:
+		return -1
+	case at > bt:
+		return 1
+	default:
+		return bytes.Compare(aid[:], bid[:])
+	}
+}
@@ -0,0 +1,30 @@
+package paymentRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+// ErrDeclined is returned when the payment provider answered but refused
+// the request, as opposed to failing to reach it.
+var ErrDeclined = errors.New("payment provider declined the request")
+
+// ErrInvalidSignature is returned for webhooks that were not signed by the
+// payment provider or were signed too long ago.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Repository is the payment provider as the services need it.
+type Repository interface {
+	// Refund returns amount of a captured payment intent and returns the
+	// provider's refund id. Retrying with the same idempotencyKey never
+	// refunds twice.
+	Refund(ctx context.Context, paymentIntentID string, amount entity.Money, idempotencyKey string, metadata map[string]string) (string, error)
+	// CancelPaymentIntent cancels an unpaid payment intent so it can no
+	// longer be paid. An intent that is already canceled is not an error,
+	// one that succeeded or is processing returns ErrDeclined.
+	CancelPaymentIntent(ctx context.Context, paymentIntentID string) error
+	// ParseWebhook verifies signature over payload and decodes the event.
+	ParseWebhook(payload []byte, signature string) (*entity.PaymentEvent, error)
+}
@@ -0,0 +1,151 @@
+package paymentStripe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	apiURL         = "https://api.stripe.com/v1"
+	requestTimeout = 30 * time.Second
+	// webhookTolerance is how old a signed webhook may be, replays of older
+	// ones are refused
+	webhookTolerance = 5 * time.Minute
+)
+
+// PaymentStripe talks to the Stripe REST API directly, the few calls we
+// make do not warrant the SDK.
+type PaymentStripe struct {
+	logger        *slog.Logger
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+	now           func() time.Time
+}
+
+func NewPaymentStripe(
+	logger *slog.Logger,
+	env *godotenv.Env,
+) *PaymentStripe {
+	return &PaymentStripe{
+		logger:        logger.With("layer", "PaymentRepository"),
+		secretKey:     env.StripeSecretKey,
+		webhookSecret: env.StripeWebhookSecret,
+		baseURL:       apiURL,
+		client:        &http.Client{Timeout: requestTimeout},
+		now:           time.Now,
+	}
+}
+
+type stripeObject struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  *struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		// PaymentIntent is the intent in its current state when a request
+		// did not fit that state
+		PaymentIntent *struct {
+			Status string `json:"status"`
+		} `json:"payment_intent"`
+	} `json:"error"`
+}
+
+func (p *PaymentStripe) Refund(
+	ctx context.Context,
+	paymentIntentID string,
+	amount entity.Money,
+	idempotencyKey string,
+	metadata map[string]string,
+) (string, error) {
+	lg := p.logger.With("method", "Refund", "paymentIntentID", paymentIntentID)
+
+	form := url.Values{}
+	form.Set("payment_intent", paymentIntentID)
+	form.Set("amount", strconv.FormatInt(amount.Minor, 10))
+	for k, v := range metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+
+	refund, err := p.post(ctx, "/refunds", form, idempotencyKey)
+	if err != nil {
+		lg.Error("Refund failed", "err", err)
+		return "", err
+	}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return "", utils.WrapError("refund "+refund.ID+" is "+refund.Status, paymentRepository.ErrDeclined)
+	}
+	return refund.ID, nil
+}
+
+// CancelPaymentIntent cancels paymentIntentID as abandoned. Stripe refuses
+// to cancel an intent twice, that answer counts as success.
+func (p *PaymentStripe) CancelPaymentIntent(ctx context.Context, paymentIntentID string) error {
+	lg := p.logger.With("method", "CancelPaymentIntent", "paymentIntentID", paymentIntentID)
+
+	form := url.Values{}
+	form.Set("cancellation_reason", "abandoned")
+
+	obj, err := p.post(ctx, "/payment_intents/"+url.PathEscape(paymentIntentID)+"/cancel", form, "")
+	if err != nil {
+		if obj != nil && obj.Error != nil && obj.Error.PaymentIntent != nil && obj.Error.PaymentIntent.Status == "canceled" {
+			return nil
+		}
+		lg.Error("CancelPaymentIntent failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// post sends a form encoded request and decodes the Stripe object it
+// answers with. 4xx answers other than rate limiting are returned as
+// ErrDeclined, together with the decoded error.
+func (p *PaymentStripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string) (*stripeObject, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, utils.WrapError("build stripe request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, utils.WrapError("call stripe", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, utils.WrapError("read stripe response", err)
+	}
+	obj := &stripeObject{}
+	if err := json.Unmarshal(body, obj); err != nil {
+		return nil, utils.WrapError(fmt.Sprintf("decode stripe response (status %d)", res.StatusCode), err)
+	}
+
+	switch {
+	case res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests && obj.Error != nil:
+		return obj, utils.WrapError(obj.Error.Message, paymentRepository.ErrDeclined)
+	case res.StatusCode >= 300:
+		return nil, fmt.Errorf("stripe answered with status %d", res.StatusCode)
+	}
+	return obj, nil
+}
@@ -0,0 +1,127 @@
+package paymentStripe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+)
+
+var _ paymentRepository.Repository = (*PaymentStripe)(nil)
+
+func newTestStripe(t *testing.T, handler http.HandlerFunc) *PaymentStripe {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	p := NewPaymentStripe(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{StripeSecretKey: "sk_test"})
+	p.baseURL = srv.URL
+	return p
+}
+
+func TestRefundSendsAmountAndIdempotencyKey(t *testing.T) {
+	p := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm: %v", err)
+		}
+		switch {
+		case r.URL.Path != "/refunds":
+			t.Errorf("path = %s, want /refunds", r.URL.Path)
+		case r.Header.Get("Authorization") != "Bearer sk_test":
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		case r.Header.Get("Idempotency-Key") != "refund-1":
+			t.Errorf("Idempotency-Key = %q, want refund-1", r.Header.Get("Idempotency-Key"))
+		case r.PostForm.Get("payment_intent") != "pi_1" || r.PostForm.Get("amount") != "1500":
+			t.Errorf("form = %v, want pi_1 and 1500", r.PostForm)
+		case r.PostForm.Get("metadata[order_id]") != "order-1":
+			t.Errorf("metadata = %v, want order_id", r.PostForm)
+		}
+		w.Write([]byte(`{"id": "re_1", "status": "succeeded"}`))
+	})
+
+	id, err := p.Refund(context.Background(), "pi_1", entity.Money{Minor: 1500, Currency: "USD"}, "refund-1",
+		map[string]string{"order_id": "order-1"})
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if id != "re_1" {
+		t.Errorf("Refund id = %q, want re_1", id)
+	}
+}
+
+func TestRefundErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		declined bool
+	}{
+		{"card error", http.StatusBadRequest, `{"error": {"type": "invalid_request_error", "message": "charge already refunded"}}`, true},
+		{"refund failed", http.StatusOK, `{"id": "re_1", "status": "failed"}`, true},
+		{"rate limited", http.StatusTooManyRequests, `{"error": {"type": "rate_limit_error", "message": "slow down"}}`, false},
+		{"server error", http.StatusInternalServerError, `{"error": {"type": "api_error", "message": "oops"}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := p.Refund(context.Background(), "pi_1", entity.Money{Minor: 1500, Currency: "USD"}, "refund-1", nil)
+			if err == nil {
+				t.Fatal("Refund succeeded")
+			}
+			if got := errors.Is(err, paymentRepository.ErrDeclined); got != tt.declined {
+				t.Errorf("errors.Is(err, ErrDeclined) = %v, want %v (err %v)", got, tt.declined, err)
+			}
+		})
+	}
+}
+
+func TestCancelPaymentIntent(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantErr  bool
+		declined bool
+	}{
+		{"canceled", http.StatusOK, `{"id": "pi_1", "status": "canceled"}`, false, false},
+		{"already canceled", http.StatusBadRequest,
+			`{"error": {"type": "invalid_request_error", "code": "payment_intent_unexpected_state", "message": "already canceled", "payment_intent": {"id": "pi_1", "status": "canceled"}}}`,
+			false, false},
+		{"already paid", http.StatusBadRequest,
+			`{"error": {"type": "invalid_request_error", "code": "payment_intent_unexpected_state", "message": "already succeeded", "payment_intent": {"id": "pi_1", "status": "succeeded"}}}`,
+			true, true},
+		{"server error", http.StatusInternalServerError, `{"error": {"type": "api_error", "message": "oops"}}`, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("ParseForm: %v", err)
+				}
+				if r.URL.Path != "/payment_intents/pi_1/cancel" || r.PostForm.Get("cancellation_reason") != "abandoned" {
+					t.Errorf("request = %s %v, want the abandoned cancel of pi_1", r.URL.Path, r.PostForm)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			err := p.CancelPaymentIntent(context.Background(), "pi_1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CancelPaymentIntent error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, paymentRepository.ErrDeclined); got != tt.declined {
+				t.Errorf("errors.Is(err, ErrDeclined) = %v, want %v (err %v)", got, tt.declined, err)
+			}
+		})
+	}
+}
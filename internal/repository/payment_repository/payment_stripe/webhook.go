@@ -0,0 +1,96 @@
+package paymentStripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/utils"
+)
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object struct {
+			ID             string            `json:"id"`
+			Customer       string            `json:"customer"`
+			AmountReceived int64             `json:"amount_received"`
+			Currency       string            `json:"currency"`
+			Metadata       map[string]string `json:"metadata"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook checks the Stripe-Signature header the way the Stripe
+// libraries do: an HMAC-SHA256 of "<t>.<payload>" under the endpoint secret
+// in one of the v1 entries, with t no older than webhookTolerance.
+func (p *PaymentStripe) ParseWebhook(payload []byte, signature string) (*entity.PaymentEvent, error) {
+	if err := p.verify(payload, signature); err != nil {
+		p.logger.Warn("webhook rejected", "method", "ParseWebhook", "err", err)
+		return nil, err
+	}
+
+	event := &stripeEvent{}
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, utils.WrapError("decode stripe event", err)
+	}
+	object := event.Data.Object
+	// Stripe sends amounts in minor units and currencies in lower case
+	received := entity.Money{Minor: object.AmountReceived, Currency: strings.ToUpper(object.Currency)}
+	return &entity.PaymentEvent{
+		ID:              event.ID,
+		Type:            event.Type,
+		PaymentIntentID: object.ID,
+		CustomerID:      object.Customer,
+		OrderID:         object.Metadata["order_id"],
+		Amount:          received,
+		CreatedAt:       event.Created,
+	}, nil
+}
+
+func (p *PaymentStripe) verify(payload []byte, signature string) error {
+	if p.webhookSecret == "" {
+		return utils.WrapError("webhook secret is not configured", paymentRepository.ErrInvalidSignature)
+	}
+
+	var (
+		timestamp  int64
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return utils.WrapError("malformed signature header", paymentRepository.ErrInvalidSignature)
+	}
+	if p.now().Sub(time.Unix(timestamp, 0)) > webhookTolerance {
+		return utils.WrapError("signature timestamp too old", paymentRepository.ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return utils.WrapError("no matching signature", paymentRepository.ErrInvalidSignature)
+}
@@ -0,0 +1,83 @@
+package paymentStripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+)
+
+const testEvent = `{
+	"id": "evt_1",
+	"type": "payment_intent.succeeded",
+	"created": 1700000000,
+	"data": {"object": {"id": "pi_1", "customer": "cus_1", "amount_received": 4999, "currency": "usd", "metadata": {"order_id": "order-1"}}}
+}`
+
+func sign(secret string, timestamp int64, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", timestamp, payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookStripe(now time.Time) *PaymentStripe {
+	p := NewPaymentStripe(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{StripeWebhookSecret: "whsec_test"})
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func TestParseWebhook(t *testing.T) {
+	now := time.Unix(1_700_000_100, 0)
+	p := newWebhookStripe(now)
+
+	header := fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), sign("old_secret", now.Unix(), testEvent), sign("whsec_test", now.Unix(), testEvent))
+	event, err := p.ParseWebhook([]byte(testEvent), header)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	want := entity.PaymentEvent{
+		ID:              "evt_1",
+		Type:            entity.PaymentEventIntentSucceeded,
+		PaymentIntentID: "pi_1",
+		CustomerID:      "cus_1",
+		OrderID:         "order-1",
+		Amount:          entity.Money{Minor: 4999, Currency: "USD"},
+		CreatedAt:       1_700_000_000,
+	}
+	if *event != want {
+		t.Errorf("ParseWebhook = %+v, want %+v", *event, want)
+	}
+}
+
+func TestParseWebhookRejects(t *testing.T) {
+	now := time.Unix(1_700_000_100, 0)
+	stale := now.Add(-10 * time.Minute).Unix()
+
+	tests := []struct {
+		name    string
+		payload string
+		header  string
+	}{
+		{"no header", testEvent, ""},
+		{"wrong secret", testEvent, fmt.Sprintf("t=%d,v1=%s", now.Unix(), sign("whsec_other", now.Unix(), testEvent))},
+		{"tampered payload", testEvent + " ", fmt.Sprintf("t=%d,v1=%s", now.Unix(), sign("whsec_test", now.Unix(), testEvent))},
+		{"replayed", testEvent, fmt.Sprintf("t=%d,v1=%s", stale, sign("whsec_test", stale, testEvent))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newWebhookStripe(now).ParseWebhook([]byte(tt.payload), tt.header)
+			if !errors.Is(err, paymentRepository.ErrInvalidSignature) {
+				t.Errorf("ParseWebhook error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package productPGX
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/repository/batch"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO products (
+			order_type, name, video_width, video_height, max_duration_seconds,
+			watermark_free, active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5,
+			$6, $7, $8, $9
+		) RETURNING id`
+
+	updateQuery = `
+		UPDATE products SET
+			order_type=$2, name=$3, video_width=$4, video_height=$5, max_duration_seconds=$6,
+			watermark_free=$7, active=$8, updated_at=$9
+		WHERE id=$1`
+
+	selectColumns = `
+		id, order_type, name, video_width, video_height, max_duration_seconds,
+		watermark_free, active, created_at, updated_at`
+
+	findByIDQuery = `SELECT ` + selectColumns + ` FROM products WHERE id = $1`
+
+	findActiveQuery = `SELECT ` + selectColumns + ` FROM products WHERE order_type = $1 AND active`
+
+	listQuery = `SELECT ` + selectColumns + ` FROM products ORDER BY order_type, created_at`
+
+	listActiveQuery = `SELECT ` + selectColumns + ` FROM products WHERE active ORDER BY order_type, created_at`
+
+	deleteQuery = `DELETE FROM products WHERE id = $1`
+
+	insertPriceQuery = `
+		INSERT INTO product_prices (product_id, currency, amount_minor)
+		VALUES ($1, $2, $3)`
+
+	deletePricesQuery = `DELETE FROM product_prices WHERE product_id = $1`
+
+	pricesQuery = `
+		SELECT product_id, currency, amount_minor
+		FROM product_prices WHERE product_id = ANY($1)
+		ORDER BY currency`
+
+	// activeOrderTypeIndex keeps one active product per order type.
+	activeOrderTypeIndex = "products_active_order_type_idx"
+)
+
+type ProductPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewProductPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *ProductPgx {
+	return &ProductPgx{
+		logger:   logger.With("layer", "ProductRepository"),
+		postgres: postgres,
+	}
+}
+
+func (p *ProductPgx) Create(ctx context.Context, product *entity.Product) (string, error) {
+	lg := p.logger.With("method", "Create")
+	if err := batch.RequireTx(ctx); err != nil {
+		return "", err
+	}
+
+	var id uuid.UUID
+	err := p.postgres.Querier(ctx).QueryRow(ctx, createQuery,
+		product.OrderType, product.Name, product.VideoWidth, product.VideoHeight, product.MaxDurationSeconds,
+		product.WatermarkFree, product.Active, product.CreatedAt, product.UpdatedAt,
+	).Scan(&id)
+	if err != nil {
+		if mapped := mapWriteError(err); mapped != err {
+			return "", mapped
+		}
+		lg.Error("Create failed", "err", err)
+		return "", utils.WrapError("create product", err)
+	}
+	product.ID = id
+
+	if err := p.insertPrices(ctx, product); err != nil {
+		lg.Error("Create failed", "id", id, "err", err)
+		return "", utils.WrapError("create product prices", err)
+	}
+	return id.String(), nil
+}
+
+func (p *ProductPgx) Update(ctx context.Context, product *entity.Product) error {
+	lg := p.logger.With("method", "Update")
+	if err := batch.RequireTx(ctx); err != nil {
+		return err
+	}
+
+	cmd, err := p.postgres.Querier(ctx).Exec(ctx, updateQuery,
+		product.ID, product.OrderType, product.Name, product.VideoWidth, product.VideoHeight, product.MaxDurationSeconds,
+		product.WatermarkFree, product.Active, product.UpdatedAt,
+	)
+	if err != nil {
+		if mapped := mapWriteError(err); mapped != err {
+			return mapped
+		}
+		lg.Error("Update failed", "id", product.ID, "err", err)
+		return utils.WrapError("update product", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return productRepository.ErrProductNotFound
+	}
+
+	if _, err := p.postgres.Querier(ctx).Exec(ctx, deletePricesQuery, product.ID); err != nil {
+		lg.Error("Update failed", "id", product.ID, "err", err)
+		return utils.WrapError("replace product prices", err)
+	}
+	if err := p.insertPrices(ctx, product); err != nil {
+		lg.Error("Update failed", "id", product.ID, "err", err)
+		return utils.WrapError("replace product prices", err)
+	}
+	return nil
+}
+
+func (p *ProductPgx) FindByID(ctx context.Context, id string) (*entity.Product, error) {
+	return p.findOne(ctx, "FindByID", findByIDQuery, id)
+}
+
+func (p *ProductPgx) FindActive(ctx context.Context, orderType entity.OrderType) (*entity.Product, error) {
+	return p.findOne(ctx, "FindActive", findActiveQuery, orderType)
+}
+
+func (p *ProductPgx) List(ctx context.Context, includeInactive bool) ([]*entity.Product, error) {
+	lg := p.logger.With("method", "List")
+
+	query := listActiveQuery
+	if includeInactive {
+		query = listQuery
+	}
+	rows, err := p.postgres.Reader(ctx).Query(ctx, query)
+	if err != nil {
+		lg.Error("List failed", "err", err)
+		return nil, utils.WrapError("list products", err)
+	}
+	defer rows.Close()
+
+	products := []*entity.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan product", err)
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list products", err)
+	}
+
+	if err := p.loadPrices(ctx, products...); err != nil {
+		lg.Error("List failed", "err", err)
+		return nil, utils.WrapError("list product prices", err)
+	}
+	return products, nil
+}
+
+func (p *ProductPgx) Delete(ctx context.Context, id string) error {
+	lg := p.logger.With("method", "Delete")
+
+	cmd, err := p.postgres.Querier(ctx).Exec(ctx, deleteQuery, id)
+	if err != nil {
+		lg.Error("Delete failed", "id", id, "err", err)
+		return utils.WrapError("delete product", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return productRepository.ErrProductNotFound
+	}
+	return nil
+}
+
+func (p *ProductPgx) findOne(ctx context.Context, method, query string, arg interface{}) (*entity.Product, error) {
+	lg := p.logger.With("method", method)
+
+	product, err := scanProduct(p.postgres.Reader(ctx).QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, productRepository.ErrProductNotFound
+		}
+		lg.Error(method+" failed", "err", err)
+		return nil, utils.WrapError("find product", err)
+	}
+	if err := p.loadPrices(ctx, product); err != nil {
+		lg.Error(method+" failed", "id", product.ID, "err", err)
+		return nil, utils.WrapError("find product prices", err)
+	}
+	return product, nil
+}
+
+// insertPrices sends every price of product in one round trip.
+func (p *ProductPgx) insertPrices(ctx context.Context, product *entity.Product) error {
+	if len(product.Prices) == 0 {
+		return nil
+	}
+	b := &pgx.Batch{}
+	for _, price := range product.Prices {
+		b.Queue(insertPriceQuery, product.ID, price.Price.Currency, price.Price.Minor)
+	}
+	return p.postgres.Querier(ctx).SendBatch(ctx, b).Close()
+}
+
+// loadPrices fills the prices of products with a single query.
+func (p *ProductPgx) loadPrices(ctx context.Context, products ...*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*entity.Product, len(products))
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		product.Prices = []entity.ProductPrice{}
+		byID[product.ID] = product
+		ids = append(ids, product.ID)
+	}
+
+	rows, err := p.postgres.Reader(ctx).Query(ctx, pricesQuery, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			productID uuid.UUID
+			price     entity.ProductPrice
+		)
+		if err := rows.Scan(&productID, &price.Price.Currency, &price.Price.Minor); err != nil {
+			return err
+		}
+		if product, ok := byID[productID]; ok {
+			product.Prices = append(product.Prices, price)
+		}
+	}
+	return rows.Err()
+}
+
+func scanProduct(row pgx.Row) (*entity.Product, error) {
+	product := &entity.Product{}
+	err := row.Scan(
+		&product.ID, &product.OrderType, &product.Name, &product.VideoWidth, &product.VideoHeight, &product.MaxDurationSeconds,
+		&product.WatermarkFree, &product.Active, &product.CreatedAt, &product.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// mapWriteError turns a violation of the one-active-product index into
+// ErrActiveProductExists and returns any other error unchanged.
+func mapWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == activeOrderTypeIndex {
+		return productRepository.ErrActiveProductExists
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package productPGX
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	"github.com/playture/backend/internal/repository/batch"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ productRepository.Repository = (*ProductPgx)(nil)
+
+func newTestRepo(t *testing.T) (*ProductPgx, uow.IUOW) {
+	pg := pgtest.New(t)
+	return NewProductPgx(pgtest.Logger(), pg), uow.NewUOW(pg)
+}
+
+func newProduct(orderType entity.OrderType, active bool) *entity.Product {
+	return &entity.Product{
+		OrderType:          orderType,
+		Name:               orderType.String() + " video",
+		VideoWidth:         1920,
+		VideoHeight:        1080,
+		MaxDurationSeconds: 60,
+		WatermarkFree:      true,
+		Active:             active,
+		Prices: []entity.ProductPrice{
+			{Price: entity.Money{Minor: 4999, Currency: "USD"}},
+			{Price: entity.Money{Minor: 7500, Currency: "JPY"}},
+		},
+		CreatedAt: 1_700_000_000,
+		UpdatedAt: 1_700_000_000,
+	}
+}
+
+func create(t *testing.T, repo *ProductPgx, u uow.IUOW, product *entity.Product) error {
+	t.Helper()
+	_, err := u.Do(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return repo.Create(ctx, product)
+	}, time.Second)
+	return err
+}
+
+func TestCreateAndFind(t *testing.T) {
+	repo, u := newTestRepo(t)
+	ctx := context.Background()
+
+	product := newProduct(entity.OrderTypePremium, true)
+	if err := create(t, repo, u, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := repo.FindActive(ctx, entity.OrderTypePremium)
+	if err != nil {
+		t.Fatalf("FindActive: %v", err)
+	}
+	if got.ID != product.ID || got.Name != product.Name || !got.WatermarkFree {
+		t.Errorf("FindActive = %+v, want %+v", got, product)
+	}
+	if len(got.Prices) != 2 {
+		t.Fatalf("prices = %+v, want 2", got.Prices)
+	}
+	usd, ok := got.PriceIn("usd")
+	if !ok || usd.Price.Minor != 4999 {
+		t.Errorf("USD price = %+v, %v", usd, ok)
+	}
+	if jpy, ok := got.PriceIn("JPY"); !ok || jpy.Price.Minor != 7500 {
+		t.Errorf("JPY price = %+v, %v", jpy, ok)
+	}
+
+	if _, err := repo.FindActive(ctx, entity.OrderTypeBasic); !errors.Is(err, productRepository.ErrProductNotFound) {
+		t.Errorf("FindActive(BASIC) err = %v, want ErrProductNotFound", err)
+	}
+}
+
+func TestWritesRequireTx(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	product := newProduct(entity.OrderTypeBasic, true)
+	if _, err := repo.Create(context.Background(), product); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("Create err = %v, want ErrTxRequired", err)
+	}
+	if err := repo.Update(context.Background(), product); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("Update err = %v, want ErrTxRequired", err)
+	}
+}
+
+func TestOneActiveProductPerOrderType(t *testing.T) {
+	repo, u := newTestRepo(t)
+
+	if err := create(t, repo, u, newProduct(entity.OrderTypeBasic, true)); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := create(t, repo, u, newProduct(entity.OrderTypeBasic, false)); err != nil {
+		t.Fatalf("Create inactive: %v", err)
+	}
+	err := create(t, repo, u, newProduct(entity.OrderTypeBasic, true))
+	if !errors.Is(err, productRepository.ErrActiveProductExists) {
+		t.Errorf("second active Create err = %v, want ErrActiveProductExists", err)
+	}
+
+	active, err := repo.List(context.Background(), false)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	all, err := repo.List(context.Background(), true)
+	if err != nil {
+		t.Fatalf("List all: %v", err)
+	}
+	if len(active) != 1 || len(all) != 2 {
+		t.Errorf("List = %d active, %d total, want 1 and 2", len(active), len(all))
+	}
+}
+
+func TestUpdateReplacesPrices(t *testing.T) {
+	repo, u := newTestRepo(t)
+	ctx := context.Background()
+
+	product := newProduct(entity.OrderTypeCustom, true)
+	if err := create(t, repo, u, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	product.Name = "Custom cut"
+	product.Prices = []entity.ProductPrice{{Price: entity.Money{Minor: 19900, Currency: "EUR"}}}
+	_, err := u.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, repo.Update(ctx, product)
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := repo.FindByID(ctx, product.ID.String())
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Name != "Custom cut" || len(got.Prices) != 1 || got.Prices[0].Price != product.Prices[0].Price {
+		t.Errorf("FindByID = %+v, want the updated name and only the EUR price", got)
+	}
+}
+
+func TestDeleteCascadesPrices(t *testing.T) {
+	repo, u := newTestRepo(t)
+	ctx := context.Background()
+
+	product := newProduct(entity.OrderTypeBasic, true)
+	if err := create(t, repo, u, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Delete(ctx, product.ID.String()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, product.ID.String()); !errors.Is(err, productRepository.ErrProductNotFound) {
+		t.Errorf("second Delete err = %v, want ErrProductNotFound", err)
+	}
+	// a new active product for the same type must not trip over old prices
+	if err := create(t, repo, u, newProduct(entity.OrderTypeBasic, true)); err != nil {
+		t.Errorf("Create after delete: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package productRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	// ErrActiveProductExists is returned when a write would leave two active
+	// products for one order type.
+	ErrActiveProductExists = errors.New("order type already has an active product")
+)
+
+type Repository interface {
+	// Create and Update write the product together with its prices and must
+	// run inside a uow transaction. Update replaces every price.
+	Create(ctx context.Context, product *entity.Product) (string, error)
+	Update(ctx context.Context, product *entity.Product) error
+	FindByID(ctx context.Context, id string) (*entity.Product, error)
+	// FindActive returns the product checkout sells for orderType.
+	FindActive(ctx context.Context, orderType entity.OrderType) (*entity.Product, error)
+	List(ctx context.Context, includeInactive bool) ([]*entity.Product, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,277 @@
+package promotionPGX
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	"github.com/playture/backend/internal/repository/batch"
+	"github.com/playture/backend/internal/repository/pagination"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO promotions (
+			code, kind, percent_off, amount_off_minor, amount_off_currency,
+			starts_at, ends_at, max_redemptions, max_per_email, order_types,
+			active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5,
+			$6, $7, $8, $9, $10,
+			$11, $12, $13
+		) RETURNING id`
+
+	// updateQuery leaves redemption_count alone, only Redeem moves it.
+	updateQuery = `
+		UPDATE promotions SET
+			code=$2, kind=$3, percent_off=$4, amount_off_minor=$5, amount_off_currency=$6,
+			starts_at=$7, ends_at=$8, max_redemptions=$9, max_per_email=$10, order_types=$11,
+			active=$12, updated_at=$13
+		WHERE id=$1`
+
+	selectColumns = `
+		id, code, kind, COALESCE(percent_off, 0), COALESCE(amount_off_minor, 0), COALESCE(amount_off_currency, ''),
+		COALESCE(starts_at, 0), COALESCE(ends_at, 0), COALESCE(max_redemptions, 0), COALESCE(max_per_email, 0),
+		redemption_count, order_types, active, created_at, updated_at`
+
+	findByIDQuery = `SELECT ` + selectColumns + ` FROM promotions WHERE id = $1`
+
+	lockByCodeQuery = `SELECT ` + selectColumns + ` FROM promotions WHERE code = $1 FOR UPDATE`
+
+	countRedemptionsQuery = `SELECT count(*) FROM promotion_redemptions WHERE promotion_id = $1 AND email = $2`
+
+	insertRedemptionQuery = `
+		INSERT INTO promotion_redemptions (promotion_id, order_id, email, discount_minor, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	incrementRedemptionsQuery = `UPDATE promotions SET redemption_count = redemption_count + 1 WHERE id = $1`
+
+	releaseQuery = `
+		WITH released AS (
+			DELETE FROM promotion_redemptions WHERE order_id = $1 RETURNING promotion_id
+		)
+		UPDATE promotions SET redemption_count = redemption_count - 1
+		FROM released WHERE promotions.id = released.promotion_id`
+
+	// codeIndex is the unique constraint on promotions.code.
+	codeIndex = "promotions_code_key"
+)
+
+type PromotionPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewPromotionPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *PromotionPgx {
+	return &PromotionPgx{
+		logger:   logger.With("layer", "PromotionRepository"),
+		postgres: postgres,
+	}
+}
+
+func (p *PromotionPgx) Create(ctx context.Context, promotion *entity.Promotion) (string, error) {
+	lg := p.logger.With("method", "Create")
+
+	var id uuid.UUID
+	err := p.postgres.Querier(ctx).QueryRow(ctx, createQuery, append(writeValues(promotion), promotion.CreatedAt, promotion.UpdatedAt)...).Scan(&id)
+	if err != nil {
+		if mapped := mapWriteError(err); mapped != err {
+			return "", mapped
+		}
+		lg.Error("Create failed", "err", err)
+		return "", utils.WrapError("create promotion", err)
+	}
+	promotion.ID = id
+	return id.String(), nil
+}
+
+func (p *PromotionPgx) Update(ctx context.Context, promotion *entity.Promotion) error {
+	lg := p.logger.With("method", "Update")
+
+	args := append([]interface{}{promotion.ID}, writeValues(promotion)...)
+	args = append(args, promotion.UpdatedAt)
+	cmd, err := p.postgres.Querier(ctx).Exec(ctx, updateQuery, args...)
+	if err != nil {
+		if mapped := mapWriteError(err); mapped != err {
+			return mapped
+		}
+		lg.Error("Update failed", "id", promotion.ID, "err", err)
+		return utils.WrapError("update promotion", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return promotionRepository.ErrPromotionNotFound
+	}
+	return nil
+}
+
+func (p *PromotionPgx) FindByID(ctx context.Context, id string) (*entity.Promotion, error) {
+	lg := p.logger.With("method", "FindByID")
+
+	promotion, err := scanPromotion(p.postgres.Reader(ctx).QueryRow(ctx, findByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, promotionRepository.ErrPromotionNotFound
+		}
+		lg.Error("FindByID failed", "id", id, "err", err)
+		return nil, utils.WrapError("find promotion", err)
+	}
+	return promotion, nil
+}
+
+func (p *PromotionPgx) List(ctx context.Context, page pagination.Request) (*pagination.Page[*entity.Promotion], error) {
+	lg := p.logger.With("method", "List")
+
+	keyset, err := page.Keyset(1)
+	if err != nil {
+		return nil, utils.WrapError("list promotions", err)
+	}
+	query := "SELECT " + selectColumns + " FROM promotions"
+	args := keyset.Args
+	if keyset.Condition != "" {
+		query += " WHERE " + keyset.Condition
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", keyset.OrderBy, len(args)+1)
+	args = append(args, keyset.Limit)
+
+	rows, err := p.postgres.Reader(ctx).Query(ctx, query, args...)
+	if err != nil {
+		lg.Error("List failed", "err", err)
+		return nil, utils.WrapError("list promotions", err)
+	}
+	defer rows.Close()
+
+	var promotions []*entity.Promotion
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan promotion", err)
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list promotions", err)
+	}
+
+	return pagination.Build(keyset, promotions, func(promotion *entity.Promotion) (int64, uuid.UUID) {
+		return promotion.CreatedAt, promotion.ID
+	}), nil
+}
+
+func (p *PromotionPgx) LockByCode(ctx context.Context, code string) (*entity.Promotion, error) {
+	lg := p.logger.With("method", "LockByCode")
+	if err := batch.RequireTx(ctx); err != nil {
+		return nil, err
+	}
+
+	promotion, err := scanPromotion(p.postgres.Querier(ctx).QueryRow(ctx, lockByCodeQuery, code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, promotionRepository.ErrPromotionNotFound
+		}
+		lg.Error("LockByCode failed", "err", err)
+		return nil, utils.WrapError("lock promotion", err)
+	}
+	return promotion, nil
+}
+
+func (p *PromotionPgx) CountRedemptions(ctx context.Context, promotionID string, email string) (int, error) {
+	var count int
+	// read through the transaction, a replica may not have seen the last
+	// redemption yet
+	if err := p.postgres.Querier(ctx).QueryRow(ctx, countRedemptionsQuery, promotionID, email).Scan(&count); err != nil {
+		p.logger.Error("CountRedemptions failed", "id", promotionID, "err", err)
+		return 0, utils.WrapError("count redemptions", err)
+	}
+	return count, nil
+}
+
+func (p *PromotionPgx) Redeem(ctx context.Context, redemption *entity.PromotionRedemption) error {
+	lg := p.logger.With("method", "Redeem")
+	if err := batch.RequireTx(ctx); err != nil {
+		return err
+	}
+
+	q := p.postgres.Querier(ctx)
+	err := q.QueryRow(ctx, insertRedemptionQuery,
+		redemption.PromotionID, redemption.OrderID, redemption.Email,
+		redemption.Discount.Minor, redemption.Discount.Currency, redemption.CreatedAt,
+	).Scan(&redemption.ID)
+	if err != nil {
+		lg.Error("Redeem failed", "id", redemption.PromotionID, "err", err)
+		return utils.WrapError("insert redemption", err)
+	}
+	cmd, err := q.Exec(ctx, incrementRedemptionsQuery, redemption.PromotionID)
+	if err != nil {
+		lg.Error("Redeem failed", "id", redemption.PromotionID, "err", err)
+		return utils.WrapError("count redemption", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return promotionRepository.ErrPromotionNotFound
+	}
+	return nil
+}
+
+func (p *PromotionPgx) Release(ctx context.Context, orderID string) error {
+	if _, err := p.postgres.Querier(ctx).Exec(ctx, releaseQuery, orderID); err != nil {
+		p.logger.Error("Release failed", "method", "Release", "orderID", orderID, "err", err)
+		return utils.WrapError("release redemption", err)
+	}
+	return nil
+}
+
+// writeValues returns the columns from code to active that Create and
+// Update write. Open bounds and limits are written as NULL.
+func writeValues(promotion *entity.Promotion) []interface{} {
+	orderTypes := make([]int16, len(promotion.OrderTypes))
+	for i, t := range promotion.OrderTypes {
+		orderTypes[i] = int16(t)
+	}
+	return []interface{}{
+		promotion.Code, promotion.Kind, postgresql.NullIfZero(promotion.PercentOff),
+		postgresql.NullIfZero(promotion.AmountOff.Minor), postgresql.NullIfZero(promotion.AmountOff.Currency),
+		postgresql.NullIfZero(promotion.StartsAt), postgresql.NullIfZero(promotion.EndsAt),
+		postgresql.NullIfZero(promotion.MaxRedemptions), postgresql.NullIfZero(promotion.MaxPerEmail),
+		orderTypes, promotion.Active,
+	}
+}
+
+func scanPromotion(row pgx.Row) (*entity.Promotion, error) {
+	promotion := &entity.Promotion{}
+	var orderTypes []int16
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Kind, &promotion.PercentOff,
+		&promotion.AmountOff.Minor, &promotion.AmountOff.Currency,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.MaxRedemptions, &promotion.MaxPerEmail,
+		&promotion.Redemptions, &orderTypes, &promotion.Active, &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	promotion.OrderTypes = make([]entity.OrderType, len(orderTypes))
+	for i, t := range orderTypes {
+		promotion.OrderTypes[i] = entity.OrderType(t)
+	}
+	return promotion, nil
+}
+
+// mapWriteError turns a duplicate code into ErrCodeExists and returns any
+// other error unchanged.
+func mapWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == codeIndex {
+		return promotionRepository.ErrCodeExists
+	}
+	return err
+}
@@ -0,0 +1,281 @@
+package promotionPGX
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	"github.com/playture/backend/internal/repository/batch"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobPGX "github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_pgx"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var _ promotionRepository.Repository = (*PromotionPgx)(nil)
+
+type fixture struct {
+	repo *PromotionPgx
+	uow  uow.IUOW
+	// newOrderID inserts a job and an order for redemptions to point at.
+	newOrderID func(t *testing.T) uuid.UUID
+}
+
+func newFixture(t *testing.T) fixture {
+	pg := pgtest.New(t)
+	jobs := jobPGX.NewJobPgx(pgtest.Logger(), pg)
+	orders := order_pgx.NewOrderPgx(pgtest.Logger(), pg)
+	return fixture{
+		repo: NewPromotionPgx(pgtest.Logger(), pg),
+		uow:  uow.NewUOW(pg),
+		newOrderID: func(t *testing.T) uuid.UUID {
+			t.Helper()
+			ctx := context.Background()
+			jobID, err := jobs.Create(ctx, jobContract.NewJob(1_700_000_000))
+			if err != nil {
+				t.Fatalf("create job: %v", err)
+			}
+			order := order_contract.NewOrder(uuid.MustParse(jobID), 1_700_000_000)
+			if _, err := orders.Create(ctx, order); err != nil {
+				t.Fatalf("create order: %v", err)
+			}
+			return order.ID
+		},
+	}
+}
+
+func newPromotion(code string) *entity.Promotion {
+	return &entity.Promotion{
+		Code:       code,
+		Kind:       entity.PromotionKindPercent,
+		PercentOff: 20,
+		OrderTypes: []entity.OrderType{entity.OrderTypeBasic, entity.OrderTypePremium},
+		Active:     true,
+		CreatedAt:  1_700_000_000,
+		UpdatedAt:  1_700_000_000,
+	}
+}
+
+func TestCreateAndFind(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	promotion := newPromotion("SPRING20")
+	promotion.EndsAt = 1_800_000_000
+	id, err := f.repo.Create(ctx, promotion)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := f.repo.FindByID(ctx, id)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Code != "SPRING20" || got.PercentOff != 20 || got.EndsAt != 1_800_000_000 || got.StartsAt != 0 || got.MaxRedemptions != 0 {
+		t.Errorf("FindByID = %+v, want %+v", got, promotion)
+	}
+	if !slices.Equal(got.OrderTypes, promotion.OrderTypes) {
+		t.Errorf("order types = %v, want %v", got.OrderTypes, promotion.OrderTypes)
+	}
+
+	if _, err := f.repo.Create(ctx, newPromotion("SPRING20")); !errors.Is(err, promotionRepository.ErrCodeExists) {
+		t.Errorf("duplicate Create err = %v, want ErrCodeExists", err)
+	}
+}
+
+func TestFixedAmountRoundTrip(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	promotion := newPromotion("TENOFF")
+	promotion.Kind, promotion.PercentOff = entity.PromotionKindFixed, 0
+	promotion.AmountOff = entity.Money{Minor: 1000, Currency: "EUR"}
+	promotion.OrderTypes = nil
+	id, err := f.repo.Create(ctx, promotion)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := f.repo.FindByID(ctx, id)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.AmountOff != promotion.AmountOff || got.PercentOff != 0 || len(got.OrderTypes) != 0 {
+		t.Errorf("FindByID = %+v, want 10 EUR off every order type", got)
+	}
+}
+
+func TestLockRequiresTx(t *testing.T) {
+	f := newFixture(t)
+
+	if _, err := f.repo.LockByCode(context.Background(), "ANY"); !errors.Is(err, batch.ErrTxRequired) {
+		t.Errorf("LockByCode err = %v, want ErrTxRequired", err)
+	}
+}
+
+// TestConcurrentRedemptionsRespectLimit races more checkouts than the code
+// allows. Each one locks the code, checks the limit and redeems the way the
+// promotions service does, and only MaxRedemptions of them may succeed.
+func TestConcurrentRedemptionsRespectLimit(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	promotion := newPromotion("LIMITED")
+	promotion.MaxRedemptions = 3
+	if _, err := f.repo.Create(ctx, promotion); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	const attempts = 10
+	orderIDs := make([]uuid.UUID, attempts)
+	for i := range orderIDs {
+		orderIDs[i] = f.newOrderID(t)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		redeemed int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(orderID uuid.UUID) {
+			defer wg.Done()
+			ok, err := uow.Do(ctx, f.uow, func(ctx context.Context) (bool, error) {
+				locked, err := f.repo.LockByCode(ctx, "LIMITED")
+				if err != nil {
+					return false, err
+				}
+				if locked.Redemptions >= locked.MaxRedemptions {
+					return false, nil
+				}
+				return true, f.repo.Redeem(ctx, &entity.PromotionRedemption{
+					PromotionID: locked.ID,
+					OrderID:     orderID,
+					Email:       "customer@example.com",
+					Discount:    entity.Money{Minor: 1000, Currency: "USD"},
+					CreatedAt:   1_700_000_000,
+				})
+			}, 10*time.Second)
+			if err != nil {
+				t.Errorf("redeem: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				redeemed++
+				mu.Unlock()
+			}
+		}(orderIDs[i])
+	}
+	wg.Wait()
+
+	if redeemed != 3 {
+		t.Errorf("%d redemptions went through, want 3", redeemed)
+	}
+	got, err := f.repo.FindByID(ctx, promotion.ID.String())
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Redemptions != 3 {
+		t.Errorf("redemption count = %d, want 3", got.Redemptions)
+	}
+	used, err := f.repo.CountRedemptions(ctx, promotion.ID.String(), "customer@example.com")
+	if err != nil {
+		t.Fatalf("CountRedemptions: %v", err)
+	}
+	if used != 3 {
+		t.Errorf("CountRedemptions = %d, want 3", used)
+	}
+}
+
+func TestRedeemOncePerOrder(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	promotion := newPromotion("ONCE")
+	if _, err := f.repo.Create(ctx, promotion); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	orderID := f.newOrderID(t)
+
+	redeem := func() error {
+		_, err := f.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+			return nil, f.repo.Redeem(ctx, &entity.PromotionRedemption{
+				PromotionID: promotion.ID,
+				OrderID:     orderID,
+				Email:       "customer@example.com",
+				Discount:    entity.Money{Minor: 1000, Currency: "USD"},
+				CreatedAt:   1_700_000_000,
+			})
+		}, time.Second)
+		return err
+	}
+	if err := redeem(); err != nil {
+		t.Fatalf("Redeem: %v", err)
+	}
+	if err := redeem(); err == nil {
+		t.Errorf("second Redeem for one order succeeded")
+	}
+
+	got, err := f.repo.FindByID(ctx, promotion.ID.String())
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Redemptions != 1 {
+		t.Errorf("redemption count = %d, want 1 after the rolled back attempt", got.Redemptions)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	promotion := newPromotion("RELEASE")
+	if _, err := f.repo.Create(ctx, promotion); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	kept, released := f.newOrderID(t), f.newOrderID(t)
+	for _, orderID := range []uuid.UUID{kept, released} {
+		_, err := f.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+			return nil, f.repo.Redeem(ctx, &entity.PromotionRedemption{
+				PromotionID: promotion.ID,
+				OrderID:     orderID,
+				Email:       "customer@example.com",
+				Discount:    entity.Money{Minor: 1000, Currency: "USD"},
+				CreatedAt:   1_700_000_000,
+			})
+		}, time.Second)
+		if err != nil {
+			t.Fatalf("Redeem: %v", err)
+		}
+	}
+
+	// releasing twice, or an order without a redemption, changes nothing more
+	for _, orderID := range []uuid.UUID{released, released, f.newOrderID(t)} {
+		if err := f.repo.Release(ctx, orderID.String()); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+	}
+
+	got, err := f.repo.FindByID(ctx, promotion.ID.String())
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if got.Redemptions != 1 {
+		t.Errorf("redemption count = %d, want 1", got.Redemptions)
+	}
+	used, err := f.repo.CountRedemptions(ctx, promotion.ID.String(), "customer@example.com")
+	if err != nil {
+		t.Fatalf("CountRedemptions: %v", err)
+	}
+	if used != 1 {
+		t.Errorf("CountRedemptions = %d, want 1", used)
+	}
+}
@@ -0,0 +1,34 @@
+package promotionRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/pagination"
+)
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrCodeExists        = errors.New("promotion code already exists")
+)
+
+type Repository interface {
+	Create(ctx context.Context, promotion *entity.Promotion) (string, error)
+	Update(ctx context.Context, promotion *entity.Promotion) error
+	FindByID(ctx context.Context, id string) (*entity.Promotion, error)
+	List(ctx context.Context, page pagination.Request) (*pagination.Page[*entity.Promotion], error)
+
+	// LockByCode loads the promotion behind code and holds its row until the
+	// surrounding transaction ends, so concurrent checkouts redeeming the
+	// same code take turns. It must run inside a uow transaction.
+	LockByCode(ctx context.Context, code string) (*entity.Promotion, error)
+	// CountRedemptions returns how often email has used the promotion.
+	CountRedemptions(ctx context.Context, promotionID string, email string) (int, error)
+	// Redeem records the redemption and bumps the promotion's counter. Call
+	// it after LockByCode in the same transaction.
+	Redeem(ctx context.Context, redemption *entity.PromotionRedemption) error
+	// Release deletes the redemption of an order, if it has one, and gives
+	// its use back to the promotion.
+	Release(ctx context.Context, orderID string) error
+}
@@ -0,0 +1,183 @@
+package refundPGX
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	refundRepository "github.com/playture/backend/internal/repository/refund_repository"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	createQuery = `
+		INSERT INTO refunds (
+			order_id, amount_minor, currency, reason, actor_id, actor_name,
+			status, stripe_refund_id, failure_reason, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6,
+			$7, $8, $9, $10, $11
+		) RETURNING id`
+
+	updateResultQuery = `
+		UPDATE refunds SET status=$2, stripe_refund_id=$3, failure_reason=$4, updated_at=$5
+		WHERE id=$1`
+
+	selectColumns = `
+		id, order_id, amount_minor, currency, reason, actor_id, actor_name,
+		status, COALESCE(stripe_refund_id, ''), COALESCE(failure_reason, ''), created_at, updated_at`
+
+	findByIDQuery = `SELECT ` + selectColumns + ` FROM refunds WHERE id = $1`
+
+	listByOrderQuery = `SELECT ` + selectColumns + ` FROM refunds WHERE order_id = $1 ORDER BY created_at, id`
+
+	listPendingQuery = `
+		SELECT ` + selectColumns + ` FROM refunds
+		WHERE status = 1 AND created_at > $1 AND updated_at < $2
+		ORDER BY updated_at, id
+		LIMIT $3`
+
+	totalQuery = `SELECT COALESCE(sum(amount_minor), 0) FROM refunds WHERE order_id = $1 AND status = ANY($2)`
+)
+
+type RefundPgx struct {
+	logger   *slog.Logger
+	postgres *postgresql.Postgres
+}
+
+func NewRefundPgx(
+	logger *slog.Logger,
+	postgres *postgresql.Postgres,
+) *RefundPgx {
+	return &RefundPgx{
+		logger:   logger.With("layer", "RefundRepository"),
+		postgres: postgres,
+	}
+}
+
+func (r *RefundPgx) Create(ctx context.Context, refund *entity.Refund) (string, error) {
+	lg := r.logger.With("method", "Create")
+
+	var id uuid.UUID
+	err := r.postgres.Querier(ctx).QueryRow(ctx, createQuery,
+		refund.OrderID, refund.Amount.Minor, refund.Amount.Currency, refund.Reason, refund.ActorID, refund.ActorName,
+		refund.Status, postgresql.NullIfZero(refund.StripeRefundID), postgresql.NullIfZero(refund.FailureReason),
+		refund.CreatedAt, refund.UpdatedAt,
+	).Scan(&id)
+	if err != nil {
+		lg.Error("Create failed", "orderID", refund.OrderID, "err", err)
+		return "", utils.WrapError("create refund", err)
+	}
+	refund.ID = id
+	return id.String(), nil
+}
+
+func (r *RefundPgx) UpdateResult(ctx context.Context, refund *entity.Refund) error {
+	lg := r.logger.With("method", "UpdateResult")
+
+	cmd, err := r.postgres.Querier(ctx).Exec(ctx, updateResultQuery,
+		refund.ID, refund.Status, postgresql.NullIfZero(refund.StripeRefundID), postgresql.NullIfZero(refund.FailureReason),
+		refund.UpdatedAt,
+	)
+	if err != nil {
+		lg.Error("UpdateResult failed", "id", refund.ID, "err", err)
+		return utils.WrapError("update refund", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return refundRepository.ErrRefundNotFound
+	}
+	return nil
+}
+
+// FindByID reads through the transaction, settling a refund checks it is
+// still pending under the order lock.
+func (r *RefundPgx) FindByID(ctx context.Context, id string) (*entity.Refund, error) {
+	refund, err := scanRefund(r.postgres.Querier(ctx).QueryRow(ctx, findByIDQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, refundRepository.ErrRefundNotFound
+		}
+		r.logger.Error("FindByID failed", "method", "FindByID", "id", id, "err", err)
+		return nil, utils.WrapError("find refund", err)
+	}
+	return refund, nil
+}
+
+func (r *RefundPgx) ListByOrder(ctx context.Context, orderID string) ([]*entity.Refund, error) {
+	lg := r.logger.With("method", "ListByOrder")
+
+	rows, err := r.postgres.Reader(ctx).Query(ctx, listByOrderQuery, orderID)
+	if err != nil {
+		lg.Error("ListByOrder failed", "orderID", orderID, "err", err)
+		return nil, utils.WrapError("list refunds", err)
+	}
+	defer rows.Close()
+
+	refunds := []*entity.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan refund", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list refunds", err)
+	}
+	return refunds, nil
+}
+
+func (r *RefundPgx) ListPending(ctx context.Context, createdAfter, updatedBefore int64, limit int) ([]*entity.Refund, error) {
+	lg := r.logger.With("method", "ListPending")
+
+	rows, err := r.postgres.Querier(ctx).Query(ctx, listPendingQuery, createdAfter, updatedBefore, limit)
+	if err != nil {
+		lg.Error("ListPending failed", "err", err)
+		return nil, utils.WrapError("list pending refunds", err)
+	}
+	defer rows.Close()
+
+	refunds := []*entity.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, utils.WrapError("scan refund", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.WrapError("list pending refunds", err)
+	}
+	return refunds, nil
+}
+
+func (r *RefundPgx) Total(ctx context.Context, orderID string, statuses ...entity.RefundStatus) (int64, error) {
+	codes := make([]int16, len(statuses))
+	for i, status := range statuses {
+		codes[i] = int16(status)
+	}
+
+	var total int64
+	// read through the transaction, callers sum refunds to decide on a new one
+	if err := r.postgres.Querier(ctx).QueryRow(ctx, totalQuery, orderID, codes).Scan(&total); err != nil {
+		r.logger.Error("Total failed", "method", "Total", "orderID", orderID, "err", err)
+		return 0, utils.WrapError("total refunds", err)
+	}
+	return total, nil
+}
+
+func scanRefund(row pgx.Row) (*entity.Refund, error) {
+	refund := &entity.Refund{}
+	err := row.Scan(
+		&refund.ID, &refund.OrderID, &refund.Amount.Minor, &refund.Amount.Currency, &refund.Reason, &refund.ActorID, &refund.ActorName,
+		&refund.Status, &refund.StripeRefundID, &refund.FailureReason, &refund.CreatedAt, &refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
@@ -0,0 +1,165 @@
+package refundPGX
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/postgresql/pgtest"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobPGX "github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_pgx"
+	refundRepository "github.com/playture/backend/internal/repository/refund_repository"
+)
+
+var _ refundRepository.Repository = (*RefundPgx)(nil)
+
+// newTestRepo returns the repository and an order for refunds to point at.
+func newTestRepo(t *testing.T) (*RefundPgx, *entity.Order) {
+	pg := pgtest.New(t)
+	ctx := context.Background()
+
+	jobID, err := jobPGX.NewJobPgx(pgtest.Logger(), pg).Create(ctx, jobContract.NewJob(1_700_000_000))
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	order := order_contract.NewOrder(uuid.MustParse(jobID), 1_700_000_000)
+	if _, err := order_pgx.NewOrderPgx(pgtest.Logger(), pg).Create(ctx, order); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	return NewRefundPgx(pgtest.Logger(), pg), order
+}
+
+func newRefund(orderID uuid.UUID, minor int64, createdAt int64) *entity.Refund {
+	return &entity.Refund{
+		OrderID:   orderID,
+		Amount:    entity.Money{Minor: minor, Currency: "USD"},
+		Reason:    "customer asked",
+		ActorName: "finance",
+		Status:    entity.RefundStatusPending,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+func TestCreateAndList(t *testing.T) {
+	repo, order := newTestRepo(t)
+	ctx := context.Background()
+
+	actorID := uuid.New()
+	first := newRefund(order.ID, 1000, 1_700_000_100)
+	first.ActorID = &actorID
+	second := newRefund(order.ID, 500, 1_700_000_200)
+	for _, refund := range []*entity.Refund{second, first} {
+		if _, err := repo.Create(ctx, refund); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	refunds, err := repo.ListByOrder(ctx, order.ID.String())
+	if err != nil {
+		t.Fatalf("ListByOrder: %v", err)
+	}
+	if len(refunds) != 2 || refunds[0].ID != first.ID || refunds[1].ID != second.ID {
+		t.Fatalf("ListByOrder = %+v, want first then second", refunds)
+	}
+	if refunds[0].ActorID == nil || *refunds[0].ActorID != actorID || refunds[1].ActorID != nil {
+		t.Errorf("actor ids = %v and %v, want %s and nil", refunds[0].ActorID, refunds[1].ActorID, actorID)
+	}
+	if refunds[0].Amount != first.Amount || refunds[0].StripeRefundID != "" {
+		t.Errorf("first = %+v, want %+v", refunds[0], first)
+	}
+}
+
+func TestUpdateResultAndTotal(t *testing.T) {
+	repo, order := newTestRepo(t)
+	ctx := context.Background()
+
+	succeeded := newRefund(order.ID, 1000, 1_700_000_100)
+	failed := newRefund(order.ID, 700, 1_700_000_200)
+	pending := newRefund(order.ID, 300, 1_700_000_300)
+	for _, refund := range []*entity.Refund{succeeded, failed, pending} {
+		if _, err := repo.Create(ctx, refund); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	succeeded.Status, succeeded.StripeRefundID = entity.RefundStatusSucceeded, "re_1"
+	failed.Status, failed.FailureReason = entity.RefundStatusFailed, "charge disputed"
+	for _, refund := range []*entity.Refund{succeeded, failed} {
+		if err := repo.UpdateResult(ctx, refund); err != nil {
+			t.Fatalf("UpdateResult: %v", err)
+		}
+	}
+
+	id := order.ID.String()
+	if total, err := repo.Total(ctx, id, entity.RefundStatusSucceeded); err != nil || total != 1000 {
+		t.Errorf("Total(succeeded) = %d, %v, want 1000", total, err)
+	}
+	if total, err := repo.Total(ctx, id, entity.RefundStatusPending, entity.RefundStatusSucceeded); err != nil || total != 1300 {
+		t.Errorf("Total(pending, succeeded) = %d, %v, want 1300", total, err)
+	}
+	if total, err := repo.Total(ctx, uuid.NewString(), entity.RefundStatusSucceeded); err != nil || total != 0 {
+		t.Errorf("Total(unknown order) = %d, %v, want 0", total, err)
+	}
+
+	refunds, err := repo.ListByOrder(ctx, id)
+	if err != nil {
+		t.Fatalf("ListByOrder: %v", err)
+	}
+	if refunds[0].StripeRefundID != "re_1" || refunds[1].FailureReason != "charge disputed" {
+		t.Errorf("results not written: %+v, %+v", refunds[0], refunds[1])
+	}
+
+	missing := newRefund(order.ID, 100, 1_700_000_400)
+	missing.ID = uuid.New()
+	if err := repo.UpdateResult(ctx, missing); !errors.Is(err, refundRepository.ErrRefundNotFound) {
+		t.Errorf("UpdateResult(unknown) error = %v, want ErrRefundNotFound", err)
+	}
+}
+
+func TestFindByIDAndListPending(t *testing.T) {
+	repo, order := newTestRepo(t)
+	ctx := context.Background()
+
+	old := newRefund(order.ID, 100, 1_700_000_000)
+	stale := newRefund(order.ID, 200, 1_700_000_100)
+	recent := newRefund(order.ID, 300, 1_700_000_200)
+	retried := newRefund(order.ID, 400, 1_700_000_150)
+	done := newRefund(order.ID, 500, 1_700_000_100)
+	for _, refund := range []*entity.Refund{old, stale, recent, retried, done} {
+		if _, err := repo.Create(ctx, refund); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	retried.UpdatedAt, retried.FailureReason = 1_700_000_180, "stripe unreachable"
+	done.Status, done.StripeRefundID = entity.RefundStatusSucceeded, "re_1"
+	for _, refund := range []*entity.Refund{retried, done} {
+		if err := repo.UpdateResult(ctx, refund); err != nil {
+			t.Fatalf("UpdateResult: %v", err)
+		}
+	}
+
+	got, err := repo.FindByID(ctx, retried.ID.String())
+	if err != nil || got.FailureReason != "stripe unreachable" || got.Status != entity.RefundStatusPending {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := repo.FindByID(ctx, uuid.NewString()); !errors.Is(err, refundRepository.ErrRefundNotFound) {
+		t.Errorf("FindByID(unknown) error = %v, want ErrRefundNotFound", err)
+	}
+
+	// old was created too long ago, recent was tried too lately, done is settled
+	pending, err := repo.ListPending(ctx, 1_700_000_050, 1_700_000_190, 10)
+	if err != nil {
+		t.Fatalf("ListPending: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != stale.ID || pending[1].ID != retried.ID {
+		t.Errorf("ListPending = %+v, want stale then retried", pending)
+	}
+	if pending, err := repo.ListPending(ctx, 1_700_000_050, 1_700_000_190, 1); err != nil || len(pending) != 1 {
+		t.Errorf("ListPending(limit 1) = %d rows, %v", len(pending), err)
+	}
+}
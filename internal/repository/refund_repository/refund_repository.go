@@ -0,0 +1,25 @@
+package refundRepository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/playture/backend/internal/entity"
+)
+
+var ErrRefundNotFound = errors.New("refund not found")
+
+type Repository interface {
+	Create(ctx context.Context, refund *entity.Refund) (string, error)
+	// UpdateResult writes the status, Stripe refund id and failure reason.
+	UpdateResult(ctx context.Context, refund *entity.Refund) error
+	FindByID(ctx context.Context, id string) (*entity.Refund, error)
+	ListByOrder(ctx context.Context, orderID string) ([]*entity.Refund, error)
+	// ListPending returns up to limit pending refunds created after
+	// createdAfter and last updated before updatedBefore, least recently
+	// updated first.
+	ListPending(ctx context.Context, createdAfter, updatedBefore int64, limit int) ([]*entity.Refund, error)
+	// Total sums the refunds of an order in the given statuses, in minor
+	// units of the order currency.
+	Total(ctx context.Context, orderID string, statuses ...entity.RefundStatus) (int64, error)
+}
@@ -1 +1,66 @@
 package repository
+
+import (
+	"github.com/google/wire"
+	apiKeyRepository "github.com/playture/backend/internal/repository/apikey_repository"
+	apiKeyPGX "github.com/playture/backend/internal/repository/apikey_repository/apikey_pgx"
+	auditRepository "github.com/playture/backend/internal/repository/audit_repository"
+	auditPGX "github.com/playture/backend/internal/repository/audit_repository/audit_pgx"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	deliveryPGX "github.com/playture/backend/internal/repository/delivery_repository/delivery_pgx"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobPGX "github.com/playture/backend/internal/repository/job_repository/job_pgx"
+	jobStatusEventRepository "github.com/playture/backend/internal/repository/job_status_event_repository"
+	jobStatusEventPGX "github.com/playture/backend/internal/repository/job_status_event_repository/job_status_event_pgx"
+	mailRepository "github.com/playture/backend/internal/repository/mail_repository"
+	mailPostmark "github.com/playture/backend/internal/repository/mail_repository/mail_postmark"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/order_repository/order_pgx"
+	outboxRepository "github.com/playture/backend/internal/repository/outbox_repository"
+	outboxPGX "github.com/playture/backend/internal/repository/outbox_repository/outbox_pgx"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	paymentStripe "github.com/playture/backend/internal/repository/payment_repository/payment_stripe"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	productPGX "github.com/playture/backend/internal/repository/product_repository/product_pgx"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	promotionPGX "github.com/playture/backend/internal/repository/promotion_repository/promotion_pgx"
+	refundRepository "github.com/playture/backend/internal/repository/refund_repository"
+	refundPGX "github.com/playture/backend/internal/repository/refund_repository/refund_pgx"
+	storageRepository "github.com/playture/backend/internal/repository/storage_repository"
+	storageCloudfront "github.com/playture/backend/internal/repository/storage_repository/storage_cloudfront"
+	streamRepository "github.com/playture/backend/internal/repository/stream_repository"
+	streamRueidis "github.com/playture/backend/internal/repository/stream_repository/stream_rueidis"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var ProviderSet = wire.NewSet(
+	uow.NewUOW,
+	jobPGX.NewJobPgx,
+	wire.Bind(new(jobRepository.Repository), new(*jobPGX.JobPgx)),
+	apiKeyPGX.NewAPIKeyPgx,
+	wire.Bind(new(apiKeyRepository.Repository), new(*apiKeyPGX.APIKeyPgx)),
+	auditPGX.NewAuditPgx,
+	wire.Bind(new(auditRepository.Repository), new(*auditPGX.AuditPgx)),
+	jobStatusEventPGX.NewJobStatusEventPgx,
+	wire.Bind(new(jobStatusEventRepository.Repository), new(*jobStatusEventPGX.JobStatusEventPgx)),
+	outboxPGX.NewOutboxPgx,
+	wire.Bind(new(outboxRepository.Repository), new(*outboxPGX.OutboxPgx)),
+	streamRueidis.NewStreamRueidis,
+	wire.Bind(new(streamRepository.Repository), new(*streamRueidis.StreamRueidis)),
+	order_pgx.NewOrderPgx,
+	wire.Bind(new(orderRepository.Repository), new(*order_pgx.OrderPgx)),
+	productPGX.NewProductPgx,
+	wire.Bind(new(productRepository.Repository), new(*productPGX.ProductPgx)),
+	promotionPGX.NewPromotionPgx,
+	wire.Bind(new(promotionRepository.Repository), new(*promotionPGX.PromotionPgx)),
+	refundPGX.NewRefundPgx,
+	wire.Bind(new(refundRepository.Repository), new(*refundPGX.RefundPgx)),
+	paymentStripe.NewPaymentStripe,
+	wire.Bind(new(paymentRepository.Repository), new(*paymentStripe.PaymentStripe)),
+	deliveryPGX.NewDeliveryPgx,
+	wire.Bind(new(deliveryRepository.Repository), new(*deliveryPGX.DeliveryPgx)),
+	mailPostmark.NewMailPostmark,
+	wire.Bind(new(mailRepository.Repository), new(*mailPostmark.MailPostmark)),
+	storageCloudfront.NewStorageCloudfront,
+	wire.Bind(new(storageRepository.Repository), new(*storageCloudfront.StorageCloudfront)),
+)
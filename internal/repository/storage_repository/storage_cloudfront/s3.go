@@ -0,0 +1,163 @@
+package storageCloudfront
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/utils"
+)
+
+const (
+	s3RequestTimeout = 30 * time.Second
+	s3DateFormat     = "20060102T150405Z"
+)
+
+// s3Client puts objects with a Signature Version 4 signed request.
+type s3Client struct {
+	endpoint  string // https://<bucket>.s3.<region>.amazonaws.com
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func newS3Client(env *godotenv.Env) s3Client {
+	c := s3Client{
+		region:    env.AWSRegion,
+		accessKey: env.AWSAccessKeyID,
+		secretKey: env.AWSSecretAccessKey,
+		client:    &http.Client{Timeout: s3RequestTimeout},
+		now:       time.Now,
+	}
+	if env.AWSS3Bucket != "" && env.AWSRegion != "" {
+		c.endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", env.AWSS3Bucket, env.AWSRegion)
+	}
+	return c
+}
+
+func (s *StorageCloudfront) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	lg := s.logger.With("method", "Put", "key", key)
+
+	url, err := s.s3.put(ctx, key, contentType, data)
+	if err != nil {
+		lg.Error("Put failed", "err", err)
+		return "", utils.WrapError("put object", err)
+	}
+	return url, nil
+}
+
+func (c s3Client) put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	if c.endpoint == "" || c.accessKey == "" || c.secretKey == "" {
+		return "", errors.New("s3 bucket, region and credentials are required")
+	}
+
+	path := "/" + escapePath(strings.TrimPrefix(key, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return "", utils.WrapError("build s3 request", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	c.sign(req, path, sha256Hex(data))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", utils.WrapError("call s3", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<10))
+		return "", fmt.Errorf("s3 answered with status %d: %s", res.StatusCode, body)
+	}
+	return c.endpoint + path, nil
+}
+
+// sign adds the Signature Version 4 headers for a request to the escaped
+// path whose payload hashes to payloadHash. Only the headers set so far
+// are signed.
+func (c s3Client) sign(req *http.Request, path, payloadHash string) {
+	now := c.now().UTC()
+	stamp, day := now.Format(s3DateFormat), now.Format("20060102")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := []string{"host"}
+	values := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.TrimSpace(req.Header.Get(name))
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+	signature := hex.EncodeToString(hmacSHA256(signingKey(c.secretKey, day, c.region, "s3"), toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the Signature Version 4 key for one day, region and
+// service.
+func signingKey(secret, day, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// escapePath percent-encodes every byte of an object key but the RFC 3986
+// unreserved characters and the slashes between segments, the way
+// Signature Version 4 expects the path.
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~', ch == '/':
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
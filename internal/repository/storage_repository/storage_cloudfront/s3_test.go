@@ -0,0 +1,97 @@
+package storageCloudfront
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+)
+
+// TestSigningKey checks the key derivation against the example in the AWS
+// Signature Version 4 documentation.
+func TestSigningKey(t *testing.T) {
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := map[string]string{
+		"inputs/photo.jpg":    "inputs/photo.jpg",
+		"inputs/my photo.jpg": "inputs/my%20photo.jpg",
+		"test$file.text":      "test%24file.text",
+		"a+b=c&d@e:f/~_-.":    "a%2Bb%3Dc%26d%40e%3Af/~_-.",
+		"café/ü.png":          "caf%C3%A9/%C3%BC.png",
+	}
+	for key, want := range tests {
+		if got := escapePath(key); got != want {
+			t.Errorf("escapePath(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPut(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(srv.Close)
+
+	s := NewStorageCloudfront(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{
+		AWSAccessKeyID:     "AKID",
+		AWSSecretAccessKey: "secret",
+		AWSRegion:          "eu-west-1",
+		AWSS3Bucket:        "uploads",
+	})
+	s.s3.endpoint = srv.URL
+	s.s3.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	url, err := s.Put(context.Background(), "inputs/a b.jpg", "image/jpeg", []byte("jpeg"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if url != srv.URL+"/inputs/a%20b.jpg" {
+		t.Errorf("url = %s", url)
+	}
+	if got.Method != http.MethodPut || got.URL.EscapedPath() != "/inputs/a%20b.jpg" || string(body) != "jpeg" {
+		t.Errorf("request = %s %s %q", got.Method, got.URL.EscapedPath(), body)
+	}
+	if got.Header.Get("X-Amz-Date") != "20240501T120000Z" || got.Header.Get("X-Amz-Content-Sha256") != sha256Hex([]byte("jpeg")) {
+		t.Errorf("amz headers = %v", got.Header)
+	}
+	auth := got.Header.Get("Authorization")
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20240501/eu-west-1/s3/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, wantPrefix) || len(auth) != len(wantPrefix)+64 {
+		t.Errorf("Authorization = %s", auth)
+	}
+}
+
+func TestPutErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := NewStorageCloudfront(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{})
+	if _, err := s.Put(context.Background(), "a.jpg", "image/jpeg", nil); err == nil {
+		t.Error("Put succeeded without a bucket")
+	}
+
+	s.s3.endpoint, s.s3.accessKey, s.s3.secretKey = srv.URL, "AKID", "secret"
+	if _, err := s.Put(context.Background(), "a.jpg", "image/jpeg", nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put error = %v, want the 403", err)
+	}
+}
@@ -0,0 +1,114 @@
+package storageCloudfront
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/utils"
+)
+
+// signatureEncoding is base64 with the characters CloudFront does not
+// accept in query strings swapped out.
+var signatureEncoding = strings.NewReplacer("+", "-", "=", "_", "/", "~")
+
+// StorageCloudfront writes objects to the S3 bucket behind the CloudFront
+// distribution and signs CloudFront URLs with a canned policy. Both need
+// nothing but the credentials, so no AWS SDK is involved.
+type StorageCloudfront struct {
+	logger    *slog.Logger
+	domain    string
+	keyPairID string
+	key       *rsa.PrivateKey
+	// keyErr is why key could not be loaded, returned on every use so a
+	// misconfigured signer does not stop the service from starting
+	keyErr error
+
+	s3 s3Client
+}
+
+func NewStorageCloudfront(
+	logger *slog.Logger,
+	env *godotenv.Env,
+) *StorageCloudfront {
+	s := &StorageCloudfront{
+		logger:    logger.With("layer", "StorageRepository"),
+		domain:    env.AWSCFDomain,
+		keyPairID: env.AWSCFKeyPairID,
+		s3:        newS3Client(env),
+	}
+	s.key, s.keyErr = loadKey(env.AWSCFPrivateKeyPath)
+	if s.keyErr != nil {
+		s.logger.Warn("cloudfront signing key unavailable", "err", s.keyErr)
+	}
+	return s
+}
+
+func (s *StorageCloudfront) SignedURL(key string, expires time.Time) (string, error) {
+	if s.keyErr != nil {
+		return "", s.keyErr
+	}
+	if s.domain == "" || s.keyPairID == "" {
+		return "", errors.New("cloudfront domain and key pair id are required")
+	}
+
+	resource := (&url.URL{Scheme: "https", Host: s.domain, Path: "/" + strings.TrimPrefix(key, "/")}).String()
+	policy := fmt.Sprintf(`{"Statement":[{"Resource":%q,"Condition":{"DateLessThan":{"AWS:EpochTime":%d}}}]}`,
+		resource, expires.Unix())
+
+	digest := sha1.Sum([]byte(policy))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", utils.WrapError("sign cloudfront url", err)
+	}
+
+	query := url.Values{}
+	query.Set("Expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("Signature", signatureEncoding.Replace(base64.StdEncoding.EncodeToString(signature)))
+	query.Set("Key-Pair-Id", s.keyPairID)
+	return resource + "?" + query.Encode(), nil
+}
+
+// loadKey reads a PEM encoded RSA key in PKCS#1 or PKCS#8 form.
+func loadKey(path string) (*rsa.PrivateKey, error) {
+	if path == "" {
+		return nil, errors.New("cloudfront private key path is not configured")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, utils.WrapError("read cloudfront private key", err)
+	}
+	return parseKey(raw)
+}
+
+func parseKey(raw []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("cloudfront private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, utils.WrapError("parse cloudfront private key", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("cloudfront private key is not an RSA key")
+	}
+	return key, nil
+}
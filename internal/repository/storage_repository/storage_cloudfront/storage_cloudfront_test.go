@@ -0,0 +1,79 @@
+package storageCloudfront
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	storageRepository "github.com/playture/backend/internal/repository/storage_repository"
+)
+
+var _ storageRepository.Repository = (*StorageCloudfront)(nil)
+
+func TestSignedURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cf.pem")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, pemKey, 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	s := NewStorageCloudfront(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{
+		AWSCFDomain:         "cdn.example.com",
+		AWSCFKeyPairID:      "KPID",
+		AWSCFPrivateKeyPath: path,
+	})
+	expires := time.Unix(1_700_000_000, 0)
+	signed, err := s.SignedURL("renders/final clip.mp4", expires)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse %q: %v", signed, err)
+	}
+	resource := "https://cdn.example.com/renders/final%20clip.mp4"
+	if base := strings.SplitN(signed, "?", 2)[0]; base != resource {
+		t.Errorf("resource = %s, want %s", base, resource)
+	}
+	q := u.Query()
+	if q.Get("Expires") != "1700000000" || q.Get("Key-Pair-Id") != "KPID" {
+		t.Errorf("query = %v", q)
+	}
+
+	raw := strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(q.Get("Signature"))
+	signature, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	policy := fmt.Sprintf(`{"Statement":[{"Resource":%q,"Condition":{"DateLessThan":{"AWS:EpochTime":1700000000}}}]}`, resource)
+	digest := sha1.Sum([]byte(policy))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, digest[:], signature); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestSignedURLWithoutKey(t *testing.T) {
+	s := NewStorageCloudfront(slog.New(slog.NewTextHandler(io.Discard, nil)), &godotenv.Env{AWSCFDomain: "cdn.example.com"})
+	if _, err := s.SignedURL("a.mp4", time.Now()); err == nil {
+		t.Error("SignedURL succeeded without a key")
+	}
+}
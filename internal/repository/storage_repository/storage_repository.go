@@ -0,0 +1,15 @@
+package storageRepository
+
+import (
+	"context"
+	"time"
+)
+
+// Repository stores objects and hands out access to them.
+type Repository interface {
+	// Put stores data at key and returns the URL of the object, which is
+	// not publicly readable.
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+	// SignedURL returns a URL that serves the object at key until expires.
+	SignedURL(key string, expires time.Time) (string, error)
+}
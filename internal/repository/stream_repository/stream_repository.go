@@ -0,0 +1,10 @@
+package streamRepository
+
+import (
+	"context"
+)
+
+type Repository interface {
+	// Publish appends an entry to a stream and returns its id.
+	Publish(ctx context.Context, stream string, fields map[string]string) (string, error)
+}
@@ -0,0 +1,50 @@
+package streamRueidis
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/playture/backend/internal/infrastructure/redis"
+	"github.com/playture/backend/utils"
+)
+
+// streams are capped, consumers are expected to keep up well within this
+const maxStreamLength = 100_000
+
+type StreamRueidis struct {
+	logger *slog.Logger
+	redis  *redis.Redis
+}
+
+func NewStreamRueidis(
+	logger *slog.Logger,
+	redis *redis.Redis,
+) *StreamRueidis {
+	return &StreamRueidis{
+		logger: logger.With("layer", "StreamRepository"),
+		redis:  redis,
+	}
+}
+
+func (s *StreamRueidis) Publish(ctx context.Context, stream string, fields map[string]string) (string, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	client := s.redis.Client
+	cmd := client.B().Xadd().Key(stream).Maxlen().Almost().Threshold(strconv.Itoa(maxStreamLength)).Id("*").FieldValue()
+	for _, k := range keys {
+		cmd = cmd.FieldValue(k, fields[k])
+	}
+
+	id, err := client.Do(ctx, cmd.Build()).ToString()
+	if err != nil {
+		s.logger.Error("Publish failed", "method", "Publish", "stream", stream, "err", err)
+		return "", utils.WrapError("publish to stream", err)
+	}
+	return id, nil
+}
@@ -2,16 +2,71 @@ package uow
 
 import (
 	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/playture/backend/internal/infrastructure/postgresql"
 	"github.com/playture/backend/utils"
-	"time"
 )
 
-type TransactionFN func(ctx context.Context, tx pgx.Tx) (interface{}, error)
+const (
+	defaultMaxRetries = 3
+	retryBaseDelay    = 20 * time.Millisecond
+)
+
+// SQLSTATEs after which the whole transaction can simply be run again
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// TransactionFN runs with the transaction stored in ctx, repositories pick
+// it up from there.
+type TransactionFN func(ctx context.Context) (interface{}, error)
 
 type IUOW interface {
-	Do(ctx context.Context, fn TransactionFN, timeout time.Duration) (interface{}, error)
+	Do(ctx context.Context, fn TransactionFN, timeout time.Duration, opts ...Option) (interface{}, error)
+}
+
+type options struct {
+	txOptions  pgx.TxOptions
+	maxRetries int
+}
+
+type Option func(*options)
+
+// Serializable runs the transaction with SERIALIZABLE isolation.
+func Serializable() Option {
+	return func(o *options) {
+		o.txOptions.IsoLevel = pgx.Serializable
+	}
+}
+
+// RepeatableRead runs the transaction with REPEATABLE READ isolation.
+func RepeatableRead() Option {
+	return func(o *options) {
+		o.txOptions.IsoLevel = pgx.RepeatableRead
+	}
+}
+
+// ReadOnly starts a READ ONLY transaction. It runs on the replica when one
+// is configured and ctx was not marked with postgresql.WithPrimary, unless
+// it is also Serializable, which a standby cannot run.
+func ReadOnly() Option {
+	return func(o *options) {
+		o.txOptions.AccessMode = pgx.ReadOnly
+	}
+}
+
+// MaxRetries sets how often fn is run again after a serialization failure or
+// a deadlock, 0 disables retrying.
+func MaxRetries(n int) Option {
+	return func(o *options) {
+		o.maxRetries = n
+	}
 }
 
 type UOW struct {
@@ -23,23 +78,58 @@ func NewUOW(conn *postgresql.Postgres) IUOW {
 		pg: conn,
 	}
 }
-func (uow *UOW) Do(ctx context.Context, fn TransactionFN, timeout time.Duration) (interface{}, error) {
+
+// Do runs fn in a transaction. When ctx already carries a transaction from
+// an outer Do, fn runs in a savepoint of it instead: a failing fn only rolls
+// back its own work, and options and retries are left to the outer call.
+func (uow *UOW) Do(ctx context.Context, fn TransactionFN, timeout time.Duration, opts ...Option) (interface{}, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	conn, err := uow.pg.PrimaryConn.Acquire(ctx)
+	if outer, ok := postgresql.TxFromContext(ctx); ok {
+		return uow.savepoint(ctx, outer, fn)
+	}
+
+	o := options{maxRetries: defaultMaxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := uow.run(ctx, fn, o.txOptions)
+		if err == nil || attempt >= o.maxRetries || !isRetryable(err) {
+			return result, err
+		}
+
+		// exponential backoff with jitter so competing transactions spread out
+		delay := retryBaseDelay << attempt
+		delay += rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return nil, utils.WrapError("transaction retry aborted", ctx.Err(), err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (uow *UOW) run(ctx context.Context, fn TransactionFN, txOptions pgx.TxOptions) (interface{}, error) {
+	pool := uow.pg.PrimaryConn
+	if txOptions.AccessMode == pgx.ReadOnly && txOptions.IsoLevel != pgx.Serializable {
+		pool = uow.pg.ReadPool(ctx)
+	}
+	conn, err := pool.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer conn.Release()
 
-	tx, err := conn.Begin(ctx)
+	tx, err := conn.BeginTx(ctx, txOptions)
 	if err != nil {
 		return nil, err
 	}
 
 	var result interface{}
-	result, err = fn(ctx, tx)
+	result, err = fn(postgresql.WithTx(ctx, tx))
 	if err != nil {
 		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
 			return nil, utils.WrapError("transaction rollback failed", rollbackErr, err)
@@ -53,3 +143,45 @@ func (uow *UOW) Do(ctx context.Context, fn TransactionFN, timeout time.Duration)
 
 	return result, nil
 }
+
+func (uow *UOW) savepoint(ctx context.Context, outer pgx.Tx, fn TransactionFN) (interface{}, error) {
+	sp, err := outer.Begin(ctx)
+	if err != nil {
+		return nil, utils.WrapError("create savepoint", err)
+	}
+
+	result, err := fn(postgresql.WithTx(ctx, sp))
+	if err != nil {
+		if rollbackErr := sp.Rollback(ctx); rollbackErr != nil {
+			return nil, utils.WrapError("savepoint rollback failed", rollbackErr, err)
+		}
+		return nil, err
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return nil, utils.WrapError("savepoint release failed", err)
+	}
+	return result, nil
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
+
+// Do is the typed form of IUOW.Do, it saves callers the type assertion on
+// the result.
+func Do[T any](ctx context.Context, u IUOW, fn func(ctx context.Context) (T, error), timeout time.Duration, opts ...Option) (T, error) {
+	res, err := u.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	}, timeout, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	v, _ := res.(T)
+	return v, nil
+}
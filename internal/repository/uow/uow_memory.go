@@ -0,0 +1,68 @@
+package uow
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+)
+
+// Snapshotter is a store the in-memory unit of work can roll back. Snapshot
+// captures the current state and returns a function restoring it.
+type Snapshotter interface {
+	Snapshot() (restore func())
+}
+
+// memoryTx marks a context as being inside a MemoryUOW transaction, so code
+// checking postgresql.TxFromContext behaves as it does with Postgres. The
+// in-memory repositories never call it, any call panics.
+type memoryTx struct {
+	pgx.Tx
+}
+
+// MemoryUOW is an IUOW for tests running against in-memory repositories. A
+// transaction snapshots every store and restores them when fn fails. Outer
+// transactions are serialized so a rollback never discards the writes of a
+// concurrent one, nested calls roll back only their own writes like a
+// savepoint. Writes made outside any transaction while one is open are
+// rolled back with it. Options are accepted and ignored.
+type MemoryUOW struct {
+	mu     sync.Mutex
+	stores []Snapshotter
+}
+
+func NewMemoryUOW(stores ...Snapshotter) *MemoryUOW {
+	return &MemoryUOW{
+		stores: stores,
+	}
+}
+
+func (m *MemoryUOW) Do(ctx context.Context, fn TransactionFN, timeout time.Duration, _ ...Option) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, ok := postgresql.TxFromContext(ctx); !ok {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		ctx = postgresql.WithTx(ctx, memoryTx{})
+	}
+
+	restores := make([]func(), len(m.stores))
+	for i, store := range m.stores {
+		restores[i] = store.Snapshot()
+	}
+
+	result, err := fn(ctx)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return nil, err
+	}
+	return result, nil
+}
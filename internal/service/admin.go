@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/utils"
+)
+
+var (
+	ErrInvalidFilter     = errors.New("invalid filter")
+	ErrJobNotRetryable   = errors.New("only failed or cancelled jobs can be retried")
+	ErrJobNotCancellable = errors.New("job already finished")
+	ErrJobHasOrder       = errors.New("job has an order attached")
+)
+
+const adminTimeout = 10 * time.Second
+
+type Admin interface {
+	ListJobs(ctx context.Context, req dto.AdminListJobsReq) (*pagination.Page[*entity.Job], error)
+	ListOrders(ctx context.Context, req dto.AdminListOrdersReq) (*pagination.Page[*entity.Order], error)
+	GetJob(ctx context.Context, id string) (*dto.AdminJobDetail, error)
+	RetryJob(ctx context.Context, id string) (*entity.Job, error)
+	CancelJob(ctx context.Context, id string) (*entity.Job, error)
+	DeleteJob(ctx context.Context, id string) error
+	UpdateOrderNotes(ctx context.Context, id string, req dto.AdminUpdateOrderNotesReq) (*entity.Order, error)
+}
+
+type admin struct {
+	logger    *slog.Logger
+	uow       uow.IUOW
+	audit     Audit
+	timeline  Timeline
+	jobRepo   jobRepository.Repository
+	orderRepo orderRepository.Repository
+}
+
+func NewAdmin(logger *slog.Logger,
+	uow uow.IUOW,
+	audit Audit,
+	timeline Timeline,
+	jobRepo jobRepository.Repository,
+	orderRepo orderRepository.Repository,
+) Admin {
+	return &admin{
+		logger:    logger.With("layer", "AdminService"),
+		uow:       uow,
+		audit:     audit,
+		timeline:  timeline,
+		jobRepo:   jobRepo,
+		orderRepo: orderRepo,
+	}
+}
+
+func (a *admin) ListJobs(ctx context.Context, req dto.AdminListJobsReq) (*pagination.Page[*entity.Job], error) {
+	filter := jobRepository.Filter{
+		UserEmail:   req.Email,
+		Style:       req.Style,
+		CreatedFrom: req.From,
+		CreatedTo:   req.To,
+	}
+	for _, s := range req.Statuses {
+		st, ok := entity.ParseJobStatus(s)
+		if !ok {
+			return nil, utils.WrapError("unknown job status "+s, ErrInvalidFilter)
+		}
+		filter.Statuses = append(filter.Statuses, st)
+	}
+
+	return a.jobRepo.List(ctx, filter, pagination.Request{Cursor: req.Cursor, Limit: req.Limit})
+}
+
+func (a *admin) ListOrders(ctx context.Context, req dto.AdminListOrdersReq) (*pagination.Page[*entity.Order], error) {
+	var (
+		paymentStatus    *entity.PaymentStatus
+		productionStatus *entity.ProductionStatus
+	)
+	if req.PaymentStatus != "" {
+		st, ok := entity.ParsePaymentStatus(req.PaymentStatus)
+		if !ok {
+			return nil, utils.WrapError("unknown payment status "+req.PaymentStatus, ErrInvalidFilter)
+		}
+		paymentStatus = &st
+	}
+	if req.ProductionStatus != "" {
+		st, ok := entity.ParseProductionStatus(req.ProductionStatus)
+		if !ok {
+			return nil, utils.WrapError("unknown production status "+req.ProductionStatus, ErrInvalidFilter)
+		}
+		productionStatus = &st
+	}
+
+	return uow.Do(ctx, a.uow, func(ctx context.Context) (*pagination.Page[*entity.Order], error) {
+		return a.orderRepo.List(ctx, paymentStatus, productionStatus, pagination.Request{Cursor: req.Cursor, Limit: req.Limit})
+	}, adminTimeout, uow.ReadOnly())
+}
+
+func (a *admin) GetJob(ctx context.Context, id string) (*dto.AdminJobDetail, error) {
+	return uow.Do(ctx, a.uow, func(ctx context.Context) (*dto.AdminJobDetail, error) {
+		job, err := a.jobRepo.FindByField(ctx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		detail := &dto.AdminJobDetail{Job: job}
+
+		order, err := a.orderRepo.FindByField(ctx, "job_id", id)
+		switch {
+		case err == nil:
+			detail.Order = order
+		case !errors.Is(err, orderRepository.ErrOrderNotFound):
+			return nil, err
+		}
+		return detail, nil
+	}, adminTimeout, uow.ReadOnly())
+}
+
+// RetryJob puts a failed or cancelled job back at the start of the pipeline.
+func (a *admin) RetryJob(ctx context.Context, id string) (*entity.Job, error) {
+	lg := a.logger.With("method", "RetryJob", "jobID", id)
+
+	job, err := uow.Do(ctx, a.uow, func(ctx context.Context) (*entity.Job, error) {
+		job, err := a.jobRepo.FindByField(ctx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != entity.JobStatusFailed && job.Status != entity.JobStatusCancelled {
+			return nil, ErrJobNotRetryable
+		}
+		before := *job
+
+		job.ErrorMessage, job.ErrorStack = "", ""
+		job.RetryCount++
+		job.StartedAt, job.CompletedAt, job.TotalProcessingTime = 0, 0, 0
+
+		if err := a.timeline.Transition(ctx, job, entity.JobStatusReceived, "retried by admin"); err != nil {
+			return nil, err
+		}
+		if err := a.audit.Record(ctx, entity.AuditActionJobRetry, entity.AuditEntityJob, id, &before, job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("retry failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("job queued for retry")
+	return job, nil
+}
+
+func (a *admin) CancelJob(ctx context.Context, id string) (*entity.Job, error) {
+	lg := a.logger.With("method", "CancelJob", "jobID", id)
+
+	job, err := uow.Do(ctx, a.uow, func(ctx context.Context) (*entity.Job, error) {
+		job, err := a.jobRepo.FindByField(ctx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status.IsTerminal() {
+			return nil, ErrJobNotCancellable
+		}
+		before := *job
+
+		if err := a.timeline.Transition(ctx, job, entity.JobStatusCancelled, "cancelled by admin"); err != nil {
+			return nil, err
+		}
+		if err := a.audit.Record(ctx, entity.AuditActionJobCancel, entity.AuditEntityJob, id, &before, job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("cancel failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("job cancelled")
+	return job, nil
+}
+
+// DeleteJob removes a job. Jobs that were converted to an order are kept,
+// the order references them.
+func (a *admin) DeleteJob(ctx context.Context, id string) error {
+	lg := a.logger.With("method", "DeleteJob", "jobID", id)
+
+	_, err := a.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		job, err := a.jobRepo.FindByField(ctx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		if job.ConvertedToOrder {
+			return nil, ErrJobHasOrder
+		}
+		_, err = a.orderRepo.FindByField(ctx, "job_id", id)
+		switch {
+		case err == nil:
+			return nil, ErrJobHasOrder
+		case !errors.Is(err, orderRepository.ErrOrderNotFound):
+			return nil, err
+		}
+
+		if err := a.jobRepo.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, a.audit.Record(ctx, entity.AuditActionJobDelete, entity.AuditEntityJob, id, job, nil)
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("delete failed", "err", err)
+		return err
+	}
+
+	lg.Info("job deleted")
+	return nil
+}
+
+func (a *admin) UpdateOrderNotes(ctx context.Context, id string, req dto.AdminUpdateOrderNotesReq) (*entity.Order, error) {
+	lg := a.logger.With("method", "UpdateOrderNotes", "orderID", id)
+
+	order, err := uow.Do(ctx, a.uow, func(ctx context.Context) (*entity.Order, error) {
+		order, err := a.orderRepo.FindByField(ctx, "id", id)
+		if err != nil {
+			return nil, err
+		}
+		before := *order
+
+		order.CustomerNotes = req.CustomerNotes
+
+		if err := a.orderRepo.UpdateCustomerNotes(ctx, order); err != nil {
+			return nil, err
+		}
+		if err := a.audit.Record(ctx, entity.AuditActionOrderNotesUpdate, entity.AuditEntityOrder, id, &before, order); err != nil {
+			return nil, err
+		}
+		return order, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("update notes failed", "err", err)
+		return nil, err
+	}
+
+	return order, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	auditRepository "github.com/playture/backend/internal/repository/audit_repository"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/utils"
+)
+
+type actorContextKey struct{}
+
+// systemActor is recorded when a privileged action runs without an admin
+// behind it, e.g. from a scheduled job.
+var systemActor = entity.AuditActor{Name: "system"}
+
+// WithActor attaches the admin performing the request to ctx.
+func WithActor(ctx context.Context, actor entity.AuditActor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+func ActorFrom(ctx context.Context) entity.AuditActor {
+	if actor, ok := ctx.Value(actorContextKey{}).(entity.AuditActor); ok {
+		return actor
+	}
+	return systemActor
+}
+
+// ignoredAuditFields change on every write and would only add noise.
+var ignoredAuditFields = map[string]bool{
+	"updatedAt": true,
+}
+
+type Audit interface {
+	// Record must be called with the ctx of the transaction that applies the
+	// change, so the event is committed or rolled back together with it.
+	Record(ctx context.Context, action entity.AuditAction, entityType entity.AuditEntityType, entityID string, before, after interface{}) error
+	List(ctx context.Context, req dto.AdminListAuditReq) (*pagination.Page[*entity.AuditEvent], error)
+}
+
+type audit struct {
+	logger    *slog.Logger
+	auditRepo auditRepository.Repository
+}
+
+func NewAudit(logger *slog.Logger,
+	auditRepo auditRepository.Repository,
+) Audit {
+	return &audit{
+		logger:    logger.With("layer", "AuditService"),
+		auditRepo: auditRepo,
+	}
+}
+
+func (a *audit) Record(
+	ctx context.Context,
+	action entity.AuditAction,
+	entityType entity.AuditEntityType,
+	entityID string,
+	before, after interface{},
+) error {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		return utils.WrapError("diff audit fields", err)
+	}
+
+	actor := ActorFrom(ctx)
+	event := &entity.AuditEvent{
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		IPAddress:  actor.IPAddress,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+		CreatedAt:  time.Now().Unix(),
+	}
+	if _, err := a.auditRepo.Create(ctx, event); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *audit) List(ctx context.Context, req dto.AdminListAuditReq) (*pagination.Page[*entity.AuditEvent], error) {
+	filter := auditRepository.Filter{
+		EntityType: entity.AuditEntityType(req.EntityType),
+		EntityID:   req.EntityID,
+		ActorID:    req.ActorID,
+	}
+	if req.ActorID != "" {
+		if _, err := uuid.Parse(req.ActorID); err != nil {
+			return nil, utils.WrapError("actorId is not a uuid", ErrInvalidFilter)
+		}
+	}
+
+	return a.auditRepo.List(ctx, filter, pagination.Request{Cursor: req.Cursor, Limit: req.Limit})
+}
+
+// diffFields compares the JSON form of two snapshots of an entity and
+// returns every field whose value differs. Either side may be nil, for
+// creations and deletions.
+func diffFields(before, after interface{}) (map[string]entity.FieldChange, error) {
+	b, err := toFieldMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toFieldMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]entity.FieldChange{}
+	for k, bv := range b {
+		if ignoredAuditFields[k] {
+			continue
+		}
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(av, bv) {
+			changes[k] = entity.FieldChange{Before: bv, After: av}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; ok || ignoredAuditFields[k] {
+			continue
+		}
+		changes[k] = entity.FieldChange{After: av}
+	}
+	return changes, nil
+}
+
+func toFieldMap(v interface{}) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return m, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
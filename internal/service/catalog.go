@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/utils"
+)
+
+var (
+	ErrInvalidProduct = errors.New("invalid product")
+	ErrNotForSale     = errors.New("not for sale")
+)
+
+type Catalog interface {
+	ListProducts(ctx context.Context, req dto.AdminListProductsReq) ([]*entity.Product, error)
+	GetProduct(ctx context.Context, id string) (*entity.Product, error)
+	CreateProduct(ctx context.Context, req dto.AdminProductReq) (*entity.Product, error)
+	UpdateProduct(ctx context.Context, id string, req dto.AdminProductReq) (*entity.Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	// Quote resolves orderType to its active product and that product's
+	// price in currency. Call it with the ctx of the transaction creating
+	// the order so the price read is the one committed with it.
+	Quote(ctx context.Context, orderType entity.OrderType, currency string) (*entity.Product, entity.ProductPrice, error)
+}
+
+type catalog struct {
+	logger      *slog.Logger
+	uow         uow.IUOW
+	audit       Audit
+	productRepo productRepository.Repository
+}
+
+func NewCatalog(logger *slog.Logger,
+	uow uow.IUOW,
+	audit Audit,
+	productRepo productRepository.Repository,
+) Catalog {
+	return &catalog{
+		logger:      logger.With("layer", "CatalogService"),
+		uow:         uow,
+		audit:       audit,
+		productRepo: productRepo,
+	}
+}
+
+func (c *catalog) ListProducts(ctx context.Context, req dto.AdminListProductsReq) ([]*entity.Product, error) {
+	return c.productRepo.List(ctx, req.IncludeInactive)
+}
+
+func (c *catalog) GetProduct(ctx context.Context, id string) (*entity.Product, error) {
+	return c.productRepo.FindByID(ctx, id)
+}
+
+func (c *catalog) CreateProduct(ctx context.Context, req dto.AdminProductReq) (*entity.Product, error) {
+	lg := c.logger.With("method", "CreateProduct")
+
+	now := time.Now().Unix()
+	product := &entity.Product{CreatedAt: now}
+	if err := applyProductReq(product, req, now); err != nil {
+		return nil, err
+	}
+
+	product, err := uow.Do(ctx, c.uow, func(ctx context.Context) (*entity.Product, error) {
+		id, err := c.productRepo.Create(ctx, product)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.audit.Record(ctx, entity.AuditActionProductCreate, entity.AuditEntityProduct, id, nil, product); err != nil {
+			return nil, err
+		}
+		return product, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("create failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("product created", "productID", product.ID, "orderType", product.OrderType)
+	return product, nil
+}
+
+func (c *catalog) UpdateProduct(ctx context.Context, id string, req dto.AdminProductReq) (*entity.Product, error) {
+	lg := c.logger.With("method", "UpdateProduct", "productID", id)
+
+	product, err := uow.Do(ctx, c.uow, func(ctx context.Context) (*entity.Product, error) {
+		product, err := c.productRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		before := *product
+
+		if err := applyProductReq(product, req, time.Now().Unix()); err != nil {
+			return nil, err
+		}
+		if err := c.productRepo.Update(ctx, product); err != nil {
+			return nil, err
+		}
+		if err := c.audit.Record(ctx, entity.AuditActionProductUpdate, entity.AuditEntityProduct, id, &before, product); err != nil {
+			return nil, err
+		}
+		return product, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("update failed", "err", err)
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// DeleteProduct removes a product and its prices. Orders keep the amount
+// they were priced at, deactivating is the usual way to stop selling one.
+func (c *catalog) DeleteProduct(ctx context.Context, id string) error {
+	lg := c.logger.With("method", "DeleteProduct", "productID", id)
+
+	_, err := c.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		product, err := c.productRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.productRepo.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, c.audit.Record(ctx, entity.AuditActionProductDelete, entity.AuditEntityProduct, id, product, nil)
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("delete failed", "err", err)
+		return err
+	}
+
+	lg.Info("product deleted")
+	return nil
+}
+
+func (c *catalog) Quote(ctx context.Context, orderType entity.OrderType, currency string) (*entity.Product, entity.ProductPrice, error) {
+	product, err := c.productRepo.FindActive(ctx, orderType)
+	if err != nil {
+		if errors.Is(err, productRepository.ErrProductNotFound) {
+			return nil, entity.ProductPrice{}, utils.WrapError("order type "+orderType.String(), ErrNotForSale)
+		}
+		return nil, entity.ProductPrice{}, err
+	}
+	price, ok := product.PriceIn(currency)
+	if !ok {
+		return nil, entity.ProductPrice{}, utils.WrapError(fmt.Sprintf("%s in %s", orderType, currency), ErrNotForSale)
+	}
+	return product, price, nil
+}
+
+// applyProductReq validates req and copies it onto product.
+func applyProductReq(product *entity.Product, req dto.AdminProductReq, now int64) error {
+	switch {
+	case req.OrderType < entity.OrderTypeBasic || req.OrderType > entity.OrderTypeCustom:
+		return utils.WrapError("unknown order type", ErrInvalidProduct)
+	case req.Name == "":
+		return utils.WrapError("name is required", ErrInvalidProduct)
+	case req.VideoWidth <= 0 || req.VideoHeight <= 0 || req.MaxDurationSeconds <= 0:
+		return utils.WrapError("video size and duration must be positive", ErrInvalidProduct)
+	case len(req.Prices) == 0:
+		return utils.WrapError("at least one price is required", ErrInvalidProduct)
+	}
+
+	prices := make([]entity.ProductPrice, 0, len(req.Prices))
+	seen := map[string]bool{}
+	for _, p := range req.Prices {
+		price, err := entity.ParseMoney(p.Amount, p.Currency)
+		if err != nil {
+			return utils.WrapError(err.Error(), ErrInvalidProduct)
+		}
+		if price.Minor < 0 {
+			return utils.WrapError("price must not be negative", ErrInvalidProduct)
+		}
+		if seen[price.Currency] {
+			return utils.WrapError("more than one price in "+price.Currency, ErrInvalidProduct)
+		}
+		seen[price.Currency] = true
+		prices = append(prices, entity.ProductPrice{Price: price})
+	}
+
+	product.OrderType = req.OrderType
+	product.Name = req.Name
+	product.VideoWidth, product.VideoHeight = req.VideoWidth, req.VideoHeight
+	product.MaxDurationSeconds = req.MaxDurationSeconds
+	product.WatermarkFree = req.WatermarkFree
+	product.Active = req.Active
+	product.Prices = prices
+	product.UpdatedAt = now
+	return nil
+}
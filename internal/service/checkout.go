@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+var (
+	ErrJobNotConvertible     = errors.New("only completed jobs can be ordered")
+	ErrInvalidDeliveryMethod = errors.New("deliveryMethod must be 1 (download), 2 (email) or 3 (both)")
+)
+
+const (
+	checkoutTimeout = 10 * time.Second
+	defaultCurrency = "USD"
+	// defaultPaymentWindow is how long an order may stay unpaid before the
+	// sweeper expires it
+	defaultPaymentWindow = 24 * time.Hour
+)
+
+type Checkout interface {
+	// CreateOrder turns a completed sample job into a pending order priced
+	// from the catalog, less the discount of req.PromoCode when one is given.
+	// A job has at most one open order, it can be ordered again once its
+	// order expired unpaid or was refunded in full.
+	CreateOrder(ctx context.Context, req dto.CreateOrderReq) (*entity.Order, error)
+}
+
+type checkout struct {
+	logger        *slog.Logger
+	uow           uow.IUOW
+	paymentWindow time.Duration
+	catalog       Catalog
+	promotions    Promotions
+	jobRepo       jobRepository.Repository
+	orderRepo     orderRepository.Repository
+}
+
+func NewCheckout(logger *slog.Logger,
+	env *godotenv.Env,
+	uow uow.IUOW,
+	catalog Catalog,
+	promotions Promotions,
+	jobRepo jobRepository.Repository,
+	orderRepo orderRepository.Repository,
+) Checkout {
+	c := &checkout{
+		logger:        logger.With("layer", "CheckoutService"),
+		uow:           uow,
+		paymentWindow: defaultPaymentWindow,
+		catalog:       catalog,
+		promotions:    promotions,
+		jobRepo:       jobRepo,
+		orderRepo:     orderRepo,
+	}
+	if v, err := strconv.Atoi(env.OrderPaymentWindowMinutes); err == nil && v > 0 {
+		c.paymentWindow = time.Duration(v) * time.Minute
+	}
+	return c
+}
+
+func (c *checkout) CreateOrder(ctx context.Context, req dto.CreateOrderReq) (*entity.Order, error) {
+	lg := c.logger.With("method", "CreateOrder", "jobID", req.JobID)
+
+	currency := req.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	method := req.DeliveryMethod
+	switch method {
+	case 0:
+		method = entity.DeliveryMethodDownload
+	case entity.DeliveryMethodDownload, entity.DeliveryMethodEmail, entity.DeliveryMethodBoth:
+	default:
+		return nil, ErrInvalidDeliveryMethod
+	}
+
+	order, err := uow.Do(ctx, c.uow, func(ctx context.Context) (*entity.Order, error) {
+		job, err := c.jobRepo.FindByField(ctx, "id", req.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != entity.JobStatusCompleted {
+			return nil, ErrJobNotConvertible
+		}
+		if job.ConvertedToOrder {
+			return nil, ErrJobHasOrder
+		}
+
+		_, price, err := c.catalog.Quote(ctx, req.OrderType, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		created := time.Now()
+		now := created.Unix()
+		order := &entity.Order{
+			ID:               uuid.New(),
+			JobID:            job.ID,
+			UserEmail:        job.UserEmail,
+			UserName:         job.UserName,
+			Amount:           price.Price,
+			Discount:         entity.Money{Currency: price.Price.Currency},
+			PaymentStatus:    entity.PaymentStatusPending,
+			OrderType:        req.OrderType,
+			Requirements:     req.Requirements,
+			ProductionStatus: entity.ProductionStatusPending,
+			DeliveryMethod:   method,
+			IPAddress:        req.IPAddress,
+			UserAgent:        req.UserAgent,
+			ExpiresAt:        created.Add(c.paymentWindow).Unix(),
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+
+		var redemption *entity.PromotionRedemption
+		if req.PromoCode != "" {
+			if redemption, err = c.promotions.Apply(ctx, req.PromoCode, order); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := c.orderRepo.Create(ctx, order); err != nil {
+			return nil, err
+		}
+		if redemption != nil {
+			if err := c.promotions.Redeem(ctx, redemption); err != nil {
+				return nil, err
+			}
+		}
+
+		job.ConvertedToOrder, job.OrderID = true, &order.ID
+		if err := c.jobRepo.SetOrder(ctx, job); err != nil {
+			return nil, err
+		}
+		return order, nil
+	}, checkoutTimeout)
+	if err != nil {
+		lg.Error("create failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("order created", "orderID", order.ID, "amount", order.Amount.String(), "discount", order.Discount.String())
+	return order, nil
+}
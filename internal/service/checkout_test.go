@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	"github.com/playture/backend/internal/repository/pagination"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+type checkoutFixture struct {
+	svc        Checkout
+	jobs       *jobMemory.JobMemory
+	orders     *order_memory.OrderMemory
+	products   *fakeProducts
+	promotions *fakePromotions
+}
+
+func newCheckoutFixture(t *testing.T) *checkoutFixture {
+	t.Helper()
+	f := &checkoutFixture{
+		jobs:       jobMemory.NewJobMemory(),
+		orders:     order_memory.NewOrderMemory(),
+		products:   newFakeProducts(),
+		promotions: newFakePromotions(),
+	}
+	audit := &fakeAudit{}
+	u := uow.NewMemoryUOW(f.jobs, f.orders, f.products, f.promotions, audit)
+	f.svc = NewCheckout(testLogger, &godotenv.Env{}, u,
+		NewCatalog(testLogger, u, audit, f.products), NewPromotions(testLogger, u, audit, f.promotions), f.jobs, f.orders)
+
+	_, err := f.products.Create(context.Background(), &entity.Product{
+		OrderType: entity.OrderTypeBasic,
+		Name:      "Basic",
+		Active:    true,
+		Prices: []entity.ProductPrice{
+			{Price: entity.Money{Minor: 4999, Currency: "USD"}},
+			{Price: entity.Money{Minor: 4599, Currency: "EUR"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// completedJob stores a sample job ready to be ordered and returns its id.
+func (f *checkoutFixture) completedJob(t *testing.T) string {
+	t.Helper()
+	return f.completedJobOf(t, "someone@example.com")
+}
+
+func (f *checkoutFixture) completedJobOf(t *testing.T, email string) string {
+	t.Helper()
+	job := jobContract.NewJob(time.Now().Unix())
+	job.Status, job.UserEmail = entity.JobStatusCompleted, email
+	id, err := f.jobs.Create(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestCreateOrderDeliveryMethod(t *testing.T) {
+	tests := []struct {
+		method entity.DeliveryMethod
+		want   entity.DeliveryMethod
+		err    error
+	}{
+		{0, entity.DeliveryMethodDownload, nil},
+		{entity.DeliveryMethodDownload, entity.DeliveryMethodDownload, nil},
+		{entity.DeliveryMethodEmail, entity.DeliveryMethodEmail, nil},
+		{entity.DeliveryMethodBoth, entity.DeliveryMethodBoth, nil},
+		{4, 0, ErrInvalidDeliveryMethod},
+	}
+	for _, tt := range tests {
+		f := newCheckoutFixture(t)
+		order, err := f.svc.CreateOrder(context.Background(), dto.CreateOrderReq{
+			JobID:          f.completedJob(t),
+			OrderType:      entity.OrderTypeBasic,
+			DeliveryMethod: tt.method,
+		})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("CreateOrder(deliveryMethod %d) error = %v, want %v", tt.method, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		stored, err := f.orders.FindByField(context.Background(), "id", order.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.DeliveryMethod != tt.want {
+			t.Errorf("CreateOrder(deliveryMethod %d) stored %s, want %s", tt.method, stored.DeliveryMethod, tt.want)
+		}
+	}
+}
+
+func TestCreateOrderPricesFromCatalog(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     entity.Money
+	}{
+		{"", entity.Money{Minor: 4999, Currency: "USD"}},
+		{"USD", entity.Money{Minor: 4999, Currency: "USD"}},
+		{"eur", entity.Money{Minor: 4599, Currency: "EUR"}},
+	}
+	for _, tt := range tests {
+		f := newCheckoutFixture(t)
+		jobID := f.completedJob(t)
+		order, err := f.svc.CreateOrder(context.Background(), dto.CreateOrderReq{
+			JobID:     jobID,
+			OrderType: entity.OrderTypeBasic,
+			Currency:  tt.currency,
+		})
+		if err != nil {
+			t.Fatalf("CreateOrder(%q): %v", tt.currency, err)
+		}
+		if order.Amount != tt.want || order.Discount != (entity.Money{Currency: tt.want.Currency}) {
+			t.Errorf("CreateOrder(%q) = %s less %s, want %s", tt.currency, order.Amount, order.Discount, tt.want)
+		}
+		if order.PaymentStatus != entity.PaymentStatusPending || order.ExpiresAt <= time.Now().Unix() {
+			t.Errorf("order = %s expiring %d, want it pending within its payment window", order.PaymentStatus, order.ExpiresAt)
+		}
+
+		job, err := f.jobs.FindByField(context.Background(), "id", jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !job.ConvertedToOrder || job.OrderID == nil || *job.OrderID != order.ID {
+			t.Errorf("job = %+v, want it pointing at the order", job)
+		}
+	}
+}
+
+func TestCreateOrderRefused(t *testing.T) {
+	f := newCheckoutFixture(t)
+	ctx := context.Background()
+
+	received, err := f.jobs.Create(ctx, jobContract.NewJob(time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordered := f.completedJob(t)
+	if _, err := f.svc.CreateOrder(ctx, dto.CreateOrderReq{JobID: ordered, OrderType: entity.OrderTypeBasic}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  dto.CreateOrderReq
+		err  error
+	}{
+		{"unknown job", dto.CreateOrderReq{JobID: uuid.NewString(), OrderType: entity.OrderTypeBasic}, jobRepository.ErrJobNotFound},
+		{"job not completed", dto.CreateOrderReq{JobID: received, OrderType: entity.OrderTypeBasic}, ErrJobNotConvertible},
+		{"job already ordered", dto.CreateOrderReq{JobID: ordered, OrderType: entity.OrderTypeBasic}, ErrJobHasOrder},
+		{"no product", dto.CreateOrderReq{JobID: f.completedJob(t), OrderType: entity.OrderTypePremium}, ErrNotForSale},
+		{"no price", dto.CreateOrderReq{JobID: f.completedJob(t), OrderType: entity.OrderTypeBasic, Currency: "GBP"}, ErrNotForSale},
+	}
+	for _, tt := range tests {
+		if _, err := f.svc.CreateOrder(ctx, tt.req); !errors.Is(err, tt.err) {
+			t.Errorf("%s: CreateOrder error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	orders, err := f.orders.List(ctx, nil, nil, pagination.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 1 {
+		t.Errorf("stored %d orders, want only the first one", len(orders.Items))
+	}
+}
+
+func TestCreateOrderInactiveProduct(t *testing.T) {
+	f := newCheckoutFixture(t)
+	ctx := context.Background()
+
+	product, err := f.products.FindActive(ctx, entity.OrderTypeBasic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Active = false
+	if err := f.products.Update(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	jobID := f.completedJob(t)
+	if _, err := f.svc.CreateOrder(ctx, dto.CreateOrderReq{JobID: jobID, OrderType: entity.OrderTypeBasic}); !errors.Is(err, ErrNotForSale) {
+		t.Errorf("CreateOrder error = %v, want ErrNotForSale", err)
+	}
+	if job, _ := f.jobs.FindByField(ctx, "id", jobID); job.ConvertedToOrder {
+		t.Errorf("refused order left the job ordered")
+	}
+}
+
+func (f *checkoutFixture) promotion(t *testing.T, promotion *entity.Promotion) *entity.Promotion {
+	t.Helper()
+	if _, err := f.promotions.Create(context.Background(), promotion); err != nil {
+		t.Fatal(err)
+	}
+	return promotion
+}
+
+func TestCreateOrderWithPromoCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion *entity.Promotion
+		currency  string
+		amount    entity.Money
+		discount  entity.Money
+	}{
+		{
+			"percent", &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindPercent, PercentOff: 20, Active: true}, "USD",
+			entity.Money{Minor: 3999, Currency: "USD"}, entity.Money{Minor: 1000, Currency: "USD"},
+		},
+		{
+			"fixed", &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindFixed, AmountOff: entity.Money{Minor: 1500, Currency: "EUR"}, Active: true}, "EUR",
+			entity.Money{Minor: 3099, Currency: "EUR"}, entity.Money{Minor: 1500, Currency: "EUR"},
+		},
+		{
+			"fixed above the price", &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindFixed, AmountOff: entity.Money{Minor: 9000, Currency: "USD"}, Active: true}, "USD",
+			entity.Money{Minor: 0, Currency: "USD"}, entity.Money{Minor: 4999, Currency: "USD"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckoutFixture(t)
+			ctx := context.Background()
+			promotion := f.promotion(t, tt.promotion)
+
+			order, err := f.svc.CreateOrder(ctx, dto.CreateOrderReq{
+				JobID:     f.completedJobOf(t, "Someone@Example.com"),
+				OrderType: entity.OrderTypeBasic,
+				Currency:  tt.currency,
+				PromoCode: " spring ",
+			})
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			if order.Amount != tt.amount || order.Discount != tt.discount || order.PromotionID == nil || *order.PromotionID != promotion.ID {
+				t.Errorf("order = %s less %s with promotion %v, want %s less %s", order.Amount, order.Discount, order.PromotionID, tt.amount, tt.discount)
+			}
+
+			redemption, ok := f.promotions.redemptions.get(order.ID)
+			if !ok || redemption.Email != "someone@example.com" || redemption.Discount != tt.discount {
+				t.Errorf("redemption = %+v, want the discount recorded for the lowercased email", redemption)
+			}
+			if stored, _ := f.promotions.FindByID(ctx, promotion.ID.String()); stored.Redemptions != 1 {
+				t.Errorf("promotion redeemed %d times, want 1", stored.Redemptions)
+			}
+		})
+	}
+}
+
+func TestCreateOrderPromoLimits(t *testing.T) {
+	now := time.Now()
+	percent := func(edit func(p *entity.Promotion)) *entity.Promotion {
+		promotion := &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindPercent, PercentOff: 20, Active: true}
+		edit(promotion)
+		return promotion
+	}
+
+	tests := []struct {
+		name      string
+		promotion *entity.Promotion
+		currency  string
+		// used redeems the code this many times before the order under test
+		used  int
+		email string
+	}{
+		{"not started", percent(func(p *entity.Promotion) { p.StartsAt = now.Add(time.Hour).Unix() }), "USD", 0, ""},
+		{"ended", percent(func(p *entity.Promotion) { p.EndsAt = now.Add(-time.Hour).Unix() }), "USD", 0, ""},
+		{"inactive", percent(func(p *entity.Promotion) { p.Active = false }), "USD", 0, ""},
+		{"other order type", percent(func(p *entity.Promotion) { p.OrderTypes = []entity.OrderType{entity.OrderTypePremium} }), "USD", 0, ""},
+		{"other currency", &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindFixed, AmountOff: entity.Money{Minor: 500, Currency: "EUR"}, Active: true}, "USD", 0, ""},
+		{"used up", percent(func(p *entity.Promotion) { p.MaxRedemptions = 2 }), "USD", 2, ""},
+		{"used by the customer", percent(func(p *entity.Promotion) { p.MaxPerEmail = 1 }), "USD", 1, "SOMEONE@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckoutFixture(t)
+			ctx := context.Background()
+			promotion := f.promotion(t, tt.promotion)
+
+			for i := 0; i < tt.used; i++ {
+				email := "other" + strconv.Itoa(i) + "@example.com"
+				if tt.email != "" {
+					email = tt.email
+				}
+				_, err := f.svc.CreateOrder(ctx, dto.CreateOrderReq{JobID: f.completedJobOf(t, email), OrderType: entity.OrderTypeBasic, PromoCode: "SPRING"})
+				if err != nil {
+					t.Fatalf("redemption %d: %v", i+1, err)
+				}
+			}
+
+			jobID := f.completedJob(t)
+			_, err := f.svc.CreateOrder(ctx, dto.CreateOrderReq{JobID: jobID, OrderType: entity.OrderTypeBasic, Currency: tt.currency, PromoCode: "SPRING"})
+			if !errors.Is(err, ErrPromoNotApplicable) {
+				t.Fatalf("CreateOrder error = %v, want ErrPromoNotApplicable", err)
+			}
+			if job, _ := f.jobs.FindByField(ctx, "id", jobID); job.ConvertedToOrder {
+				t.Errorf("refused order left the job ordered")
+			}
+			if stored, _ := f.promotions.FindByID(ctx, promotion.ID.String()); stored.Redemptions != tt.used {
+				t.Errorf("promotion redeemed %d times, want %d", stored.Redemptions, tt.used)
+			}
+		})
+	}
+}
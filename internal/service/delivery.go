@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/infrastructure/postgresql"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	mailRepository "github.com/playture/backend/internal/repository/mail_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	storageRepository "github.com/playture/backend/internal/repository/storage_repository"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/utils"
+)
+
+var (
+	ErrOrderNotDeliverable = errors.New("only paid orders with a completed production can be delivered")
+	ErrDownloadExpired     = errors.New("download link has expired")
+)
+
+const (
+	defaultDeliveryRetention = 30 * 24 * time.Hour
+	// maxDeliveryAttempts failed attempts mark a delivery FAILED, an admin
+	// re-delivery queues it again
+	maxDeliveryAttempts = 5
+	// downloadURLTTL is how long the storage URL a download redirects to
+	// stays valid, the long-lived part is the download link itself
+	downloadURLTTL = 15 * time.Minute
+	// deliveryTimeout bounds sending one delivery and, separately, recording
+	// its outcome
+	deliveryTimeout = 30 * time.Second
+	// deliveryStaleAfter is how long a claimed delivery may go unrecorded
+	// before it is claimed again, it outlasts a batch of worker attempts
+	deliveryStaleAfter = 15 * time.Minute
+	downloadTokenLen   = 32
+)
+
+type Deliveries interface {
+	// DeliverPending works through up to limit queued deliveries and
+	// returns how many it claimed.
+	DeliverPending(ctx context.Context, limit int) (int, error) // worker
+	// Redeliver queues the delivery of an order again, which issues a new
+	// download link and resends the email the delivery method calls for.
+	Redeliver(ctx context.Context, orderID string) (*entity.Delivery, error)   // admin api
+	GetDelivery(ctx context.Context, orderID string) (*entity.Delivery, error) // admin api
+	// Download counts a download through token and returns a short-lived
+	// URL of the render.
+	Download(ctx context.Context, token string) (string, error)
+}
+
+type deliveries struct {
+	logger       *slog.Logger
+	uow          uow.IUOW
+	audit        Audit
+	outbox       Outbox
+	baseURL      string
+	template     string
+	retention    time.Duration
+	deliveryRepo deliveryRepository.Repository
+	orderRepo    orderRepository.Repository
+	jobRepo      jobRepository.Repository
+	mailRepo     mailRepository.Repository
+	storageRepo  storageRepository.Repository
+}
+
+func NewDeliveries(logger *slog.Logger,
+	env *godotenv.Env,
+	uow uow.IUOW,
+	audit Audit,
+	outbox Outbox,
+	deliveryRepo deliveryRepository.Repository,
+	orderRepo orderRepository.Repository,
+	jobRepo jobRepository.Repository,
+	mailRepo mailRepository.Repository,
+	storageRepo storageRepository.Repository,
+) Deliveries {
+	d := &deliveries{
+		logger:       logger.With("layer", "DeliveryService"),
+		uow:          uow,
+		audit:        audit,
+		outbox:       outbox,
+		baseURL:      strings.TrimSuffix(env.DeliveryBaseURL, "/"),
+		template:     env.PostmarkDeliveryTemplateID,
+		retention:    defaultDeliveryRetention,
+		deliveryRepo: deliveryRepo,
+		orderRepo:    orderRepo,
+		jobRepo:      jobRepo,
+		mailRepo:     mailRepo,
+		storageRepo:  storageRepo,
+	}
+	if v, err := strconv.Atoi(env.DeliveryRetentionDays); err == nil && v > 0 {
+		d.retention = time.Duration(v) * 24 * time.Hour
+	}
+	return d
+}
+
+// DeliverPending claims deliveries in one statement, then sends and records
+// each on its own, so no transaction or row lock is held while an email goes
+// out. A delivery whose attempt never got recorded, the worker died or the
+// database was unreachable, stays SENDING until deliveryStaleAfter and is
+// then claimed again, which sends its email once more. A failed attempt goes
+// to the back of the queue until maxDeliveryAttempts.
+func (d *deliveries) DeliverPending(ctx context.Context, limit int) (int, error) {
+	now := time.Now()
+	claimed, err := d.deliveryRepo.ClaimPending(ctx, now.Add(-deliveryStaleAfter).Unix(), now.Unix(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var failed error
+	for _, delivery := range claimed {
+		if err := d.deliver(ctx, delivery); err != nil && failed == nil {
+			failed = err
+		}
+	}
+	return len(claimed), failed
+}
+
+// deliver makes one attempt at a claimed delivery. Only failures to record
+// its outcome are returned.
+func (d *deliveries) deliver(ctx context.Context, claimed *entity.Delivery) error {
+	lg := d.logger.With("method", "deliver", "orderID", claimed.OrderID)
+
+	// the replica may not have the payment or the refund of the order yet
+	order, err := d.orderRepo.FindByField(postgresql.WithPrimary(ctx), "id", claimed.OrderID)
+	if err != nil {
+		lg.Error("reading order failed", "err", err)
+		return err
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	token, sendErr := d.send(sendCtx, order)
+	cancel()
+
+	if err := d.record(ctx, claimed, token, sendErr); err != nil {
+		lg.Error("recording delivery attempt failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// record writes the outcome of an attempt under the order lock, unless the
+// delivery was queued or claimed again meanwhile.
+func (d *deliveries) record(ctx context.Context, claimed *entity.Delivery, token string, sendErr error) error {
+	lg := d.logger.With("method", "record", "orderID", claimed.OrderID)
+
+	_, err := d.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		order, err := d.orderRepo.Lock(ctx, claimed.OrderID.String())
+		if err != nil {
+			return nil, err
+		}
+		delivery, err := d.deliveryRepo.FindByOrder(ctx, claimed.OrderID.String())
+		if err != nil {
+			return nil, err
+		}
+		if delivery.Status != entity.DeliveryStatusSending || delivery.UpdatedAt != claimed.UpdatedAt {
+			lg.Warn("delivery changed during the attempt, dropping its outcome", "status", delivery.Status.String())
+			return nil, nil
+		}
+
+		now := time.Now().Unix()
+		delivery.UpdatedAt = now
+		// a refund may have landed while the email was going out
+		if sendErr == nil {
+			sendErr = deliverable(order)
+		}
+		switch {
+		case errors.Is(sendErr, ErrOrderNotDeliverable):
+			lg.Warn("order is no longer deliverable", "paymentStatus", order.PaymentStatus.String())
+			delivery.Status, delivery.LastError = entity.DeliveryStatusFailed, sendErr.Error()
+			return nil, d.deliveryRepo.Update(ctx, delivery)
+		case sendErr != nil:
+			lg.Error("delivery attempt failed", "attempt", delivery.Attempts, "err", sendErr)
+			delivery.Status, delivery.LastError = entity.DeliveryStatusPending, sendErr.Error()
+			if delivery.Attempts >= maxDeliveryAttempts {
+				delivery.Status = entity.DeliveryStatusFailed
+			}
+			return nil, d.deliveryRepo.Update(ctx, delivery)
+		}
+
+		delivery.Status, delivery.Token, delivery.LastError, delivery.DeliveredAt = entity.DeliveryStatusDelivered, token, "", now
+		if order.DeliveryMethod.SendsEmail() {
+			delivery.EmailSentAt = now
+		}
+		if err := d.deliveryRepo.Update(ctx, delivery); err != nil {
+			return nil, err
+		}
+
+		order.DeliveredAt, order.ExpiresAt = now, now+int64(d.retention/time.Second)
+		if err := d.orderRepo.MarkDelivered(ctx, order); err != nil {
+			return nil, err
+		}
+		if err := d.orderRepo.UpdateExpiry(ctx, order); err != nil {
+			return nil, err
+		}
+
+		lg.Info("order delivered", "deliveryMethod", order.DeliveryMethod.String(), "expiresAt", order.ExpiresAt)
+		return nil, d.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderDelivered,
+			string(entity.AuditEntityOrder), order.ID.String(), orderDeliveredEvent{
+				OrderID:        order.ID.String(),
+				DeliveryMethod: order.DeliveryMethod,
+				DownloadURL:    d.link(token),
+				ExpiresAt:      order.ExpiresAt,
+				UserEmail:      order.UserEmail,
+				UserName:       order.UserName,
+			})
+	}, deliveryTimeout)
+	return err
+}
+
+// send issues a new download token for order and emails the link when the
+// delivery method asks for it. The token is only stored once this worked,
+// so a failed attempt leaves the previous link working.
+func (d *deliveries) send(ctx context.Context, order *entity.Order) (string, error) {
+	if err := deliverable(order); err != nil {
+		return "", err
+	}
+
+	token, err := newDownloadToken()
+	if err != nil {
+		return "", err
+	}
+	if !order.DeliveryMethod.SendsEmail() {
+		return token, nil
+	}
+
+	err = d.mailRepo.SendTemplate(ctx, order.UserEmail, order.UserName, d.template, map[string]interface{}{
+		"name":         order.UserName,
+		"order_id":     order.ID.String(),
+		"download_url": d.link(token),
+		"expires_at":   time.Now().Add(d.retention).UTC().Format(time.DateOnly),
+	})
+	if err != nil {
+		return "", utils.WrapError("send delivery email", err)
+	}
+	return token, nil
+}
+
+func (d *deliveries) Redeliver(ctx context.Context, orderID string) (*entity.Delivery, error) {
+	lg := d.logger.With("method", "Redeliver", "orderID", orderID)
+
+	delivery, err := uow.Do(ctx, d.uow, func(ctx context.Context) (*entity.Delivery, error) {
+		order, err := d.orderRepo.Lock(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if err := deliverable(order); err != nil {
+			return nil, err
+		}
+
+		before, err := d.deliveryRepo.FindByOrder(ctx, orderID)
+		if err != nil && !errors.Is(err, deliveryRepository.ErrDeliveryNotFound) {
+			return nil, err
+		}
+		if err := d.deliveryRepo.Queue(ctx, orderID, time.Now().Unix()); err != nil {
+			return nil, err
+		}
+		after, err := d.deliveryRepo.FindByOrder(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := d.audit.Record(ctx, entity.AuditActionOrderRedeliver, entity.AuditEntityOrder, orderID, before, after); err != nil {
+			return nil, err
+		}
+		return after, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("redeliver failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("delivery queued again")
+	return delivery, nil
+}
+
+func (d *deliveries) GetDelivery(ctx context.Context, orderID string) (*entity.Delivery, error) {
+	if _, err := d.orderRepo.FindByField(ctx, "id", orderID); err != nil {
+		return nil, err
+	}
+	return d.deliveryRepo.FindByOrder(ctx, orderID)
+}
+
+// Download checks the order behind token every time, so a refund or the
+// end of the retention window shuts the link even though it was handed out.
+func (d *deliveries) Download(ctx context.Context, token string) (string, error) {
+	lg := d.logger.With("method", "Download")
+	// a lagging replica would keep a revoked token or a refunded order's
+	// link working
+	ctx = postgresql.WithPrimary(ctx)
+
+	delivery, err := d.deliveryRepo.FindByToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	order, err := d.orderRepo.FindByField(ctx, "id", delivery.OrderID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if order.PaymentStatus != entity.PaymentStatusPaid || (order.ExpiresAt != 0 && now.Unix() >= order.ExpiresAt) {
+		return "", ErrDownloadExpired
+	}
+	if order.ProductionJobID == nil {
+		return "", ErrOrderNotDeliverable
+	}
+	job, err := d.jobRepo.FindByField(ctx, "id", *order.ProductionJobID)
+	if err != nil {
+		return "", err
+	}
+	if job.FinalVideoS3Key == "" {
+		return "", ErrOrderNotDeliverable
+	}
+
+	url, err := d.storageRepo.SignedURL(job.FinalVideoS3Key, now.Add(downloadURLTTL))
+	if err != nil {
+		lg.Error("signing download url failed", "orderID", order.ID, "err", err)
+		return "", err
+	}
+	if err := d.deliveryRepo.RecordDownload(ctx, token, now.Unix()); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+func (d *deliveries) link(token string) string {
+	return d.baseURL + "/downloads/" + token
+}
+
+func deliverable(order *entity.Order) error {
+	if order.PaymentStatus != entity.PaymentStatusPaid || order.ProductionStatus != entity.ProductionStatusCompleted {
+		return ErrOrderNotDeliverable
+	}
+	return nil
+}
+
+func newDownloadToken() (string, error) {
+	raw := make([]byte, downloadTokenLen)
+	if _, err := rand.Read(raw); err != nil {
+		return "", utils.WrapError("generate download token", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+type orderDeliveredEvent struct {
+	OrderID        string                `json:"orderId"`
+	DeliveryMethod entity.DeliveryMethod `json:"deliveryMethod"`
+	DownloadURL    string                `json:"downloadUrl"`
+	ExpiresAt      int64                 `json:"expiresAt"`
+	UserEmail      string                `json:"userEmail"`
+	UserName       string                `json:"userName"`
+}
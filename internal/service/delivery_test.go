@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+type deliveryFixture struct {
+	svc        *deliveries
+	orders     *order_memory.OrderMemory
+	deliveries *fakeDeliveries
+	outbox     *fakeOutbox
+	mail       *fakeMail
+}
+
+func newDeliveryFixture() *deliveryFixture {
+	f := &deliveryFixture{
+		orders: order_memory.NewOrderMemory(),
+		outbox: &fakeOutbox{},
+		mail:   &fakeMail{},
+	}
+	f.deliveries = newFakeDeliveries(f.orders)
+	audit := &fakeAudit{}
+	u := uow.NewMemoryUOW(f.orders, f.deliveries, f.outbox, audit)
+	env := &godotenv.Env{DeliveryBaseURL: "https://example.com/", PostmarkDeliveryTemplateID: "delivery"}
+	f.svc = NewDeliveries(testLogger, env, u, audit, f.outbox, f.deliveries, f.orders, nil, f.mail, fakeStorage{}).(*deliveries)
+	return f
+}
+
+// queuedOrder stores a paid order with a completed production and queues
+// its delivery.
+func (f *deliveryFixture) queuedOrder(t *testing.T, method entity.DeliveryMethod) *entity.Order {
+	t.Helper()
+	ctx := context.Background()
+
+	now := time.Now().Unix()
+	productionJobID := uuid.New()
+	order := order_contract.NewOrder(uuid.New(), now)
+	order.PaymentStatus, order.StripePaymentIntentID, order.PaidAt = entity.PaymentStatusPaid, "pi_1", now
+	order.ProductionJobID, order.ProductionStatus = &productionJobID, entity.ProductionStatusCompleted
+	order.DeliveryMethod = method
+	if _, err := f.orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.deliveries.Queue(ctx, order.ID.String(), now); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func (f *deliveryFixture) delivery(t *testing.T, orderID uuid.UUID) *entity.Delivery {
+	t.Helper()
+	delivery, err := f.deliveries.FindByOrder(context.Background(), orderID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+func TestDeliverPendingByMethod(t *testing.T) {
+	tests := []struct {
+		method entity.DeliveryMethod
+		emails int
+	}{
+		{entity.DeliveryMethodDownload, 0},
+		{entity.DeliveryMethodEmail, 1},
+		{entity.DeliveryMethodBoth, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method.String(), func(t *testing.T) {
+			f := newDeliveryFixture()
+			order := f.queuedOrder(t, tt.method)
+
+			n, err := f.svc.DeliverPending(context.Background(), 10)
+			if err != nil || n != 1 {
+				t.Fatalf("DeliverPending = %d, %v, want 1 delivery", n, err)
+			}
+
+			if f.mail.count() != tt.emails {
+				t.Fatalf("sent %d emails, want %d", f.mail.count(), tt.emails)
+			}
+			delivery := f.delivery(t, order.ID)
+			if delivery.Status != entity.DeliveryStatusDelivered || delivery.Token == "" || delivery.Attempts != 1 {
+				t.Errorf("delivery = %+v, want delivered with a token", delivery)
+			}
+			if (delivery.EmailSentAt != 0) != (tt.emails == 1) {
+				t.Errorf("email sent at %d, want it set only when an email went out", delivery.EmailSentAt)
+			}
+			if tt.emails == 1 {
+				sent := f.mail.sent[0]
+				if sent.To != order.UserEmail || sent.Template != "delivery" || sent.Model["download_url"] != "https://example.com/downloads/"+delivery.Token {
+					t.Errorf("email = %+v, want the download link sent to the customer", sent)
+				}
+			}
+
+			got, err := f.orders.FindByField(context.Background(), "id", order.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.DeliveredAt == 0 || got.ExpiresAt <= got.DeliveredAt {
+				t.Errorf("order delivered at %d expiring %d, want both set", got.DeliveredAt, got.ExpiresAt)
+			}
+			if events := f.outbox.events(); !slices.Equal(events, []string{entity.OutboxEventOrderDelivered}) {
+				t.Errorf("events = %v", events)
+			}
+		})
+	}
+}
+
+func TestDeliverPendingRetriesFailedEmail(t *testing.T) {
+	f := newDeliveryFixture()
+	order := f.queuedOrder(t, entity.DeliveryMethodEmail)
+	f.mail.err = errors.New("postmark down")
+
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		if _, err := f.svc.DeliverPending(context.Background(), 10); err != nil {
+			t.Fatalf("DeliverPending: %v", err)
+		}
+		delivery := f.delivery(t, order.ID)
+		want := entity.DeliveryStatusPending
+		if attempt == maxDeliveryAttempts {
+			want = entity.DeliveryStatusFailed
+		}
+		if delivery.Status != want || delivery.Attempts != attempt || delivery.Token != "" || delivery.LastError == "" {
+			t.Fatalf("after attempt %d delivery = %+v, want %s", attempt, delivery, want)
+		}
+	}
+
+	if n, err := f.svc.DeliverPending(context.Background(), 10); err != nil || n != 0 {
+		t.Errorf("DeliverPending after failure = %d, %v, want nothing claimed", n, err)
+	}
+	if got, _ := f.orders.FindByField(context.Background(), "id", order.ID); got.DeliveredAt != 0 {
+		t.Errorf("order marked delivered at %d", got.DeliveredAt)
+	}
+}
+
+func TestDeliverPendingRefundedOrder(t *testing.T) {
+	f := newDeliveryFixture()
+	order := f.queuedOrder(t, entity.DeliveryMethodBoth)
+	order.PaymentStatus = entity.PaymentStatusRefunded
+	if err := f.orders.UpdatePayment(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.svc.DeliverPending(context.Background(), 10); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if f.mail.count() != 0 {
+		t.Errorf("sent %d emails for a refunded order", f.mail.count())
+	}
+	if delivery := f.delivery(t, order.ID); delivery.Status != entity.DeliveryStatusFailed || delivery.Token != "" {
+		t.Errorf("delivery = %+v, want failed without a token", delivery)
+	}
+}
+
+func TestDeliverPendingReclaimsStaleAttempt(t *testing.T) {
+	f := newDeliveryFixture()
+	stale, fresh := f.queuedOrder(t, entity.DeliveryMethodEmail), f.queuedOrder(t, entity.DeliveryMethodEmail)
+
+	now := time.Now()
+	for id, claimedAt := range map[uuid.UUID]time.Time{
+		stale.ID: now.Add(-deliveryStaleAfter - time.Minute),
+		fresh.ID: now.Add(-time.Minute),
+	} {
+		delivery := f.delivery(t, id)
+		delivery.Status, delivery.Attempts, delivery.UpdatedAt = entity.DeliveryStatusSending, 1, claimedAt.Unix()
+		f.deliveries.put(id, *delivery)
+	}
+
+	if n, err := f.svc.DeliverPending(context.Background(), 10); err != nil || n != 1 {
+		t.Fatalf("DeliverPending = %d, %v, want only the stale delivery", n, err)
+	}
+	if delivery := f.delivery(t, stale.ID); delivery.Status != entity.DeliveryStatusDelivered || delivery.Attempts != 2 {
+		t.Errorf("stale delivery = %+v, want delivered on its second attempt", delivery)
+	}
+	if delivery := f.delivery(t, fresh.ID); delivery.Status != entity.DeliveryStatusSending {
+		t.Errorf("fresh delivery = %+v, want it left to its worker", delivery)
+	}
+}
+
+func TestRecordDropsSupersededAttempt(t *testing.T) {
+	f := newDeliveryFixture()
+	ctx := context.Background()
+	order := f.queuedOrder(t, entity.DeliveryMethodEmail)
+
+	claimed, err := f.deliveries.ClaimPending(ctx, 0, time.Now().Unix(), 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimPending = %v, %v", claimed, err)
+	}
+	// an admin queues the delivery again while the email is going out
+	if err := f.deliveries.Queue(ctx, order.ID.String(), time.Now().Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.svc.record(ctx, claimed[0], "tok", nil); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if delivery := f.delivery(t, order.ID); delivery.Status != entity.DeliveryStatusPending || delivery.Token != "" {
+		t.Errorf("delivery = %+v, want it still queued", delivery)
+	}
+	if events := f.outbox.events(); len(events) != 0 {
+		t.Errorf("events = %v, want none", events)
+	}
+}
@@ -0,0 +1,575 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	"github.com/playture/backend/internal/repository/pagination"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	refundRepository "github.com/playture/backend/internal/repository/refund_repository"
+)
+
+// The fakes below stand in for the repositories and services that have no
+// in-memory implementation. Stores implement uow.Snapshotter so MemoryUOW
+// rolls them back with the job and order repositories.
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// table is a map MemoryUOW can roll back.
+type table[K comparable, V any] struct {
+	mu   sync.Mutex
+	rows map[K]V
+}
+
+func newTable[K comparable, V any]() *table[K, V] {
+	return &table[K, V]{rows: map[K]V{}}
+}
+
+func (t *table[K, V]) Snapshot() func() {
+	t.mu.Lock()
+	saved := make(map[K]V, len(t.rows))
+	for k, v := range t.rows {
+		saved[k] = v
+	}
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		t.rows = saved
+		t.mu.Unlock()
+	}
+}
+
+func (t *table[K, V]) get(k K) (V, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.rows[k]
+	return v, ok
+}
+
+func (t *table[K, V]) put(k K, v V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows[k] = v
+}
+
+func (t *table[K, V]) remove(k K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.rows, k)
+}
+
+func (t *table[K, V]) all() []V {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	all := make([]V, 0, len(t.rows))
+	for _, v := range t.rows {
+		all = append(all, v)
+	}
+	return all
+}
+
+// history is an append-only list MemoryUOW can roll back.
+type history[V any] struct {
+	mu      sync.Mutex
+	entries []V
+}
+
+func (l *history[V]) Snapshot() func() {
+	l.mu.Lock()
+	n := len(l.entries)
+	l.mu.Unlock()
+	return func() {
+		l.mu.Lock()
+		l.entries = l.entries[:n]
+		l.mu.Unlock()
+	}
+}
+
+func (l *history[V]) add(v V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, v)
+}
+
+func (l *history[V]) list() []V {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]V(nil), l.entries...)
+}
+
+type fakeRefunds struct {
+	*table[uuid.UUID, entity.Refund]
+}
+
+func newFakeRefunds() *fakeRefunds {
+	return &fakeRefunds{newTable[uuid.UUID, entity.Refund]()}
+}
+
+func (f *fakeRefunds) Create(ctx context.Context, refund *entity.Refund) (string, error) {
+	refund.ID = uuid.New()
+	f.put(refund.ID, *refund)
+	return refund.ID.String(), nil
+}
+
+func (f *fakeRefunds) UpdateResult(ctx context.Context, refund *entity.Refund) error {
+	stored, ok := f.get(refund.ID)
+	if !ok {
+		return refundRepository.ErrRefundNotFound
+	}
+	stored.Status, stored.StripeRefundID, stored.FailureReason, stored.UpdatedAt =
+		refund.Status, refund.StripeRefundID, refund.FailureReason, refund.UpdatedAt
+	f.put(refund.ID, stored)
+	return nil
+}
+
+func (f *fakeRefunds) FindByID(ctx context.Context, id string) (*entity.Refund, error) {
+	stored, ok := f.get(uuid.MustParse(id))
+	if !ok {
+		return nil, refundRepository.ErrRefundNotFound
+	}
+	return &stored, nil
+}
+
+func (f *fakeRefunds) ListByOrder(ctx context.Context, orderID string) ([]*entity.Refund, error) {
+	refunds := []*entity.Refund{}
+	for _, refund := range f.all() {
+		if refund.OrderID.String() == orderID {
+			refunds = append(refunds, &refund)
+		}
+	}
+	sort.Slice(refunds, func(a, b int) bool { return refunds[a].CreatedAt < refunds[b].CreatedAt })
+	return refunds, nil
+}
+
+func (f *fakeRefunds) ListPending(ctx context.Context, createdAfter, updatedBefore int64, limit int) ([]*entity.Refund, error) {
+	refunds := []*entity.Refund{}
+	for _, refund := range f.all() {
+		if refund.Status == entity.RefundStatusPending && refund.CreatedAt > createdAfter && refund.UpdatedAt < updatedBefore {
+			refunds = append(refunds, &refund)
+		}
+	}
+	sort.Slice(refunds, func(a, b int) bool { return refunds[a].UpdatedAt < refunds[b].UpdatedAt })
+	return refunds[:min(limit, len(refunds))], nil
+}
+
+func (f *fakeRefunds) Total(ctx context.Context, orderID string, statuses ...entity.RefundStatus) (int64, error) {
+	var total int64
+	for _, refund := range f.all() {
+		for _, status := range statuses {
+			if refund.OrderID.String() == orderID && refund.Status == status {
+				total += refund.Amount.Minor
+			}
+		}
+	}
+	return total, nil
+}
+
+type fakeDeliveries struct {
+	*table[uuid.UUID, entity.Delivery]
+	orders *order_memory.OrderMemory
+}
+
+func newFakeDeliveries(orders *order_memory.OrderMemory) *fakeDeliveries {
+	return &fakeDeliveries{newTable[uuid.UUID, entity.Delivery](), orders}
+}
+
+func (f *fakeDeliveries) Queue(ctx context.Context, orderID string, at int64) error {
+	id := uuid.MustParse(orderID)
+	delivery, ok := f.get(id)
+	if !ok {
+		delivery = entity.Delivery{OrderID: id, CreatedAt: at}
+	}
+	delivery.Status, delivery.Attempts, delivery.LastError, delivery.UpdatedAt = entity.DeliveryStatusPending, 0, "", at
+	f.put(id, delivery)
+	return nil
+}
+
+func (f *fakeDeliveries) ClaimPending(ctx context.Context, staleBefore, at int64, limit int) ([]*entity.Delivery, error) {
+	claimed := []*entity.Delivery{}
+	for _, delivery := range f.all() {
+		stale := delivery.Status == entity.DeliveryStatusSending && delivery.UpdatedAt < staleBefore
+		if delivery.Status == entity.DeliveryStatusPending || stale {
+			claimed = append(claimed, &delivery)
+		}
+	}
+	sort.Slice(claimed, func(a, b int) bool { return claimed[a].UpdatedAt < claimed[b].UpdatedAt })
+	claimed = claimed[:min(limit, len(claimed))]
+	for _, delivery := range claimed {
+		delivery.Status, delivery.Attempts, delivery.UpdatedAt = entity.DeliveryStatusSending, delivery.Attempts+1, at
+		f.put(delivery.OrderID, *delivery)
+	}
+	return claimed, nil
+}
+
+func (f *fakeDeliveries) Update(ctx context.Context, delivery *entity.Delivery) error {
+	if _, ok := f.get(delivery.OrderID); !ok {
+		return deliveryRepository.ErrDeliveryNotFound
+	}
+	f.put(delivery.OrderID, *delivery)
+	return nil
+}
+
+func (f *fakeDeliveries) ListExpired(ctx context.Context, before int64, limit int) ([]*entity.Delivery, error) {
+	expired := []*entity.Delivery{}
+	for _, delivery := range f.all() {
+		order, err := f.orders.FindByField(ctx, "id", delivery.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if delivery.Status == entity.DeliveryStatusDelivered && order.ExpiresAt != 0 && order.ExpiresAt < before {
+			expired = append(expired, &delivery)
+		}
+	}
+	return expired[:min(limit, len(expired))], nil
+}
+
+func (f *fakeDeliveries) FindByOrder(ctx context.Context, orderID string) (*entity.Delivery, error) {
+	delivery, ok := f.get(uuid.MustParse(orderID))
+	if !ok {
+		return nil, deliveryRepository.ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+func (f *fakeDeliveries) FindByToken(ctx context.Context, token string) (*entity.Delivery, error) {
+	for _, delivery := range f.all() {
+		if token != "" && delivery.Token == token {
+			return &delivery, nil
+		}
+	}
+	return nil, deliveryRepository.ErrDeliveryNotFound
+}
+
+func (f *fakeDeliveries) RecordDownload(ctx context.Context, token string, at int64) error {
+	delivery, err := f.FindByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	delivery.DownloadCount, delivery.LastDownloadedAt = delivery.DownloadCount+1, at
+	f.put(delivery.OrderID, *delivery)
+	return nil
+}
+
+type fakeProducts struct {
+	*table[uuid.UUID, entity.Product]
+}
+
+func newFakeProducts() *fakeProducts {
+	return &fakeProducts{newTable[uuid.UUID, entity.Product]()}
+}
+
+func (f *fakeProducts) Create(ctx context.Context, product *entity.Product) (string, error) {
+	if active, err := f.FindActive(ctx, product.OrderType); err == nil && product.Active && active.ID != product.ID {
+		return "", productRepository.ErrActiveProductExists
+	}
+	if product.ID == uuid.Nil {
+		product.ID = uuid.New()
+	}
+	f.put(product.ID, *product)
+	return product.ID.String(), nil
+}
+
+func (f *fakeProducts) Update(ctx context.Context, product *entity.Product) error {
+	if _, ok := f.get(product.ID); !ok {
+		return productRepository.ErrProductNotFound
+	}
+	f.put(product.ID, *product)
+	return nil
+}
+
+func (f *fakeProducts) FindByID(ctx context.Context, id string) (*entity.Product, error) {
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return nil, productRepository.ErrProductNotFound
+	}
+	product, ok := f.get(key)
+	if !ok {
+		return nil, productRepository.ErrProductNotFound
+	}
+	return &product, nil
+}
+
+func (f *fakeProducts) FindActive(ctx context.Context, orderType entity.OrderType) (*entity.Product, error) {
+	for _, product := range f.all() {
+		if product.Active && product.OrderType == orderType {
+			return &product, nil
+		}
+	}
+	return nil, productRepository.ErrProductNotFound
+}
+
+func (f *fakeProducts) List(ctx context.Context, includeInactive bool) ([]*entity.Product, error) {
+	products := []*entity.Product{}
+	for _, product := range f.all() {
+		if includeInactive || product.Active {
+			products = append(products, &product)
+		}
+	}
+	return products, nil
+}
+
+func (f *fakeProducts) Delete(ctx context.Context, id string) error {
+	product, err := f.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	f.remove(product.ID)
+	return nil
+}
+
+// fakePromotions keeps redemptions by order id, like the unique order_id
+// of promotion_redemptions.
+type fakePromotions struct {
+	*table[uuid.UUID, entity.Promotion]
+	redemptions *table[uuid.UUID, entity.PromotionRedemption]
+}
+
+func newFakePromotions() *fakePromotions {
+	return &fakePromotions{newTable[uuid.UUID, entity.Promotion](), newTable[uuid.UUID, entity.PromotionRedemption]()}
+}
+
+func (f *fakePromotions) Snapshot() func() {
+	restorePromotions, restoreRedemptions := f.table.Snapshot(), f.redemptions.Snapshot()
+	return func() {
+		restorePromotions()
+		restoreRedemptions()
+	}
+}
+
+func (f *fakePromotions) Create(ctx context.Context, promotion *entity.Promotion) (string, error) {
+	if _, err := f.LockByCode(ctx, promotion.Code); err == nil {
+		return "", promotionRepository.ErrCodeExists
+	}
+	promotion.ID = uuid.New()
+	f.put(promotion.ID, *promotion)
+	return promotion.ID.String(), nil
+}
+
+func (f *fakePromotions) Update(ctx context.Context, promotion *entity.Promotion) error {
+	stored, ok := f.get(promotion.ID)
+	if !ok {
+		return promotionRepository.ErrPromotionNotFound
+	}
+	updated := *promotion
+	updated.Redemptions = stored.Redemptions
+	f.put(promotion.ID, updated)
+	return nil
+}
+
+func (f *fakePromotions) FindByID(ctx context.Context, id string) (*entity.Promotion, error) {
+	key, err := uuid.Parse(id)
+	if err != nil {
+		return nil, promotionRepository.ErrPromotionNotFound
+	}
+	promotion, ok := f.get(key)
+	if !ok {
+		return nil, promotionRepository.ErrPromotionNotFound
+	}
+	return &promotion, nil
+}
+
+func (f *fakePromotions) List(ctx context.Context, page pagination.Request) (*pagination.Page[*entity.Promotion], error) {
+	promotions := []*entity.Promotion{}
+	for _, promotion := range f.all() {
+		promotions = append(promotions, &promotion)
+	}
+	return &pagination.Page[*entity.Promotion]{Items: promotions}, nil
+}
+
+func (f *fakePromotions) LockByCode(ctx context.Context, code string) (*entity.Promotion, error) {
+	for _, promotion := range f.all() {
+		if promotion.Code == code {
+			return &promotion, nil
+		}
+	}
+	return nil, promotionRepository.ErrPromotionNotFound
+}
+
+func (f *fakePromotions) CountRedemptions(ctx context.Context, promotionID string, email string) (int, error) {
+	count := 0
+	for _, redemption := range f.redemptions.all() {
+		if redemption.PromotionID.String() == promotionID && redemption.Email == email {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakePromotions) Redeem(ctx context.Context, redemption *entity.PromotionRedemption) error {
+	if _, ok := f.redemptions.get(redemption.OrderID); ok {
+		return errors.New("order already redeemed a promotion")
+	}
+	promotion, ok := f.get(redemption.PromotionID)
+	if !ok {
+		return promotionRepository.ErrPromotionNotFound
+	}
+	redemption.ID = uuid.New()
+	f.redemptions.put(redemption.OrderID, *redemption)
+	promotion.Redemptions++
+	f.put(promotion.ID, promotion)
+	return nil
+}
+
+func (f *fakePromotions) Release(ctx context.Context, orderID string) error {
+	id := uuid.MustParse(orderID)
+	redemption, ok := f.redemptions.get(id)
+	if !ok {
+		return nil
+	}
+	f.redemptions.remove(id)
+	if promotion, ok := f.get(redemption.PromotionID); ok {
+		promotion.Redemptions--
+		f.put(promotion.ID, promotion)
+	}
+	return nil
+}
+
+type auditRecord struct {
+	Action   entity.AuditAction
+	EntityID string
+}
+
+// fakeAudit records what would have been audited.
+type fakeAudit struct {
+	history[auditRecord]
+}
+
+func (f *fakeAudit) Record(ctx context.Context, action entity.AuditAction, entityType entity.AuditEntityType, entityID string, before, after interface{}) error {
+	f.add(auditRecord{Action: action, EntityID: entityID})
+	return nil
+}
+
+func (f *fakeAudit) List(ctx context.Context, req dto.AdminListAuditReq) (*pagination.Page[*entity.AuditEvent], error) {
+	return &pagination.Page[*entity.AuditEvent]{}, nil
+}
+
+type outboxMessage struct {
+	EventType   string
+	AggregateID string
+	Payload     interface{}
+}
+
+// fakeOutbox keeps the enqueued messages, dropping them on rollback like
+// the outbox table.
+type fakeOutbox struct {
+	history[outboxMessage]
+}
+
+func (f *fakeOutbox) Enqueue(ctx context.Context, topic, eventType, aggregateType, aggregateID string, payload interface{}) error {
+	f.add(outboxMessage{EventType: eventType, AggregateID: aggregateID, Payload: payload})
+	return nil
+}
+
+func (f *fakeOutbox) events() []string {
+	var types []string
+	for _, m := range f.list() {
+		types = append(types, m.EventType)
+	}
+	return types
+}
+
+type refundCall struct {
+	PaymentIntentID string
+	Amount          entity.Money
+	IdempotencyKey  string
+}
+
+// fakePayments answers like Stripe would, with the errors set on it.
+type fakePayments struct {
+	mu        sync.Mutex
+	refundErr error
+	cancelErr error
+	refunds   []refundCall
+	canceled  []string
+	// onCancel runs inside CancelPaymentIntent, before it returns cancelErr
+	onCancel   func()
+	webhook    *entity.PaymentEvent
+	webhookErr error
+}
+
+func (f *fakePayments) Refund(ctx context.Context, paymentIntentID string, amount entity.Money, idempotencyKey string, metadata map[string]string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refunds = append(f.refunds, refundCall{paymentIntentID, amount, idempotencyKey})
+	if f.refundErr != nil {
+		return "", f.refundErr
+	}
+	return "re_" + idempotencyKey, nil
+}
+
+func (f *fakePayments) CancelPaymentIntent(ctx context.Context, paymentIntentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.canceled = append(f.canceled, paymentIntentID)
+	if f.onCancel != nil {
+		f.onCancel()
+	}
+	return f.cancelErr
+}
+
+func (f *fakePayments) ParseWebhook(payload []byte, signature string) (*entity.PaymentEvent, error) {
+	return f.webhook, f.webhookErr
+}
+
+type sentMail struct {
+	To       string
+	Template string
+	Model    map[string]interface{}
+}
+
+type fakeMail struct {
+	mu   sync.Mutex
+	err  error
+	sent []sentMail
+}
+
+func (f *fakeMail) SendTemplate(ctx context.Context, toEmail, toName, template string, model map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, sentMail{To: toEmail, Template: template, Model: model})
+	return nil
+}
+
+func (f *fakeMail) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+type fakeStorage struct{}
+
+func (fakeStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	return "https://bucket.example.com/" + key, nil
+}
+
+func (fakeStorage) SignedURL(key string, expires time.Time) (string, error) {
+	return "https://cdn.example.com/" + key + "?Expires=" + expires.UTC().Format(time.RFC3339), nil
+}
+
+// fakeProduction hands out a production job id without creating the job.
+type fakeProduction struct {
+	history[uuid.UUID]
+}
+
+func (f *fakeProduction) Start(ctx context.Context, order *entity.Order) (*entity.Job, error) {
+	f.add(order.ID)
+	return &entity.Job{ID: uuid.New()}, nil
+}
+
+func (f *fakeProduction) Sync(ctx context.Context, job *entity.Job) error {
+	return nil
+}
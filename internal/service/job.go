@@ -2,48 +2,120 @@ package service
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/playture/backend/internal/dto"
 	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/imaging"
 	jobRepository "github.com/playture/backend/internal/repository/job_repository"
 	orderRepository "github.com/playture/backend/internal/repository/order_repository"
-	"log/slog"
+	storageRepository "github.com/playture/backend/internal/repository/storage_repository"
+	"github.com/playture/backend/utils"
 )
 
+var ErrInvalidJob = errors.New("invalid job")
+
+// PipelineStatuses are the statuses a job is processed in, everything up to
+// a terminal status.
+var PipelineStatuses = []entity.JobStatus{
+	entity.JobStatusReceived,
+	entity.JobStatusProcessing,
+	entity.JobStatusVeoGenerating,
+	entity.JobStatusVeoCompleted,
+	entity.JobStatusQueProcessing,
+	entity.JobStatusRendering,
+}
+
+const defaultStyle = "default"
+
 type Job interface {
 	CreateJob(ctx context.Context, req dto.CreateJobReq) (dto.CreateJobRes, error) // from api
 	GetJob(ctx context.Context, id string) (entity.Job, error)                     // from api
-	ProcessJob(ctx context.Context, req entity.Job)                                // worker pool
 }
 
 type job struct {
-	logger    *slog.Logger
-	jobRepo   jobRepository.Repository
-	orderRepo orderRepository.Repository
+	logger      *slog.Logger
+	imaging     *imaging.Imaging
+	jobRepo     jobRepository.Repository
+	orderRepo   orderRepository.Repository
+	storageRepo storageRepository.Repository
 }
 
 func NewJob(logger *slog.Logger,
+	imaging *imaging.Imaging,
 	jobRepo jobRepository.Repository,
 	orderRepo orderRepository.Repository,
+	storageRepo storageRepository.Repository,
 ) Job {
 	return &job{
-		logger:    logger.With("layer", "servuce"),
-		jobRepo:   jobRepo,
-		orderRepo: orderRepo,
+		logger:      logger.With("layer", "JobService"),
+		imaging:     imaging,
+		jobRepo:     jobRepo,
+		orderRepo:   orderRepo,
+		storageRepo: storageRepo,
 	}
 }
 
+// CreateJob normalizes the uploaded photo before anything is stored, so the
+// pipeline only ever sees upright images within the configured bounds and
+// without the customer's EXIF metadata.
 func (j *job) CreateJob(ctx context.Context, req dto.CreateJobReq) (dto.CreateJobRes, error) {
 	lg := j.logger.With("method", "CreateJob")
-	lg.Info("create job")
-	return dto.CreateJobRes{}, nil
+
+	req.UserEmail, req.UserName = strings.TrimSpace(req.UserEmail), strings.TrimSpace(req.UserName)
+	if _, err := mail.ParseAddress(req.UserEmail); err != nil {
+		return dto.CreateJobRes{}, utils.WrapError("email", ErrInvalidJob)
+	}
+	if req.UserName == "" {
+		return dto.CreateJobRes{}, utils.WrapError("name is required", ErrInvalidJob)
+	}
+	if req.Style == "" {
+		req.Style = defaultStyle
+	}
+	if req.Image == nil {
+		return dto.CreateJobRes{}, utils.WrapError("image is required", ErrInvalidJob)
+	}
+
+	img, err := j.imaging.Normalize(req.Image)
+	if err != nil {
+		return dto.CreateJobRes{}, err
+	}
+
+	key := "inputs/" + uuid.NewString() + img.Extension
+	url, err := j.storageRepo.Put(ctx, key, img.ContentType, img.Data)
+	if err != nil {
+		return dto.CreateJobRes{}, err
+	}
+
+	now := time.Now().Unix()
+	id, err := j.jobRepo.Create(ctx, &entity.Job{
+		UserEmail:       req.UserEmail,
+		UserName:        req.UserName,
+		InputImageURL:   url,
+		InputImageS3Key: key,
+		Style:           req.Style,
+		Status:          entity.JobStatusReceived,
+		Priority:        entity.JobPriorityNormal,
+		IPAddress:       req.IPAddress,
+		UserAgent:       req.UserAgent,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+	if err != nil {
+		// the input stays in the bucket unreferenced
+		return dto.CreateJobRes{}, err
+	}
+
+	lg.Info("job created", "jobID", id, "width", img.Width, "height", img.Height, "orientation", img.Orientation)
+	return dto.CreateJobRes{ID: id, Status: entity.JobStatusReceived}, nil
 }
 func (j *job) GetJob(ctx context.Context, id string) (entity.Job, error) {
 	lg := j.logger.With("method", "GetJob")
 	lg.Info("get job")
 	return entity.Job{}, nil
 }
-func (j *job) ProcessJob(ctx context.Context, req entity.Job) {
-	lg := j.logger.With("method", "ProcessJob")
-	lg.Info("process job")
-
-}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const (
+	expiryTimeout = 10 * time.Second
+	// expiryRetryAfter postpones an order whose payment intent could not be
+	// canceled, so it does not hold up the orders listed behind it
+	expiryRetryAfter = 10 * time.Minute
+)
+
+// unpaidStatuses are the payment statuses an order can expire from.
+var unpaidStatuses = []entity.PaymentStatus{entity.PaymentStatusPending, entity.PaymentStatusFailed}
+
+type OrderExpiry interface {
+	// ExpireUnpaid expires up to limit orders left unpaid past their
+	// ExpiresAt and returns how many it expired. Their payment intents are
+	// canceled, their promotion redemptions given back and their jobs may
+	// be ordered again.
+	ExpireUnpaid(ctx context.Context, limit int) (int, error) // worker
+	// RevokeExpired ends the download access of up to limit delivered
+	// orders whose retention window passed and returns how many it revoked.
+	RevokeExpired(ctx context.Context, limit int) (int, error) // worker
+}
+
+type orderExpiry struct {
+	logger        *slog.Logger
+	uow           uow.IUOW
+	outbox        Outbox
+	jobRepo       jobRepository.Repository
+	orderRepo     orderRepository.Repository
+	deliveryRepo  deliveryRepository.Repository
+	paymentRepo   paymentRepository.Repository
+	promotionRepo promotionRepository.Repository
+}
+
+func NewOrderExpiry(logger *slog.Logger,
+	uow uow.IUOW,
+	outbox Outbox,
+	jobRepo jobRepository.Repository,
+	orderRepo orderRepository.Repository,
+	deliveryRepo deliveryRepository.Repository,
+	paymentRepo paymentRepository.Repository,
+	promotionRepo promotionRepository.Repository,
+) OrderExpiry {
+	return &orderExpiry{
+		logger:        logger.With("layer", "OrderExpiryService"),
+		uow:           uow,
+		outbox:        outbox,
+		jobRepo:       jobRepo,
+		orderRepo:     orderRepo,
+		deliveryRepo:  deliveryRepo,
+		paymentRepo:   paymentRepo,
+		promotionRepo: promotionRepo,
+	}
+}
+
+// ExpireUnpaid cancels the payment intent of each order before expiring it,
+// outside of any transaction. An intent Stripe refuses to cancel was paid
+// or is being paid, that order is left to the payment webhook. Either way
+// an order whose intent was not canceled is postponed by expiryRetryAfter,
+// it is looked at again should the webhook never come. A failure on one
+// order is logged and does not stop the others.
+func (e *orderExpiry) ExpireUnpaid(ctx context.Context, limit int) (int, error) {
+	lg := e.logger.With("method", "ExpireUnpaid")
+
+	now := time.Now().Unix()
+	orders, err := e.orderRepo.ListExpired(ctx, unpaidStatuses, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range orders {
+		olg := lg.With("orderID", order.ID, "paymentIntentID", order.StripePaymentIntentID)
+
+		if order.StripePaymentIntentID != "" {
+			err := e.paymentRepo.CancelPaymentIntent(ctx, order.StripePaymentIntentID)
+			switch {
+			case errors.Is(err, paymentRepository.ErrDeclined):
+				olg.Warn("payment intent can no longer be canceled, leaving the order to the webhook", "err", err)
+			case err != nil:
+				olg.Error("canceling payment intent failed", "err", err)
+			}
+			if err != nil {
+				if err := e.postpone(ctx, order.ID.String(), now); err != nil {
+					olg.Error("postponing order failed", "err", err)
+				}
+				continue
+			}
+		}
+
+		ok, err := uow.Do(ctx, e.uow, func(ctx context.Context) (bool, error) {
+			return e.expire(ctx, order.ID.String(), now)
+		}, expiryTimeout)
+		if err != nil {
+			olg.Error("expiring order failed", "err", err)
+			continue
+		}
+		if ok {
+			olg.Info("order expired", "expiresAt", order.ExpiresAt)
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expire marks an order expired under its lock and frees its job, unless
+// it was paid since it was listed.
+func (e *orderExpiry) expire(ctx context.Context, orderID string, now int64) (bool, error) {
+	order, err := e.orderRepo.Lock(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	unpaid := order.PaymentStatus == entity.PaymentStatusPending || order.PaymentStatus == entity.PaymentStatusFailed
+	if !unpaid || order.ExpiresAt == 0 || order.ExpiresAt >= now {
+		return false, nil
+	}
+
+	order.PaymentStatus = entity.PaymentStatusExpired
+	if err := e.orderRepo.UpdatePayment(ctx, order); err != nil {
+		return false, err
+	}
+	if order.PromotionID != nil {
+		if err := e.promotionRepo.Release(ctx, order.ID.String()); err != nil {
+			return false, err
+		}
+	}
+	if err := releaseJob(ctx, e.jobRepo, order); err != nil {
+		return false, err
+	}
+
+	return true, e.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderExpired,
+		string(entity.AuditEntityOrder), order.ID.String(), orderExpiredEvent{
+			OrderID:   order.ID.String(),
+			JobID:     order.JobID.String(),
+			UserEmail: order.UserEmail,
+			UserName:  order.UserName,
+			ExpiresAt: order.ExpiresAt,
+		})
+}
+
+// postpone moves the expiry of an order still unpaid to expiryRetryAfter
+// from now, under its lock so a payment confirmed meanwhile is kept.
+func (e *orderExpiry) postpone(ctx context.Context, orderID string, now int64) error {
+	_, err := e.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		order, err := e.orderRepo.Lock(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		unpaid := order.PaymentStatus == entity.PaymentStatusPending || order.PaymentStatus == entity.PaymentStatusFailed
+		if !unpaid || order.ExpiresAt == 0 || order.ExpiresAt >= now {
+			return nil, nil
+		}
+		order.ExpiresAt = now + int64(expiryRetryAfter/time.Second)
+		return nil, e.orderRepo.UpdateExpiry(ctx, order)
+	}, expiryTimeout)
+	return err
+}
+
+// releaseJob lets the sample job of order be ordered again, unless the job
+// already moved on to another order.
+func releaseJob(ctx context.Context, jobRepo jobRepository.Repository, order *entity.Order) error {
+	job, err := jobRepo.FindByField(ctx, "id", order.JobID)
+	switch {
+	case errors.Is(err, jobRepository.ErrJobNotFound):
+		return nil
+	case err != nil:
+		return err
+	case job.OrderID == nil || *job.OrderID != order.ID:
+		return nil
+	}
+	job.ConvertedToOrder, job.OrderID = false, nil
+	return jobRepo.SetOrder(ctx, job)
+}
+
+// RevokeExpired drops the download token of each delivery whose order's
+// access ended. Refunds end access themselves and announce it, so only
+// paid orders get an access_revoked event here.
+func (e *orderExpiry) RevokeExpired(ctx context.Context, limit int) (int, error) {
+	lg := e.logger.With("method", "RevokeExpired")
+
+	now := time.Now().Unix()
+	deliveries, err := e.deliveryRepo.ListExpired(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, delivery := range deliveries {
+		ok, err := uow.Do(ctx, e.uow, func(ctx context.Context) (bool, error) {
+			return e.revoke(ctx, delivery.OrderID.String(), now)
+		}, expiryTimeout)
+		if err != nil {
+			lg.Error("revoking access failed", "orderID", delivery.OrderID, "err", err)
+			continue
+		}
+		if ok {
+			lg.Info("download access revoked", "orderID", delivery.OrderID, "downloads", delivery.DownloadCount)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// revoke ends the delivery of an order under the order lock, unless it was
+// delivered again with a new retention window since it was listed.
+func (e *orderExpiry) revoke(ctx context.Context, orderID string, now int64) (bool, error) {
+	order, err := e.orderRepo.Lock(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if order.ExpiresAt == 0 || order.ExpiresAt >= now {
+		return false, nil
+	}
+	delivery, err := e.deliveryRepo.FindByOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if delivery.Status != entity.DeliveryStatusDelivered {
+		return false, nil
+	}
+
+	delivery.Status, delivery.Token, delivery.UpdatedAt = entity.DeliveryStatusRevoked, "", now
+	if err := e.deliveryRepo.Update(ctx, delivery); err != nil {
+		return false, err
+	}
+	if order.PaymentStatus != entity.PaymentStatusPaid {
+		return true, nil
+	}
+	return true, e.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderAccessRevoked,
+		string(entity.AuditEntityOrder), order.ID.String(), orderAccessRevokedEvent{
+			OrderID: order.ID.String(),
+			Reason:  "retention",
+		})
+}
+
+type orderExpiredEvent struct {
+	OrderID   string `json:"orderId"`
+	JobID     string `json:"jobId"`
+	UserEmail string `json:"userEmail"`
+	UserName  string `json:"userName"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+type expiryFixture struct {
+	svc        OrderExpiry
+	jobs       *jobMemory.JobMemory
+	orders     *order_memory.OrderMemory
+	deliveries *fakeDeliveries
+	outbox     *fakeOutbox
+	payments   *fakePayments
+	promotions *fakePromotions
+}
+
+func newExpiryFixture() *expiryFixture {
+	f := &expiryFixture{
+		jobs:       jobMemory.NewJobMemory(),
+		orders:     order_memory.NewOrderMemory(),
+		outbox:     &fakeOutbox{},
+		payments:   &fakePayments{},
+		promotions: newFakePromotions(),
+	}
+	f.deliveries = newFakeDeliveries(f.orders)
+	u := uow.NewMemoryUOW(f.jobs, f.orders, f.deliveries, f.outbox, f.promotions)
+	f.svc = NewOrderExpiry(testLogger, u, f.outbox, f.jobs, f.orders, f.deliveries, f.payments, f.promotions)
+	return f
+}
+
+// unpaidOrder stores a sample job ordered by a pending order with a payment
+// intent that expires at expiresAt.
+func (f *expiryFixture) unpaidOrder(t *testing.T, expiresAt int64) *entity.Order {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := f.jobs.Create(ctx, jobContract.NewJob(time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _ := f.jobs.FindByField(ctx, "id", id)
+
+	order := order_contract.NewOrder(job.ID, time.Now().Unix())
+	order.StripePaymentIntentID, order.ExpiresAt = "pi_"+order.ID.String(), expiresAt
+	if _, err := f.orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	job.ConvertedToOrder, job.OrderID = true, &order.ID
+	if err := f.jobs.SetOrder(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func (f *expiryFixture) find(t *testing.T, order *entity.Order) (*entity.Order, *entity.Job) {
+	t.Helper()
+	ctx := context.Background()
+	stored, err := f.orders.FindByField(ctx, "id", order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := f.jobs.FindByField(ctx, "id", order.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored, job
+}
+
+func TestExpireUnpaid(t *testing.T) {
+	f := newExpiryFixture()
+	ctx := context.Background()
+	now := time.Now()
+
+	promotion := &entity.Promotion{Code: "SPRING", Kind: entity.PromotionKindPercent, PercentOff: 20, Active: true}
+	if _, err := f.promotions.Create(ctx, promotion); err != nil {
+		t.Fatal(err)
+	}
+	overdue := f.unpaidOrder(t, now.Add(-time.Minute).Unix())
+	overdue.PromotionID = &promotion.ID
+	if err := f.orders.Update(ctx, overdue); err != nil {
+		t.Fatal(err)
+	}
+	err := f.promotions.Redeem(ctx, &entity.PromotionRedemption{
+		PromotionID: promotion.ID, OrderID: overdue.ID, Email: overdue.UserEmail, Discount: entity.Money{Minor: 1000, Currency: "USD"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := f.unpaidOrder(t, now.Add(time.Hour).Unix())
+
+	n, err := f.svc.ExpireUnpaid(ctx, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("ExpireUnpaid = %d, %v, want 1 expired", n, err)
+	}
+	if !slices.Equal(f.payments.canceled, []string{overdue.StripePaymentIntentID}) {
+		t.Errorf("canceled intents = %v, want only the overdue one", f.payments.canceled)
+	}
+
+	got, job := f.find(t, overdue)
+	if got.PaymentStatus != entity.PaymentStatusExpired || job.ConvertedToOrder || job.OrderID != nil {
+		t.Errorf("order %s with job ordered %v, want it expired and the job free", got.PaymentStatus, job.ConvertedToOrder)
+	}
+	released, _ := f.promotions.FindByID(ctx, promotion.ID.String())
+	used, _ := f.promotions.CountRedemptions(ctx, promotion.ID.String(), overdue.UserEmail)
+	if released.Redemptions != 0 || used != 0 {
+		t.Errorf("promotion used %d times, %d by the customer, want the redemption given back", released.Redemptions, used)
+	}
+	if events := f.outbox.events(); !slices.Equal(events, []string{entity.OutboxEventOrderExpired}) {
+		t.Errorf("events = %v", events)
+	}
+
+	if got, job := f.find(t, due); got.PaymentStatus != entity.PaymentStatusPending || !job.ConvertedToOrder {
+		t.Errorf("order within its window = %s, want it left pending", got.PaymentStatus)
+	}
+}
+
+// TestExpireUnpaidPostponesSkipped checks an order whose intent could not
+// be canceled does not keep the orders behind it from expiring.
+func TestExpireUnpaidPostponesSkipped(t *testing.T) {
+	for name, cancelErr := range map[string]error{
+		"declined":    paymentRepository.ErrDeclined,
+		"unreachable": errors.New("connection refused"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newExpiryFixture()
+			ctx := context.Background()
+			now := time.Now()
+
+			skipped := f.unpaidOrder(t, now.Add(-2*time.Hour).Unix())
+			behind := f.unpaidOrder(t, now.Add(-time.Hour).Unix())
+
+			f.payments.cancelErr = cancelErr
+			if n, err := f.svc.ExpireUnpaid(ctx, 1); err != nil || n != 0 {
+				t.Fatalf("ExpireUnpaid = %d, %v, want nothing expired", n, err)
+			}
+			got, _ := f.find(t, skipped)
+			if got.PaymentStatus != entity.PaymentStatusPending || got.ExpiresAt < now.Add(expiryRetryAfter).Unix() {
+				t.Fatalf("skipped order = %s expiring %d, want it pending and postponed", got.PaymentStatus, got.ExpiresAt)
+			}
+
+			f.payments.cancelErr = nil
+			if n, err := f.svc.ExpireUnpaid(ctx, 1); err != nil || n != 1 {
+				t.Fatalf("ExpireUnpaid = %d, %v, want the order behind expired", n, err)
+			}
+			if got, _ := f.find(t, behind); got.PaymentStatus != entity.PaymentStatusExpired {
+				t.Errorf("order behind = %s, want it expired", got.PaymentStatus)
+			}
+		})
+	}
+}
+
+func TestExpireUnpaidKeepsPaidOrder(t *testing.T) {
+	f := newExpiryFixture()
+	ctx := context.Background()
+
+	order := f.unpaidOrder(t, time.Now().Add(-time.Minute).Unix())
+	// the payment lands while the intent is being canceled
+	f.payments.cancelErr = paymentRepository.ErrDeclined
+	f.payments.onCancel = func() {
+		paid := *order
+		paid.PaymentStatus, paid.ExpiresAt = entity.PaymentStatusPaid, 0
+		if err := f.orders.Update(ctx, &paid); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := f.svc.ExpireUnpaid(ctx, 10); err != nil {
+		t.Fatalf("ExpireUnpaid: %v", err)
+	}
+	got, job := f.find(t, order)
+	if got.PaymentStatus != entity.PaymentStatusPaid || got.ExpiresAt != 0 || !job.ConvertedToOrder {
+		t.Errorf("order = %s expiring %d, want it paid and untouched", got.PaymentStatus, got.ExpiresAt)
+	}
+}
+
+func TestRevokeExpired(t *testing.T) {
+	f := newExpiryFixture()
+	ctx := context.Background()
+	now := time.Now()
+
+	deliver := func(expiresAt int64) *entity.Order {
+		order := f.unpaidOrder(t, expiresAt)
+		order.PaymentStatus, order.DeliveredAt = entity.PaymentStatusPaid, now.Add(-30*24*time.Hour).Unix()
+		if err := f.orders.Update(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		f.deliveries.put(order.ID, entity.Delivery{OrderID: order.ID, Status: entity.DeliveryStatusDelivered, Token: order.ID.String()})
+		return order
+	}
+	expired := deliver(now.Add(-time.Minute).Unix())
+	current := deliver(now.Add(time.Hour).Unix())
+
+	if n, err := f.svc.RevokeExpired(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("RevokeExpired = %d, %v, want 1 revoked", n, err)
+	}
+	if delivery, _ := f.deliveries.FindByOrder(ctx, expired.ID.String()); delivery.Status != entity.DeliveryStatusRevoked || delivery.Token != "" {
+		t.Errorf("expired delivery = %+v, want it revoked", delivery)
+	}
+	if delivery, _ := f.deliveries.FindByOrder(ctx, current.ID.String()); delivery.Status != entity.DeliveryStatusDelivered {
+		t.Errorf("current delivery = %+v, want it kept", delivery)
+	}
+	if events := f.outbox.events(); !slices.Equal(events, []string{entity.OutboxEventOrderAccessRevoked}) {
+		t.Errorf("events = %v", events)
+	}
+}
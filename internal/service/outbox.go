@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	outboxRepository "github.com/playture/backend/internal/repository/outbox_repository"
+	"github.com/playture/backend/utils"
+)
+
+type Outbox interface {
+	// Enqueue stores a message in the transaction carried by ctx. It is
+	// published by the relay once that commits and dropped on rollback.
+	Enqueue(ctx context.Context, topic, eventType, aggregateType, aggregateID string, payload interface{}) error
+}
+
+type outbox struct {
+	logger     *slog.Logger
+	outboxRepo outboxRepository.Repository
+}
+
+func NewOutbox(logger *slog.Logger,
+	outboxRepo outboxRepository.Repository,
+) Outbox {
+	return &outbox{
+		logger:     logger.With("layer", "OutboxService"),
+		outboxRepo: outboxRepo,
+	}
+}
+
+func (o *outbox) Enqueue(
+	ctx context.Context,
+	topic, eventType, aggregateType, aggregateID string,
+	payload interface{},
+) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return utils.WrapError("marshal outbox payload", err)
+	}
+
+	msg := &entity.OutboxMessage{
+		Topic:         topic,
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       raw,
+		CreatedAt:     time.Now().Unix(),
+	}
+	if _, err := o.outboxRepo.Create(ctx, msg); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const paymentTimeout = 10 * time.Second
+
+type Payments interface {
+	// HandleWebhook verifies and applies a payment provider webhook. A paid
+	// order starts production in the same transaction. Redelivered events
+	// and events for unknown orders are accepted without effect, so the
+	// provider stops retrying them.
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+}
+
+type payments struct {
+	logger      *slog.Logger
+	uow         uow.IUOW
+	outbox      Outbox
+	production  Production
+	jobRepo     jobRepository.Repository
+	orderRepo   orderRepository.Repository
+	paymentRepo paymentRepository.Repository
+}
+
+func NewPayments(logger *slog.Logger,
+	uow uow.IUOW,
+	outbox Outbox,
+	production Production,
+	jobRepo jobRepository.Repository,
+	orderRepo orderRepository.Repository,
+	paymentRepo paymentRepository.Repository,
+) Payments {
+	return &payments{
+		logger:      logger.With("layer", "PaymentService"),
+		uow:         uow,
+		outbox:      outbox,
+		production:  production,
+		jobRepo:     jobRepo,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+	}
+}
+
+func (p *payments) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := p.paymentRepo.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+	lg := p.logger.With("method", "HandleWebhook", "eventID", event.ID, "type", event.Type, "orderID", event.OrderID)
+
+	var apply func(ctx context.Context, order *entity.Order, event *entity.PaymentEvent) error
+	switch event.Type {
+	case entity.PaymentEventIntentSucceeded:
+		apply = p.confirm
+	case entity.PaymentEventIntentFailed:
+		apply = p.fail
+	default:
+		lg.Debug("event ignored")
+		return nil
+	}
+	if _, err := uuid.Parse(event.OrderID); err != nil {
+		// retrying cannot fix the metadata, ack so Stripe stops sending it
+		lg.Warn("payment intent without a valid order_id metadata", "paymentIntentID", event.PaymentIntentID)
+		return nil
+	}
+
+	_, err = p.uow.Do(ctx, func(ctx context.Context) (interface{}, error) {
+		order, err := p.orderRepo.Lock(ctx, event.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, apply(ctx, order, event)
+	}, paymentTimeout)
+	switch {
+	case errors.Is(err, orderRepository.ErrOrderNotFound):
+		lg.Warn("event for unknown order")
+		return nil
+	case err != nil:
+		lg.Error("applying event failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// confirm marks the order paid and starts its production. Orders that were
+// already paid or refunded are left alone. An order paid after it expired
+// is taken up again unless its job was ordered anew in the meantime. A
+// payment that does not match the order amount is not honoured, it has to
+// be refunded by hand.
+func (p *payments) confirm(ctx context.Context, order *entity.Order, event *entity.PaymentEvent) error {
+	if event.Amount != order.Amount {
+		p.logger.Error("payment does not match the order amount, refund it", "method", "confirm",
+			"orderID", order.ID, "paymentIntentID", event.PaymentIntentID,
+			"received", event.Amount.String(), "amount", order.Amount.String())
+		return nil
+	}
+
+	switch order.PaymentStatus {
+	case entity.PaymentStatusPending, entity.PaymentStatusFailed:
+	case entity.PaymentStatusExpired:
+		reopened, err := p.reopen(ctx, order)
+		if err != nil || !reopened {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	order.PaymentStatus, order.PaidAt = entity.PaymentStatusPaid, time.Now().Unix()
+	order.StripePaymentIntentID = event.PaymentIntentID
+	if event.CustomerID != "" {
+		order.StripeCustomerID = event.CustomerID
+	}
+	if err := p.orderRepo.UpdatePayment(ctx, order); err != nil {
+		return err
+	}
+	// the payment window is over, delivery sets when access ends
+	order.ExpiresAt = 0
+	if err := p.orderRepo.UpdateExpiry(ctx, order); err != nil {
+		return err
+	}
+
+	job, err := p.production.Start(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("order paid", "method", "confirm", "orderID", order.ID, "productionJobID", job.ID)
+	return p.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderPaid,
+		string(entity.AuditEntityOrder), order.ID.String(), orderPaidEvent{
+			OrderID:         order.ID.String(),
+			ProductionJobID: job.ID.String(),
+			UserEmail:       order.UserEmail,
+			UserName:        order.UserName,
+			Amount:          order.Amount,
+		})
+}
+
+// reopen points the job of an expired order back at it. When the job was
+// ordered again the payment cannot be honoured and has to be refunded by
+// hand, the order stays expired.
+func (p *payments) reopen(ctx context.Context, order *entity.Order) (bool, error) {
+	job, err := p.jobRepo.FindByField(ctx, "id", order.JobID)
+	if err != nil {
+		return false, err
+	}
+	if job.ConvertedToOrder && (job.OrderID == nil || *job.OrderID != order.ID) {
+		p.logger.Error("expired order was paid after its job was ordered again, refund it",
+			"method", "reopen", "orderID", order.ID, "jobID", job.ID)
+		return false, nil
+	}
+	job.ConvertedToOrder, job.OrderID = true, &order.ID
+	return true, p.jobRepo.SetOrder(ctx, job)
+}
+
+// fail records a declined payment of a pending order, the customer may
+// still pay it with another attempt on the same intent.
+func (p *payments) fail(ctx context.Context, order *entity.Order, event *entity.PaymentEvent) error {
+	if order.PaymentStatus != entity.PaymentStatusPending {
+		return nil
+	}
+	order.PaymentStatus, order.StripePaymentIntentID = entity.PaymentStatusFailed, event.PaymentIntentID
+	return p.orderRepo.UpdatePayment(ctx, order)
+}
+
+type orderPaidEvent struct {
+	OrderID         string       `json:"orderId"`
+	ProductionJobID string       `json:"productionJobId"`
+	UserEmail       string       `json:"userEmail"`
+	UserName        string       `json:"userName"`
+	Amount          entity.Money `json:"amount"`
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/entity"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+type paymentFixture struct {
+	svc        Payments
+	jobs       *jobMemory.JobMemory
+	orders     *order_memory.OrderMemory
+	outbox     *fakeOutbox
+	production *fakeProduction
+	payments   *fakePayments
+}
+
+func newPaymentFixture() *paymentFixture {
+	f := &paymentFixture{
+		jobs:       jobMemory.NewJobMemory(),
+		orders:     order_memory.NewOrderMemory(),
+		outbox:     &fakeOutbox{},
+		production: &fakeProduction{},
+		payments:   &fakePayments{},
+	}
+	u := uow.NewMemoryUOW(f.jobs, f.orders, f.outbox, f.production)
+	f.svc = NewPayments(testLogger, u, f.outbox, f.production, f.jobs, f.orders, f.payments)
+	return f
+}
+
+// order stores a sample job ordered by a USD 49.99 order in status.
+func (f *paymentFixture) order(t *testing.T, status entity.PaymentStatus) (*entity.Job, *entity.Order) {
+	t.Helper()
+	ctx := context.Background()
+
+	id, err := f.jobs.Create(ctx, jobContract.NewJob(time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _ := f.jobs.FindByField(ctx, "id", id)
+
+	order := order_contract.NewOrder(job.ID, time.Now().Unix())
+	order.PaymentStatus, order.ExpiresAt = status, time.Now().Add(time.Hour).Unix()
+	if _, err := f.orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if status != entity.PaymentStatusExpired {
+		f.link(t, job, order)
+	}
+	return job, order
+}
+
+func (f *paymentFixture) link(t *testing.T, job *entity.Job, order *entity.Order) {
+	t.Helper()
+	job.ConvertedToOrder, job.OrderID = true, &order.ID
+	if err := f.jobs.SetOrder(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *paymentFixture) deliver(t *testing.T, eventType string, order *entity.Order, amount entity.Money) {
+	t.Helper()
+	f.payments.webhook = &entity.PaymentEvent{
+		ID:              "evt_" + uuid.NewString(),
+		Type:            eventType,
+		PaymentIntentID: "pi_1",
+		CustomerID:      "cus_1",
+		OrderID:         order.ID.String(),
+		Amount:          amount,
+		CreatedAt:       time.Now().Unix(),
+	}
+	if err := f.svc.HandleWebhook(context.Background(), []byte("{}"), "sig"); err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+}
+
+func (f *paymentFixture) find(t *testing.T, id uuid.UUID) *entity.Order {
+	t.Helper()
+	order, err := f.orders.FindByField(context.Background(), "id", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestHandleWebhookConfirm(t *testing.T) {
+	f := newPaymentFixture()
+	_, order := f.order(t, entity.PaymentStatusPending)
+
+	f.deliver(t, entity.PaymentEventIntentSucceeded, order, order.Amount)
+
+	got := f.find(t, order.ID)
+	if got.PaymentStatus != entity.PaymentStatusPaid || got.PaidAt == 0 || got.ExpiresAt != 0 {
+		t.Errorf("order = %s paid at %d expiring %d, want paid without expiry", got.PaymentStatus, got.PaidAt, got.ExpiresAt)
+	}
+	if got.StripePaymentIntentID != "pi_1" || got.StripeCustomerID != "cus_1" {
+		t.Errorf("stripe ids = %s, %s", got.StripePaymentIntentID, got.StripeCustomerID)
+	}
+	if started := f.production.list(); !slices.Equal(started, []uuid.UUID{order.ID}) {
+		t.Errorf("production started for %v", started)
+	}
+	if events := f.outbox.events(); !slices.Equal(events, []string{entity.OutboxEventOrderPaid}) {
+		t.Errorf("events = %v", events)
+	}
+
+	// a redelivered event changes nothing
+	f.deliver(t, entity.PaymentEventIntentSucceeded, order, order.Amount)
+	if len(f.production.list()) != 1 || len(f.outbox.events()) != 1 {
+		t.Errorf("redelivery started production again or published again")
+	}
+}
+
+func TestHandleWebhookAmountMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount entity.Money
+	}{
+		{"less", entity.Money{Minor: 100, Currency: "USD"}},
+		{"more", entity.Money{Minor: 5000, Currency: "USD"}},
+		{"other currency", entity.Money{Minor: 4999, Currency: "EUR"}},
+		{"missing", entity.Money{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture()
+			_, order := f.order(t, entity.PaymentStatusPending)
+
+			f.deliver(t, entity.PaymentEventIntentSucceeded, order, tt.amount)
+
+			if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusPending {
+				t.Errorf("order = %s, want it left pending", got.PaymentStatus)
+			}
+			if len(f.production.list()) != 0 || len(f.outbox.events()) != 0 {
+				t.Errorf("mismatched payment started production or published %v", f.outbox.events())
+			}
+		})
+	}
+}
+
+func TestHandleWebhookFail(t *testing.T) {
+	f := newPaymentFixture()
+	_, order := f.order(t, entity.PaymentStatusPending)
+
+	f.deliver(t, entity.PaymentEventIntentFailed, order, entity.Money{Currency: "USD"})
+	if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusFailed || got.StripePaymentIntentID != "pi_1" {
+		t.Errorf("order = %s with intent %q, want failed", got.PaymentStatus, got.StripePaymentIntentID)
+	}
+
+	// another attempt on the same intent goes through
+	f.deliver(t, entity.PaymentEventIntentSucceeded, order, order.Amount)
+	if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusPaid {
+		t.Errorf("order = %s, want paid after a retry", got.PaymentStatus)
+	}
+
+	// a late failure does not undo the payment
+	f.deliver(t, entity.PaymentEventIntentFailed, order, entity.Money{Currency: "USD"})
+	if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusPaid {
+		t.Errorf("order = %s, want still paid", got.PaymentStatus)
+	}
+}
+
+func TestHandleWebhookExpiredOrder(t *testing.T) {
+	t.Run("reopened", func(t *testing.T) {
+		f := newPaymentFixture()
+		job, order := f.order(t, entity.PaymentStatusExpired)
+
+		f.deliver(t, entity.PaymentEventIntentSucceeded, order, order.Amount)
+
+		if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusPaid {
+			t.Errorf("order = %s, want paid", got.PaymentStatus)
+		}
+		got, _ := f.jobs.FindByField(context.Background(), "id", job.ID)
+		if !got.ConvertedToOrder || got.OrderID == nil || *got.OrderID != order.ID {
+			t.Errorf("job points at %v, want the reopened order", got.OrderID)
+		}
+	})
+
+	t.Run("job ordered again", func(t *testing.T) {
+		f := newPaymentFixture()
+		job, order := f.order(t, entity.PaymentStatusExpired)
+		newer := order_contract.NewOrder(job.ID, time.Now().Unix())
+		if _, err := f.orders.Create(context.Background(), newer); err != nil {
+			t.Fatal(err)
+		}
+		f.link(t, job, newer)
+
+		f.deliver(t, entity.PaymentEventIntentSucceeded, order, order.Amount)
+
+		if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusExpired {
+			t.Errorf("order = %s, want it left expired", got.PaymentStatus)
+		}
+		got, _ := f.jobs.FindByField(context.Background(), "id", job.ID)
+		if got.OrderID == nil || *got.OrderID != newer.ID {
+			t.Errorf("job points at %v, want the newer order", got.OrderID)
+		}
+		if len(f.production.list()) != 0 {
+			t.Errorf("production started for an order that was not reopened")
+		}
+	})
+}
+
+func TestHandleWebhookIgnored(t *testing.T) {
+	f := newPaymentFixture()
+	_, order := f.order(t, entity.PaymentStatusPending)
+
+	for _, orderID := range []string{"", "not-a-uuid", uuid.NewString()} {
+		f.payments.webhook = &entity.PaymentEvent{Type: entity.PaymentEventIntentSucceeded, OrderID: orderID, Amount: order.Amount}
+		if err := f.svc.HandleWebhook(context.Background(), nil, ""); err != nil {
+			t.Errorf("order_id %q: HandleWebhook = %v, want it acknowledged", orderID, err)
+		}
+	}
+	f.payments.webhook = &entity.PaymentEvent{Type: "charge.refunded", OrderID: order.ID.String()}
+	if err := f.svc.HandleWebhook(context.Background(), nil, ""); err != nil {
+		t.Errorf("other event type: HandleWebhook = %v", err)
+	}
+	if got := f.find(t, order.ID); got.PaymentStatus != entity.PaymentStatusPending {
+		t.Errorf("order = %s, want untouched", got.PaymentStatus)
+	}
+
+	f.payments.webhook, f.payments.webhookErr = nil, paymentRepository.ErrInvalidSignature
+	if err := f.svc.HandleWebhook(context.Background(), nil, ""); !errors.Is(err, paymentRepository.ErrInvalidSignature) {
+		t.Errorf("bad signature: HandleWebhook = %v, want ErrInvalidSignature", err)
+	}
+}
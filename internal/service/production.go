@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	productRepository "github.com/playture/backend/internal/repository/product_repository"
+)
+
+type Production interface {
+	// Start creates the production job of a paid order and links it through
+	// ProductionJobID. It runs inside the transaction that holds the order
+	// lock and does nothing for an order that already has one.
+	Start(ctx context.Context, order *entity.Order) (*entity.Job, error)
+	// Sync moves the production status of the order job renders to follow
+	// the job's status. Timeline calls it on every transition, it ignores
+	// jobs that are not production jobs.
+	Sync(ctx context.Context, job *entity.Job) error
+}
+
+type production struct {
+	logger       *slog.Logger
+	outbox       Outbox
+	jobRepo      jobRepository.Repository
+	orderRepo    orderRepository.Repository
+	productRepo  productRepository.Repository
+	deliveryRepo deliveryRepository.Repository
+}
+
+func NewProduction(logger *slog.Logger,
+	outbox Outbox,
+	jobRepo jobRepository.Repository,
+	orderRepo orderRepository.Repository,
+	productRepo productRepository.Repository,
+	deliveryRepo deliveryRepository.Repository,
+) Production {
+	return &production{
+		logger:       logger.With("layer", "ProductionService"),
+		outbox:       outbox,
+		jobRepo:      jobRepo,
+		orderRepo:    orderRepo,
+		productRepo:  productRepo,
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+// Start renders the sample the order was placed for again, at the specs of
+// the product currently sold for the order type and ahead of sample jobs in
+// the queue. The render is clean unless the product is not watermark-free,
+// then Watermarked tells the render step to keep the watermark. Without an
+// active product the job renders clean at the template's own settings.
+func (p *production) Start(ctx context.Context, order *entity.Order) (*entity.Job, error) {
+	lg := p.logger.With("method", "Start", "orderID", order.ID)
+
+	if order.ProductionJobID != nil {
+		return p.jobRepo.FindByField(ctx, "id", *order.ProductionJobID)
+	}
+
+	source, err := p.jobRepo.FindByField(ctx, "id", order.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	job := &entity.Job{
+		UserEmail:       source.UserEmail,
+		UserName:        source.UserName,
+		InputImageURL:   source.InputImageURL,
+		InputImageS3Key: source.InputImageS3Key,
+		Style:           source.Style,
+		Status:          entity.JobStatusReceived,
+		OrderID:         &order.ID,
+		SourceJobID:     &source.ID,
+		Priority:        entity.JobPriorityProduction,
+		IPAddress:       source.IPAddress,
+		UserAgent:       source.UserAgent,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	switch product, err := p.productRepo.FindActive(ctx, order.OrderType); {
+	case err == nil:
+		job.VideoWidth, job.VideoHeight, job.MaxDurationSeconds = product.VideoWidth, product.VideoHeight, product.MaxDurationSeconds
+		job.Watermarked = !product.WatermarkFree
+	case errors.Is(err, productRepository.ErrProductNotFound):
+		lg.Warn("no active product, rendering at template defaults", "orderType", order.OrderType.String())
+	default:
+		return nil, err
+	}
+
+	id, err := p.jobRepo.Create(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if job, err = p.jobRepo.FindByField(ctx, "id", id); err != nil {
+		return nil, err
+	}
+
+	order.ProductionJobID, order.ProductionStatus = &job.ID, entity.ProductionStatusPending
+	if err := p.orderRepo.UpdateProduction(ctx, order); err != nil {
+		return nil, err
+	}
+	if err := p.enqueueChanged(ctx, order); err != nil {
+		return nil, err
+	}
+
+	lg.Info("production job created", "jobID", job.ID, "sourceJobID", source.ID)
+	return job, nil
+}
+
+func (p *production) Sync(ctx context.Context, job *entity.Job) error {
+	if !job.IsProduction() {
+		return nil
+	}
+	lg := p.logger.With("method", "Sync", "jobID", job.ID)
+
+	found, err := p.orderRepo.FindByField(ctx, "production_job_id", job.ID)
+	if errors.Is(err, orderRepository.ErrOrderNotFound) {
+		lg.Warn("production job without order")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	order, err := p.orderRepo.Lock(ctx, found.ID.String())
+	if err != nil {
+		return err
+	}
+
+	status := productionStatus(job.Status)
+	if status == order.ProductionStatus {
+		return nil
+	}
+	order.ProductionStatus = status
+	if err := p.orderRepo.UpdateProduction(ctx, order); err != nil {
+		return err
+	}
+
+	// the delivery worker sets DeliveredAt once the link or email went out
+	if status == entity.ProductionStatusCompleted && order.DeliveredAt == 0 {
+		if err := p.deliveryRepo.Queue(ctx, order.ID.String(), time.Now().Unix()); err != nil {
+			return err
+		}
+	}
+
+	lg.Info("production status changed", "orderID", order.ID, "productionStatus", status.String())
+	return p.enqueueChanged(ctx, order)
+}
+
+func (p *production) enqueueChanged(ctx context.Context, order *entity.Order) error {
+	return p.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderProductionChanged,
+		string(entity.AuditEntityOrder), order.ID.String(), orderProductionChangedEvent{
+			OrderID:          order.ID.String(),
+			ProductionJobID:  order.ProductionJobID.String(),
+			ProductionStatus: order.ProductionStatus,
+			DeliveryMethod:   order.DeliveryMethod,
+			DeliveredAt:      order.DeliveredAt,
+			UserEmail:        order.UserEmail,
+			UserName:         order.UserName,
+		})
+}
+
+// productionStatus maps a job status onto the production status of the
+// order it renders. A retried job goes back to PENDING.
+func productionStatus(status entity.JobStatus) entity.ProductionStatus {
+	switch {
+	case status == entity.JobStatusCompleted:
+		return entity.ProductionStatusCompleted
+	case status.IsTerminal():
+		return entity.ProductionStatusFailed
+	case status.IsActive():
+		return entity.ProductionStatusProcessing
+	default:
+		return entity.ProductionStatusPending
+	}
+}
+
+type orderProductionChangedEvent struct {
+	OrderID          string                  `json:"orderId"`
+	ProductionJobID  string                  `json:"productionJobId"`
+	ProductionStatus entity.ProductionStatus `json:"productionStatus"`
+	DeliveryMethod   entity.DeliveryMethod   `json:"deliveryMethod"`
+	DeliveredAt      int64                   `json:"deliveredAt,omitempty"`
+	UserEmail        string                  `json:"userEmail"`
+	UserName         string                  `json:"userName"`
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/playture/backend/internal/entity"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+)
+
+func TestProductionStartFollowsProduct(t *testing.T) {
+	tests := []struct {
+		name        string
+		product     *entity.Product
+		watermarked bool
+		width       int
+	}{
+		{"watermark-free", &entity.Product{WatermarkFree: true, VideoWidth: 1920, VideoHeight: 1080, MaxDurationSeconds: 60}, false, 1920},
+		{"watermarked", &entity.Product{VideoWidth: 1280, VideoHeight: 720, MaxDurationSeconds: 30}, true, 1280},
+		{"no active product", nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs, orders, products := jobMemory.NewJobMemory(), order_memory.NewOrderMemory(), newFakeProducts()
+			svc := NewProduction(testLogger, &fakeOutbox{}, jobs, orders, products, newFakeDeliveries(orders))
+
+			if tt.product != nil {
+				tt.product.OrderType, tt.product.Active = entity.OrderTypeBasic, true
+				if _, err := products.Create(ctx, tt.product); err != nil {
+					t.Fatal(err)
+				}
+			}
+			sourceID, err := jobs.Create(ctx, jobContract.NewJob(time.Now().Unix()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			source, _ := jobs.FindByField(ctx, "id", sourceID)
+			order := order_contract.NewOrder(source.ID, time.Now().Unix())
+			order.OrderType, order.PaymentStatus = entity.OrderTypeBasic, entity.PaymentStatusPaid
+			if _, err := orders.Create(ctx, order); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := svc.Start(ctx, order)
+			if err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if !job.IsProduction() || job.Watermarked != tt.watermarked || job.VideoWidth != tt.width {
+				t.Errorf("production job watermarked %v at width %d, want %v at %d", job.Watermarked, job.VideoWidth, tt.watermarked, tt.width)
+			}
+		})
+	}
+}
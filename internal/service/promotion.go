@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/repository/pagination"
+	promotionRepository "github.com/playture/backend/internal/repository/promotion_repository"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/utils"
+)
+
+var (
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	// ErrPromoNotApplicable covers every reason a code cannot be used on an
+	// order, the wrapped message says which.
+	ErrPromoNotApplicable = errors.New("promo code cannot be applied")
+)
+
+type Promotions interface {
+	ListPromotions(ctx context.Context, req dto.AdminListPromotionsReq) (*pagination.Page[*entity.Promotion], error)
+	GetPromotion(ctx context.Context, id string) (*entity.Promotion, error)
+	CreatePromotion(ctx context.Context, req dto.AdminPromotionReq) (*entity.Promotion, error)
+	UpdatePromotion(ctx context.Context, id string, req dto.AdminPromotionReq) (*entity.Promotion, error)
+	// Apply locks the promotion behind code, checks it against order and
+	// takes the discount off order.Amount. The returned redemption is
+	// recorded with Redeem once the order exists. Both must run in the
+	// transaction that creates the order, the lock keeps concurrent
+	// redemptions of one code from overrunning its limits.
+	Apply(ctx context.Context, code string, order *entity.Order) (*entity.PromotionRedemption, error)
+	Redeem(ctx context.Context, redemption *entity.PromotionRedemption) error
+}
+
+type promotions struct {
+	logger        *slog.Logger
+	uow           uow.IUOW
+	audit         Audit
+	promotionRepo promotionRepository.Repository
+}
+
+func NewPromotions(logger *slog.Logger,
+	uow uow.IUOW,
+	audit Audit,
+	promotionRepo promotionRepository.Repository,
+) Promotions {
+	return &promotions{
+		logger:        logger.With("layer", "PromotionService"),
+		uow:           uow,
+		audit:         audit,
+		promotionRepo: promotionRepo,
+	}
+}
+
+func (p *promotions) ListPromotions(ctx context.Context, req dto.AdminListPromotionsReq) (*pagination.Page[*entity.Promotion], error) {
+	return p.promotionRepo.List(ctx, pagination.Request{Cursor: req.Cursor, Limit: req.Limit})
+}
+
+func (p *promotions) GetPromotion(ctx context.Context, id string) (*entity.Promotion, error) {
+	return p.promotionRepo.FindByID(ctx, id)
+}
+
+func (p *promotions) CreatePromotion(ctx context.Context, req dto.AdminPromotionReq) (*entity.Promotion, error) {
+	lg := p.logger.With("method", "CreatePromotion")
+
+	now := time.Now().Unix()
+	promotion := &entity.Promotion{CreatedAt: now}
+	if err := applyPromotionReq(promotion, req, now); err != nil {
+		return nil, err
+	}
+
+	promotion, err := uow.Do(ctx, p.uow, func(ctx context.Context) (*entity.Promotion, error) {
+		id, err := p.promotionRepo.Create(ctx, promotion)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.audit.Record(ctx, entity.AuditActionPromotionCreate, entity.AuditEntityPromotion, id, nil, promotion); err != nil {
+			return nil, err
+		}
+		return promotion, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("create failed", "err", err)
+		return nil, err
+	}
+
+	lg.Info("promotion created", "promotionID", promotion.ID, "code", promotion.Code)
+	return promotion, nil
+}
+
+func (p *promotions) UpdatePromotion(ctx context.Context, id string, req dto.AdminPromotionReq) (*entity.Promotion, error) {
+	lg := p.logger.With("method", "UpdatePromotion", "promotionID", id)
+
+	promotion, err := uow.Do(ctx, p.uow, func(ctx context.Context) (*entity.Promotion, error) {
+		promotion, err := p.promotionRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		before := *promotion
+
+		if err := applyPromotionReq(promotion, req, time.Now().Unix()); err != nil {
+			return nil, err
+		}
+		if err := p.promotionRepo.Update(ctx, promotion); err != nil {
+			return nil, err
+		}
+		if err := p.audit.Record(ctx, entity.AuditActionPromotionUpdate, entity.AuditEntityPromotion, id, &before, promotion); err != nil {
+			return nil, err
+		}
+		return promotion, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("update failed", "err", err)
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (p *promotions) Apply(ctx context.Context, code string, order *entity.Order) (*entity.PromotionRedemption, error) {
+	promotion, err := p.promotionRepo.LockByCode(ctx, normalizeCode(code))
+	if err != nil {
+		if errors.Is(err, promotionRepository.ErrPromotionNotFound) {
+			return nil, utils.WrapError("unknown code", ErrPromoNotApplicable)
+		}
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case !promotion.ValidAt(now):
+		return nil, utils.WrapError("code is not valid now", ErrPromoNotApplicable)
+	case !promotion.AppliesTo(order.OrderType):
+		return nil, utils.WrapError("code does not apply to "+order.OrderType.String(), ErrPromoNotApplicable)
+	case promotion.MaxRedemptions > 0 && promotion.Redemptions >= promotion.MaxRedemptions:
+		return nil, utils.WrapError("code is used up", ErrPromoNotApplicable)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(order.UserEmail))
+	if promotion.MaxPerEmail > 0 {
+		used, err := p.promotionRepo.CountRedemptions(ctx, promotion.ID.String(), email)
+		if err != nil {
+			return nil, err
+		}
+		if used >= promotion.MaxPerEmail {
+			return nil, utils.WrapError("code already used by this customer", ErrPromoNotApplicable)
+		}
+	}
+
+	discount, err := promotion.Discount(order.Amount)
+	if err != nil {
+		return nil, utils.WrapError("code does not apply to "+order.Amount.Currency, ErrPromoNotApplicable)
+	}
+	if order.Amount, err = order.Amount.Sub(discount); err != nil {
+		return nil, err
+	}
+	order.Discount = discount
+	order.PromotionID = &promotion.ID
+
+	return &entity.PromotionRedemption{
+		PromotionID: promotion.ID,
+		OrderID:     order.ID,
+		Email:       email,
+		Discount:    discount,
+		CreatedAt:   now,
+	}, nil
+}
+
+func (p *promotions) Redeem(ctx context.Context, redemption *entity.PromotionRedemption) error {
+	return p.promotionRepo.Redeem(ctx, redemption)
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// applyPromotionReq validates req and copies it onto promotion.
+func applyPromotionReq(promotion *entity.Promotion, req dto.AdminPromotionReq, now int64) error {
+	code := normalizeCode(req.Code)
+	switch {
+	case code == "":
+		return utils.WrapError("code is required", ErrInvalidPromotion)
+	case req.EndsAt != 0 && req.EndsAt <= req.StartsAt:
+		return utils.WrapError("endsAt must be after startsAt", ErrInvalidPromotion)
+	case req.MaxRedemptions < 0 || req.MaxPerEmail < 0:
+		return utils.WrapError("limits must not be negative", ErrInvalidPromotion)
+	}
+	for _, t := range req.OrderTypes {
+		if t < entity.OrderTypeBasic || t > entity.OrderTypeCustom {
+			return utils.WrapError("unknown order type", ErrInvalidPromotion)
+		}
+	}
+
+	promotion.PercentOff, promotion.AmountOff = 0, entity.Money{}
+	switch req.Kind {
+	case entity.PromotionKindPercent:
+		if req.PercentOff < 1 || req.PercentOff > 100 {
+			return utils.WrapError("percentOff must be between 1 and 100", ErrInvalidPromotion)
+		}
+		promotion.PercentOff = req.PercentOff
+	case entity.PromotionKindFixed:
+		amount, err := entity.ParseMoney(req.AmountOff, req.Currency)
+		if err != nil {
+			return utils.WrapError(err.Error(), ErrInvalidPromotion)
+		}
+		if amount.Minor <= 0 {
+			return utils.WrapError("amountOff must be positive", ErrInvalidPromotion)
+		}
+		promotion.AmountOff = amount
+	default:
+		return utils.WrapError("unknown kind", ErrInvalidPromotion)
+	}
+
+	promotion.Code = code
+	promotion.Kind = req.Kind
+	promotion.StartsAt, promotion.EndsAt = req.StartsAt, req.EndsAt
+	promotion.MaxRedemptions, promotion.MaxPerEmail = req.MaxRedemptions, req.MaxPerEmail
+	promotion.OrderTypes = append([]entity.OrderType{}, req.OrderTypes...)
+	promotion.Active = req.Active
+	promotion.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	deliveryRepository "github.com/playture/backend/internal/repository/delivery_repository"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	mailRepository "github.com/playture/backend/internal/repository/mail_repository"
+	orderRepository "github.com/playture/backend/internal/repository/order_repository"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	refundRepository "github.com/playture/backend/internal/repository/refund_repository"
+	"github.com/playture/backend/internal/repository/uow"
+	"github.com/playture/backend/utils"
+)
+
+var (
+	ErrInvalidRefund      = errors.New("invalid refund")
+	ErrOrderNotRefundable = errors.New("only paid orders with a payment intent can be refunded")
+	ErrRefundFailed       = errors.New("refund failed")
+	// ErrRefundPending means Stripe could not be reached or answered with
+	// an error of its own, the refund is retried with the same idempotency
+	// key until it settles.
+	ErrRefundPending = errors.New("refund not confirmed yet")
+
+	errRefundSettled = errors.New("refund already settled")
+)
+
+const (
+	// refundRetryAfter leaves a pending refund to the request that created
+	// it before the sweeper retries it.
+	refundRetryAfter = time.Minute
+	// refundRetryWindow stays within the 24 hours Stripe keeps idempotency
+	// keys, a retry after that could refund twice. Older pending refunds
+	// have to be reconciled by hand.
+	refundRetryWindow = 23 * time.Hour
+)
+
+type Refunds interface {
+	// RefundOrder returns part or all of what was paid for an order. A full
+	// refund moves the order to REFUNDED and revokes its downloads.
+	RefundOrder(ctx context.Context, orderID string, req dto.AdminRefundOrderReq) (*dto.RefundOrderRes, error)
+	ListRefunds(ctx context.Context, orderID string) ([]*entity.Refund, error)
+	// RetryPending calls Stripe again for up to limit refunds left pending
+	// and returns how many settled.
+	RetryPending(ctx context.Context, limit int) (int, error) // worker
+}
+
+type refunds struct {
+	logger       *slog.Logger
+	uow          uow.IUOW
+	audit        Audit
+	outbox       Outbox
+	template     string
+	orderRepo    orderRepository.Repository
+	refundRepo   refundRepository.Repository
+	deliveryRepo deliveryRepository.Repository
+	jobRepo      jobRepository.Repository
+	paymentRepo  paymentRepository.Repository
+	mailRepo     mailRepository.Repository
+}
+
+func NewRefunds(logger *slog.Logger,
+	env *godotenv.Env,
+	uow uow.IUOW,
+	audit Audit,
+	outbox Outbox,
+	orderRepo orderRepository.Repository,
+	refundRepo refundRepository.Repository,
+	deliveryRepo deliveryRepository.Repository,
+	jobRepo jobRepository.Repository,
+	paymentRepo paymentRepository.Repository,
+	mailRepo mailRepository.Repository,
+) Refunds {
+	return &refunds{
+		logger:       logger.With("layer", "RefundService"),
+		uow:          uow,
+		audit:        audit,
+		outbox:       outbox,
+		template:     env.PostmarkRefundTemplateID,
+		orderRepo:    orderRepo,
+		refundRepo:   refundRepo,
+		deliveryRepo: deliveryRepo,
+		jobRepo:      jobRepo,
+		paymentRepo:  paymentRepo,
+		mailRepo:     mailRepo,
+	}
+}
+
+func (r *refunds) ListRefunds(ctx context.Context, orderID string) ([]*entity.Refund, error) {
+	if _, err := r.orderRepo.FindByField(ctx, "id", orderID); err != nil {
+		return nil, err
+	}
+	return r.refundRepo.ListByOrder(ctx, orderID)
+}
+
+// RefundOrder runs in three steps so no transaction stays open across the
+// Stripe call. The refund is first recorded as pending under the order
+// lock, pending refunds count as spent so concurrent requests cannot
+// refund more than was paid. Stripe is then called with the refund id as
+// idempotency key, and the outcome is written back. A refund left pending
+// by a crash or a Stripe error keeps its share reserved until RetryPending
+// settles it.
+func (r *refunds) RefundOrder(ctx context.Context, orderID string, req dto.AdminRefundOrderReq) (*dto.RefundOrderRes, error) {
+	lg := r.logger.With("method", "RefundOrder", "orderID", orderID)
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, utils.WrapError("reason is required", ErrInvalidRefund)
+	}
+
+	var paymentIntentID string
+	refund, err := uow.Do(ctx, r.uow, func(ctx context.Context) (*entity.Refund, error) {
+		order, err := r.orderRepo.Lock(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.PaymentStatus != entity.PaymentStatusPaid || order.StripePaymentIntentID == "" {
+			return nil, ErrOrderNotRefundable
+		}
+		paymentIntentID = order.StripePaymentIntentID
+
+		spent, err := r.refundRepo.Total(ctx, orderID, entity.RefundStatusPending, entity.RefundStatusSucceeded)
+		if err != nil {
+			return nil, err
+		}
+		left := entity.Money{Minor: order.Amount.Minor - spent, Currency: order.Amount.Currency}
+
+		amount := left
+		if req.Amount != "" {
+			if amount, err = entity.ParseMoney(req.Amount, order.Amount.Currency); err != nil {
+				return nil, utils.WrapError(err.Error(), ErrInvalidRefund)
+			}
+		}
+		if amount.Minor <= 0 || amount.Minor > left.Minor {
+			return nil, utils.WrapError("amount must be positive and at most "+left.String(), ErrInvalidRefund)
+		}
+
+		actor := ActorFrom(ctx)
+		now := time.Now().Unix()
+		refund := &entity.Refund{
+			OrderID:   order.ID,
+			Amount:    amount,
+			Reason:    reason,
+			ActorID:   actor.ID,
+			ActorName: actor.Name,
+			Status:    entity.RefundStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if _, err := r.refundRepo.Create(ctx, refund); err != nil {
+			return nil, err
+		}
+		return refund, nil
+	}, adminTimeout)
+	if err != nil {
+		lg.Error("refund rejected", "err", err)
+		return nil, err
+	}
+
+	// the money may have moved, record it even if the caller went away
+	ctx = context.WithoutCancel(ctx)
+	res, err := r.attempt(ctx, paymentIntentID, refund)
+	if err != nil {
+		return nil, err
+	}
+	switch res.Refund.Status {
+	case entity.RefundStatusFailed:
+		lg.Error("stripe declined the refund", "refundID", refund.ID, "err", res.Refund.FailureReason)
+		return nil, utils.WrapError(res.Refund.FailureReason, ErrRefundFailed)
+	case entity.RefundStatusPending:
+		lg.Warn("stripe refund not confirmed, it will be retried", "refundID", refund.ID, "err", res.Refund.FailureReason)
+		return res, utils.WrapError(res.Refund.FailureReason, ErrRefundPending)
+	}
+
+	lg.Info("order refunded", "refundID", refund.ID, "amount", refund.Amount.String(), "paymentStatus", res.Order.PaymentStatus.String())
+	r.notify(ctx, res)
+	return res, nil
+}
+
+// RetryPending skips refunds created too long ago for their idempotency key
+// to still be known to Stripe, and those just created whose request may
+// still be waiting on Stripe.
+func (r *refunds) RetryPending(ctx context.Context, limit int) (int, error) {
+	lg := r.logger.With("method", "RetryPending")
+
+	now := time.Now()
+	pending, err := r.refundRepo.ListPending(ctx, now.Add(-refundRetryWindow).Unix(), now.Add(-refundRetryAfter).Unix(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, refund := range pending {
+		rlg := lg.With("refundID", refund.ID, "orderID", refund.OrderID)
+
+		order, err := r.orderRepo.FindByField(ctx, "id", refund.OrderID.String())
+		if err != nil {
+			rlg.Error("loading order failed", "err", err)
+			continue
+		}
+		res, err := r.attempt(ctx, order.StripePaymentIntentID, refund)
+		switch {
+		case err != nil:
+			continue
+		case res.Refund.Status == entity.RefundStatusPending:
+			rlg.Warn("stripe refund still not confirmed", "err", res.Refund.FailureReason)
+			continue
+		case res.Refund.Status == entity.RefundStatusFailed:
+			rlg.Error("stripe declined the refund", "err", res.Refund.FailureReason)
+		default:
+			rlg.Info("pending refund settled", "amount", refund.Amount.String(), "paymentStatus", res.Order.PaymentStatus.String())
+			r.notify(ctx, res)
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// attempt calls Stripe with the refund id as idempotency key and records
+// the outcome, a Stripe error ends up in the refund's FailureReason. Only
+// failures to record it are returned.
+func (r *refunds) attempt(ctx context.Context, paymentIntentID string, refund *entity.Refund) (*dto.RefundOrderRes, error) {
+	stripeID, stripeErr := r.paymentRepo.Refund(ctx, paymentIntentID, refund.Amount, refund.ID.String(),
+		map[string]string{"order_id": refund.OrderID.String(), "refund_id": refund.ID.String()})
+
+	res, err := uow.Do(ctx, r.uow, func(ctx context.Context) (*dto.RefundOrderRes, error) {
+		return r.settle(ctx, refund, stripeID, stripeErr)
+	}, adminTimeout)
+	if err != nil && !errors.Is(err, errRefundSettled) {
+		r.logger.Error("recording refund result failed", "method", "attempt", "refundID", refund.ID, "stripeErr", stripeErr, "err", err)
+	}
+	return res, err
+}
+
+// settle writes the Stripe outcome of refund. Only a decline fails it, any
+// other error leaves it pending with the error kept for the admin. A refund
+// that brings the succeeded total up to the amount paid moves the order to
+// REFUNDED and revokes its download access.
+func (r *refunds) settle(ctx context.Context, refund *entity.Refund, stripeID string, stripeErr error) (*dto.RefundOrderRes, error) {
+	order, err := r.orderRepo.Lock(ctx, refund.OrderID.String())
+	if err != nil {
+		return nil, err
+	}
+	// another attempt may have settled it while Stripe was called
+	current, err := r.refundRepo.FindByID(ctx, refund.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != entity.RefundStatusPending {
+		return nil, errRefundSettled
+	}
+
+	refund.UpdatedAt = time.Now().Unix()
+	switch {
+	case errors.Is(stripeErr, paymentRepository.ErrDeclined):
+		refund.Status, refund.FailureReason = entity.RefundStatusFailed, stripeErr.Error()
+		return &dto.RefundOrderRes{Refund: refund, Order: order}, r.refundRepo.UpdateResult(ctx, refund)
+	case stripeErr != nil:
+		refund.FailureReason = stripeErr.Error()
+		return &dto.RefundOrderRes{Refund: refund, Order: order}, r.refundRepo.UpdateResult(ctx, refund)
+	}
+	refund.Status, refund.StripeRefundID, refund.FailureReason = entity.RefundStatusSucceeded, stripeID, ""
+	if err := r.refundRepo.UpdateResult(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	refunded, err := r.refundRepo.Total(ctx, order.ID.String(), entity.RefundStatusSucceeded)
+	if err != nil {
+		return nil, err
+	}
+	full := refunded >= order.Amount.Minor
+	if full && order.PaymentStatus != entity.PaymentStatusRefunded {
+		order.PaymentStatus = entity.PaymentStatusRefunded
+		if err := r.orderRepo.UpdatePayment(ctx, order); err != nil {
+			return nil, err
+		}
+		if err := r.revokeAccess(ctx, order, refund.UpdatedAt); err != nil {
+			return nil, err
+		}
+		// the customer paid nothing in the end and may order the sample again
+		if err := releaseJob(ctx, r.jobRepo, order); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.audit.Record(ctx, entity.AuditActionOrderRefund, entity.AuditEntityOrder, order.ID.String(), nil, refund); err != nil {
+		return nil, err
+	}
+	if err := r.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderRefunded,
+		string(entity.AuditEntityOrder), order.ID.String(), orderRefundedEvent{
+			OrderID:   order.ID.String(),
+			RefundID:  refund.ID.String(),
+			UserEmail: order.UserEmail,
+			UserName:  order.UserName,
+			Amount:    refund.Amount,
+			Full:      full,
+		}); err != nil {
+		return nil, err
+	}
+	return &dto.RefundOrderRes{Refund: refund, Order: order}, nil
+}
+
+// notify emails the customer about a settled refund. The refund stands
+// whether or not the email goes out, a failure is only logged.
+func (r *refunds) notify(ctx context.Context, res *dto.RefundOrderRes) {
+	order, refund := res.Order, res.Refund
+	err := r.mailRepo.SendTemplate(ctx, order.UserEmail, order.UserName, r.template, map[string]interface{}{
+		"name":     order.UserName,
+		"order_id": order.ID.String(),
+		"amount":   refund.Amount.String(),
+		"full":     order.PaymentStatus == entity.PaymentStatusRefunded,
+	})
+	if err != nil {
+		r.logger.Error("sending refund email failed", "method", "notify", "orderID", order.ID, "refundID", refund.ID, "err", err)
+	}
+}
+
+// revokeAccess ends the download access of a delivered order now. The
+// download token is dropped and so is the signed URL kept on the production
+// job, links already handed out stop working.
+func (r *refunds) revokeAccess(ctx context.Context, order *entity.Order, now int64) error {
+	if order.DeliveredAt == 0 {
+		return nil
+	}
+	order.ExpiresAt = now
+	if err := r.orderRepo.UpdateExpiry(ctx, order); err != nil {
+		return err
+	}
+
+	delivery, err := r.deliveryRepo.FindByOrder(ctx, order.ID.String())
+	switch {
+	case errors.Is(err, deliveryRepository.ErrDeliveryNotFound):
+	case err != nil:
+		return err
+	case delivery.Status == entity.DeliveryStatusDelivered:
+		delivery.Status, delivery.Token, delivery.UpdatedAt = entity.DeliveryStatusRevoked, "", now
+		if err := r.deliveryRepo.Update(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	if order.ProductionJobID != nil {
+		job, err := r.jobRepo.FindByField(ctx, "id", *order.ProductionJobID)
+		switch {
+		case errors.Is(err, jobRepository.ErrJobNotFound):
+		case err != nil:
+			return err
+		case job.SignedURL != "":
+			job.SignedURL, job.SignedURLExpiry = "", 0
+			if err := r.jobRepo.SetFinalVideo(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.outbox.Enqueue(ctx, entity.OutboxTopicOrderEvents, entity.OutboxEventOrderAccessRevoked,
+		string(entity.AuditEntityOrder), order.ID.String(), orderAccessRevokedEvent{
+			OrderID: order.ID.String(),
+			Reason:  "refunded",
+		})
+}
+
+type orderRefundedEvent struct {
+	OrderID   string       `json:"orderId"`
+	RefundID  string       `json:"refundId"`
+	UserEmail string       `json:"userEmail"`
+	UserName  string       `json:"userName"`
+	Amount    entity.Money `json:"amount"`
+	Full      bool         `json:"full"`
+}
+
+type orderAccessRevokedEvent struct {
+	OrderID string `json:"orderId"`
+	Reason  string `json:"reason"`
+}
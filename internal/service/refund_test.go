@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	"github.com/playture/backend/internal/infrastructure/godotenv"
+	jobContract "github.com/playture/backend/internal/repository/job_repository/job_contract"
+	jobMemory "github.com/playture/backend/internal/repository/job_repository/job_memory"
+	"github.com/playture/backend/internal/repository/order_repository/order_contract"
+	"github.com/playture/backend/internal/repository/order_repository/order_memory"
+	paymentRepository "github.com/playture/backend/internal/repository/payment_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+type refundFixture struct {
+	svc        *refunds
+	jobs       *jobMemory.JobMemory
+	orders     *order_memory.OrderMemory
+	refunds    *fakeRefunds
+	deliveries *fakeDeliveries
+	outbox     *fakeOutbox
+	payments   *fakePayments
+	mail       *fakeMail
+}
+
+func newRefundFixture() *refundFixture {
+	f := &refundFixture{
+		jobs:     jobMemory.NewJobMemory(),
+		orders:   order_memory.NewOrderMemory(),
+		refunds:  newFakeRefunds(),
+		outbox:   &fakeOutbox{},
+		payments: &fakePayments{},
+		mail:     &fakeMail{},
+	}
+	f.deliveries = newFakeDeliveries(f.orders)
+	audit := &fakeAudit{}
+	u := uow.NewMemoryUOW(f.jobs, f.orders, f.refunds, f.deliveries, f.outbox, audit)
+	f.svc = NewRefunds(testLogger, &godotenv.Env{PostmarkRefundTemplateID: "refund"}, u, audit, f.outbox,
+		f.orders, f.refunds, f.deliveries, f.jobs, f.payments, f.mail).(*refunds)
+	return f
+}
+
+// deliveredOrder stores a paid USD 49.99 order of a sample job whose
+// production render was delivered, with a signed URL on the production job.
+func (f *refundFixture) deliveredOrder(t *testing.T) *entity.Order {
+	t.Helper()
+	ctx := context.Background()
+
+	job := jobContract.NewJob(time.Now().Unix())
+	job.SignedURL, job.SignedURLExpiry = "https://cdn.example.com/final.mp4?sig", time.Now().Add(time.Hour).Unix()
+	id, err := f.jobs.Create(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	productionJobID := uuid.MustParse(id)
+	sampleID, err := f.jobs.Create(ctx, jobContract.NewJob(time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, _ := f.jobs.FindByField(ctx, "id", sampleID)
+
+	now := time.Now().Unix()
+	order := order_contract.NewOrder(sample.ID, now)
+	order.PaymentStatus, order.StripePaymentIntentID, order.PaidAt = entity.PaymentStatusPaid, "pi_1", now
+	order.ProductionJobID, order.ProductionStatus = &productionJobID, entity.ProductionStatusCompleted
+	order.DeliveredAt, order.ExpiresAt = now, now+int64(30*24*time.Hour/time.Second)
+	if _, err := f.orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	f.deliveries.put(order.ID, entity.Delivery{
+		OrderID: order.ID, Status: entity.DeliveryStatusDelivered, Token: "tok", DeliveredAt: now, CreatedAt: now, UpdatedAt: now,
+	})
+	sample.ConvertedToOrder, sample.OrderID = true, &order.ID
+	if err := f.jobs.SetOrder(ctx, sample); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// sampleOrdered reports whether the sample job of order still points at it.
+func (f *refundFixture) sampleOrdered(t *testing.T, order *entity.Order) bool {
+	t.Helper()
+	sample, err := f.jobs.FindByField(context.Background(), "id", order.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sample.ConvertedToOrder && sample.OrderID != nil && *sample.OrderID == order.ID
+}
+
+func (f *refundFixture) order(t *testing.T, id uuid.UUID) *entity.Order {
+	t.Helper()
+	order, err := f.orders.FindByField(context.Background(), "id", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestRefundOrderPartial(t *testing.T) {
+	f := newRefundFixture()
+	order := f.deliveredOrder(t)
+
+	res, err := f.svc.RefundOrder(context.Background(), order.ID.String(), dto.AdminRefundOrderReq{Amount: "10.00", Reason: "late"})
+	if err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	if res.Refund.Status != entity.RefundStatusSucceeded || res.Refund.Amount.Minor != 1000 || res.Refund.StripeRefundID == "" {
+		t.Errorf("refund = %+v", res.Refund)
+	}
+	if len(f.payments.refunds) != 1 || f.payments.refunds[0].IdempotencyKey != res.Refund.ID.String() {
+		t.Errorf("stripe calls = %+v, want one keyed by the refund id", f.payments.refunds)
+	}
+
+	got := f.order(t, order.ID)
+	if got.PaymentStatus != entity.PaymentStatusPaid || got.ExpiresAt != order.ExpiresAt {
+		t.Errorf("order = %s expiring %d, want it paid and still downloadable", got.PaymentStatus, got.ExpiresAt)
+	}
+	if delivery, _ := f.deliveries.FindByOrder(context.Background(), order.ID.String()); delivery.Token != "tok" {
+		t.Errorf("partial refund revoked the download token")
+	}
+	if !f.sampleOrdered(t, order) {
+		t.Errorf("partial refund freed the sample job")
+	}
+	if events := f.outbox.events(); !slices.Equal(events, []string{entity.OutboxEventOrderRefunded}) {
+		t.Errorf("events = %v", events)
+	}
+	if f.mail.count() != 1 || f.mail.sent[0].Template != "refund" || f.mail.sent[0].Model["amount"] != "USD 10.00" || f.mail.sent[0].Model["full"] != false {
+		t.Errorf("mail = %+v", f.mail.sent)
+	}
+
+	// what is left can be refunded, not more
+	_, err = f.svc.RefundOrder(context.Background(), order.ID.String(), dto.AdminRefundOrderReq{Amount: "40.00", Reason: "late"})
+	if !errors.Is(err, ErrInvalidRefund) {
+		t.Errorf("refunding more than left error = %v, want ErrInvalidRefund", err)
+	}
+}
+
+func TestRefundOrderFull(t *testing.T) {
+	f := newRefundFixture()
+	order := f.deliveredOrder(t)
+	ctx := context.Background()
+
+	if _, err := f.svc.RefundOrder(ctx, order.ID.String(), dto.AdminRefundOrderReq{Amount: "9.99", Reason: "late"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := f.svc.RefundOrder(ctx, order.ID.String(), dto.AdminRefundOrderReq{Reason: "never arrived"})
+	if err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	if res.Refund.Amount.Minor != 4000 {
+		t.Errorf("refund amount = %s, want what was left", res.Refund.Amount)
+	}
+
+	got := f.order(t, order.ID)
+	if got.PaymentStatus != entity.PaymentStatusRefunded || got.ExpiresAt > time.Now().Unix() {
+		t.Errorf("order = %s expiring %d, want it refunded and expired", got.PaymentStatus, got.ExpiresAt)
+	}
+	delivery, _ := f.deliveries.FindByOrder(ctx, order.ID.String())
+	if delivery.Status != entity.DeliveryStatusRevoked || delivery.Token != "" {
+		t.Errorf("delivery = %s with token %q, want it revoked", delivery.Status, delivery.Token)
+	}
+	job, _ := f.jobs.FindByField(ctx, "id", *order.ProductionJobID)
+	if job.SignedURL != "" || job.SignedURLExpiry != 0 {
+		t.Errorf("production job still carries signed url %q", job.SignedURL)
+	}
+	if f.sampleOrdered(t, order) {
+		t.Errorf("full refund left the sample job ordered")
+	}
+	want := []string{entity.OutboxEventOrderRefunded, entity.OutboxEventOrderAccessRevoked, entity.OutboxEventOrderRefunded}
+	if events := f.outbox.events(); !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if f.mail.count() != 2 || f.mail.sent[1].Model["full"] != true {
+		t.Errorf("mail = %+v, want a second email for the full refund", f.mail.sent)
+	}
+
+	if _, err := f.svc.RefundOrder(ctx, order.ID.String(), dto.AdminRefundOrderReq{Reason: "again"}); !errors.Is(err, ErrOrderNotRefundable) {
+		t.Errorf("refunding a refunded order error = %v, want ErrOrderNotRefundable", err)
+	}
+}
+
+func TestRefundOrderDeclined(t *testing.T) {
+	f := newRefundFixture()
+	order := f.deliveredOrder(t)
+	f.payments.refundErr = paymentRepository.ErrDeclined
+
+	_, err := f.svc.RefundOrder(context.Background(), order.ID.String(), dto.AdminRefundOrderReq{Reason: "late"})
+	if !errors.Is(err, ErrRefundFailed) {
+		t.Fatalf("RefundOrder error = %v, want ErrRefundFailed", err)
+	}
+
+	refunds, _ := f.refunds.ListByOrder(context.Background(), order.ID.String())
+	if len(refunds) != 1 || refunds[0].Status != entity.RefundStatusFailed || refunds[0].FailureReason == "" {
+		t.Errorf("refunds = %+v, want one failed", refunds)
+	}
+	if got := f.order(t, order.ID); got.PaymentStatus != entity.PaymentStatusPaid {
+		t.Errorf("order = %s, want it still paid", got.PaymentStatus)
+	}
+	if f.mail.count() != 0 || len(f.outbox.events()) != 0 {
+		t.Errorf("declined refund sent %d emails and events %v", f.mail.count(), f.outbox.events())
+	}
+
+	// a failed refund does not hold back the amount
+	f.payments.refundErr = nil
+	if _, err := f.svc.RefundOrder(context.Background(), order.ID.String(), dto.AdminRefundOrderReq{Reason: "late"}); err != nil {
+		t.Errorf("refund after a decline: %v", err)
+	}
+}
+
+func TestRefundOrderStripeUnreachable(t *testing.T) {
+	f := newRefundFixture()
+	order := f.deliveredOrder(t)
+	ctx := context.Background()
+	f.payments.refundErr = errors.New("connection reset")
+
+	res, err := f.svc.RefundOrder(ctx, order.ID.String(), dto.AdminRefundOrderReq{Reason: "late"})
+	if !errors.Is(err, ErrRefundPending) {
+		t.Fatalf("RefundOrder error = %v, want ErrRefundPending", err)
+	}
+	if res.Refund.Status != entity.RefundStatusPending || res.Refund.FailureReason != "connection reset" {
+		t.Errorf("refund = %+v, want it pending with the error", res.Refund)
+	}
+	// the pending refund still holds the amount
+	if _, err := f.svc.RefundOrder(ctx, order.ID.String(), dto.AdminRefundOrderReq{Reason: "again"}); !errors.Is(err, ErrInvalidRefund) {
+		t.Errorf("second refund error = %v, want ErrInvalidRefund", err)
+	}
+
+	// too recent to be retried
+	if n, err := f.svc.RetryPending(ctx, 10); err != nil || n != 0 {
+		t.Errorf("RetryPending = %d, %v, want nothing due", n, err)
+	}
+
+	stored, _ := f.refunds.get(res.Refund.ID)
+	stored.UpdatedAt -= int64(refundRetryAfter/time.Second) + 1
+	f.refunds.put(stored.ID, stored)
+
+	if n, err := f.svc.RetryPending(ctx, 10); err != nil || n != 0 {
+		t.Errorf("RetryPending while Stripe is down = %d, %v, want 0", n, err)
+	}
+	stored, _ = f.refunds.get(res.Refund.ID)
+	stored.UpdatedAt -= int64(refundRetryAfter/time.Second) + 1
+	f.refunds.put(stored.ID, stored)
+
+	f.payments.refundErr = nil
+	if n, err := f.svc.RetryPending(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("RetryPending = %d, %v, want 1", n, err)
+	}
+	for _, call := range f.payments.refunds {
+		if call.IdempotencyKey != res.Refund.ID.String() {
+			t.Errorf("stripe call keyed %s, want every attempt keyed %s", call.IdempotencyKey, res.Refund.ID)
+		}
+	}
+	if len(f.payments.refunds) != 3 {
+		t.Errorf("stripe called %d times, want 3", len(f.payments.refunds))
+	}
+	if got := f.order(t, order.ID); got.PaymentStatus != entity.PaymentStatusRefunded {
+		t.Errorf("order = %s, want refunded", got.PaymentStatus)
+	}
+	if f.mail.count() != 1 {
+		t.Errorf("sent %d emails, want one once the refund settled", f.mail.count())
+	}
+}
+
+func TestRetryPendingSkipsExpiredKeys(t *testing.T) {
+	f := newRefundFixture()
+	order := f.deliveredOrder(t)
+
+	created := time.Now().Add(-refundRetryWindow - time.Minute).Unix()
+	refund := &entity.Refund{OrderID: order.ID, Amount: order.Amount, Reason: "old", Status: entity.RefundStatusPending, CreatedAt: created, UpdatedAt: created}
+	if _, err := f.refunds.Create(context.Background(), refund); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := f.svc.RetryPending(context.Background(), 10); err != nil || n != 0 || len(f.payments.refunds) != 0 {
+		t.Errorf("RetryPending = %d, %v with %d stripe calls, want the old refund left alone", n, err, len(f.payments.refunds))
+	}
+}
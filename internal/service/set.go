@@ -1 +1,22 @@
 package service
+
+import (
+	"github.com/google/wire"
+)
+
+var ProviderSet = wire.NewSet(
+	NewJob,
+	NewAdmin,
+	NewAPIKey,
+	NewAudit,
+	NewTimeline,
+	NewOutbox,
+	NewCatalog,
+	NewPromotions,
+	NewRefunds,
+	NewCheckout,
+	NewProduction,
+	NewPayments,
+	NewDeliveries,
+	NewOrderExpiry,
+)
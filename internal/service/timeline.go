@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/playture/backend/internal/dto"
+	"github.com/playture/backend/internal/entity"
+	jobRepository "github.com/playture/backend/internal/repository/job_repository"
+	jobStatusEventRepository "github.com/playture/backend/internal/repository/job_status_event_repository"
+	"github.com/playture/backend/internal/repository/uow"
+)
+
+const timelineTimeout = 10 * time.Second
+
+type Timeline interface {
+	// Transition moves the job to a new status, persists it and records the
+	// transition, all inside the transaction carried by ctx. Every status
+	// change must go through here, it also moves the production status of
+	// the order a production job renders.
+	Transition(ctx context.Context, job *entity.Job, to entity.JobStatus, detail string) error
+	Get(ctx context.Context, jobID string) (*dto.JobTimeline, error)                                     // admin api
+	StageDurations(ctx context.Context, req dto.AdminStageDurationsReq) ([]*entity.StageDuration, error) // admin api
+}
+
+type timeline struct {
+	logger     *slog.Logger
+	uow        uow.IUOW
+	outbox     Outbox
+	production Production
+	jobRepo    jobRepository.Repository
+	eventRepo  jobStatusEventRepository.Repository
+}
+
+func NewTimeline(logger *slog.Logger,
+	uow uow.IUOW,
+	outbox Outbox,
+	production Production,
+	jobRepo jobRepository.Repository,
+	eventRepo jobStatusEventRepository.Repository,
+) Timeline {
+	return &timeline{
+		logger:     logger.With("layer", "TimelineService"),
+		uow:        uow,
+		outbox:     outbox,
+		production: production,
+		jobRepo:    jobRepo,
+		eventRepo:  eventRepo,
+	}
+}
+
+func (t *timeline) Transition(ctx context.Context, job *entity.Job, to entity.JobStatus, detail string) error {
+	now := time.Now().Unix()
+
+	event := &entity.JobStatusEvent{
+		JobID:      job.ID,
+		FromStatus: job.Status,
+		ToStatus:   to,
+		Detail:     detail,
+		CreatedAt:  now,
+	}
+	if _, err := t.eventRepo.Create(ctx, event); err != nil {
+		return err
+	}
+
+	if err := t.outbox.Enqueue(ctx, entity.OutboxTopicJobEvents, entity.OutboxEventJobStatusChanged,
+		string(entity.AuditEntityJob), job.ID.String(), event); err != nil {
+		return err
+	}
+
+	job.Status = to
+	if job.StartedAt == 0 && to.IsActive() {
+		job.StartedAt = now
+	}
+	if to.IsTerminal() {
+		job.CompletedAt = now
+		events, err := t.eventRepo.ListByJob(ctx, job.ID.String())
+		if err != nil {
+			return err
+		}
+		job.TotalProcessingTime = processingTime(events)
+	}
+
+	if err := t.jobRepo.UpdateStatus(ctx, job); err != nil {
+		return err
+	}
+	return t.production.Sync(ctx, job)
+}
+
+func (t *timeline) Get(ctx context.Context, jobID string) (*dto.JobTimeline, error) {
+	events, err := uow.Do(ctx, t.uow, func(ctx context.Context) ([]*entity.JobStatusEvent, error) {
+		// surfaces ErrJobNotFound instead of an empty history
+		if _, err := t.jobRepo.FindByField(ctx, "id", jobID); err != nil {
+			return nil, err
+		}
+		return t.eventRepo.ListByJob(ctx, jobID)
+	}, timelineTimeout, uow.ReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	stages := make([]dto.StageSpan, 0, len(events))
+	for i, e := range events {
+		span := dto.StageSpan{Status: e.ToStatus, EnteredAt: e.CreatedAt}
+		if i+1 < len(events) {
+			span.LeftAt = events[i+1].CreatedAt
+			span.Seconds = span.LeftAt - span.EnteredAt
+		}
+		stages = append(stages, span)
+	}
+
+	return &dto.JobTimeline{
+		Events:              events,
+		Stages:              stages,
+		TotalProcessingTime: processingTime(events),
+	}, nil
+}
+
+func (t *timeline) StageDurations(ctx context.Context, req dto.AdminStageDurationsReq) ([]*entity.StageDuration, error) {
+	return t.eventRepo.StageDurations(ctx, req.From, req.To)
+}
+
+// processingTime sums the seconds spent in active statuses over the whole
+// history, so time waiting in the queue between retries is not counted.
+func processingTime(events []*entity.JobStatusEvent) int64 {
+	var total int64
+	for i := 0; i+1 < len(events); i++ {
+		if events[i].ToStatus.IsActive() {
+			total += events[i+1].CreatedAt - events[i].CreatedAt
+		}
+	}
+	return total
+}
@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+    DROP COLUMN watermarked,
+    DROP COLUMN clean_video_url,
+    DROP COLUMN clean_video_s3_key;
@@ -0,0 +1,6 @@
+-- Sample jobs deliver a watermarked render, the clean render is kept aside
+-- until the customer pays for an order.
+ALTER TABLE jobs
+    ADD COLUMN watermarked BOOLEAN DEFAULT FALSE,
+    ADD COLUMN clean_video_url TEXT,
+    ADD COLUMN clean_video_s3_key TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS jobs_created_at_id_idx;
+DROP INDEX IF EXISTS jobs_status_created_at_id_idx;
+DROP INDEX IF EXISTS orders_created_at_id_idx;
@@ -0,0 +1,4 @@
+-- Keyset pagination walks (created_at, id) in both directions.
+CREATE INDEX IF NOT EXISTS jobs_created_at_id_idx ON jobs (created_at, id);
+CREATE INDEX IF NOT EXISTS jobs_status_created_at_id_idx ON jobs (status, created_at, id);
+CREATE INDEX IF NOT EXISTS orders_created_at_id_idx ON orders (created_at, id);
@@ -0,0 +1 @@
+DROP TABLE admin_api_keys;
@@ -0,0 +1,16 @@
+CREATE TABLE admin_api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+
+    -- Only a SHA-256 of the key is stored, the prefix identifies it in logs
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+
+    role SMALLINT NOT NULL,
+
+    -- Timestamps
+    last_used_at BIGINT,
+    revoked_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER audit_events_immutable ON audit_events;
+DROP FUNCTION audit_events_immutable();
+DROP TABLE audit_events;
@@ -0,0 +1,33 @@
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- Who did it, the admin API key and its name at the time
+    actor_id UUID,
+    actor_name TEXT NOT NULL,
+    ip_address VARCHAR(64),
+
+    -- What was done to which entity
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+
+    -- Field level before/after diff
+    changes JSONB,
+
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id, created_at, id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, created_at, id);
+CREATE INDEX audit_events_created_at_id_idx ON audit_events (created_at, id);
+
+-- The log is append-only
+CREATE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_immutable
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_immutable();
@@ -0,0 +1,18 @@
+CREATE TABLE job_status_events (
+    -- serial ids keep the order of transitions within the same second
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+
+    from_status SMALLINT,
+    to_status SMALLINT NOT NULL,
+    detail TEXT,
+
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX job_status_events_job_idx ON job_status_events (job_id, created_at, id);
+CREATE INDEX job_status_events_created_at_idx ON job_status_events (created_at);
+
+-- Seed the history with the current status of existing jobs
+INSERT INTO job_status_events (job_id, to_status, detail, created_at)
+SELECT id, status, 'backfilled', updated_at FROM jobs;
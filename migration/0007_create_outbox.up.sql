@@ -0,0 +1,24 @@
+CREATE TABLE outbox (
+    -- serial ids give the relay a delivery order
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Redis stream the message is published to
+    topic TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+
+    -- Entity the message is about
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+
+    payload JSONB NOT NULL,
+
+    -- Delivery tracking
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    delivered_at BIGINT,
+
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE delivered_at IS NULL;
+CREATE INDEX outbox_delivered_at_idx ON outbox (delivered_at) WHERE delivered_at IS NOT NULL;
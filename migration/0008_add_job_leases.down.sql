@@ -0,0 +1,3 @@
+ALTER TABLE jobs
+    DROP COLUMN lease_owner,
+    DROP COLUMN lease_expires_at;
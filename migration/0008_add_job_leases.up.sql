@@ -0,0 +1,6 @@
+-- Workers without Redis claim jobs straight from this table. A job is held
+-- by lease_owner until lease_expires_at, after which any worker may take it
+-- over. An empty owner and 0 mean the job is not leased.
+ALTER TABLE jobs
+    ADD COLUMN lease_owner TEXT NOT NULL DEFAULT '',
+    ADD COLUMN lease_expires_at BIGINT NOT NULL DEFAULT 0;
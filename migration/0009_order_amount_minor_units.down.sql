@@ -0,0 +1,16 @@
+ALTER TABLE orders ADD COLUMN amount NUMERIC(10, 2);
+
+UPDATE orders SET amount = amount_minor::numeric / CASE
+    WHEN currency IN ('JPY', 'KRW', 'VND', 'CLP', 'ISK', 'PYG', 'UGX') THEN 1
+    WHEN currency IN ('BHD', 'JOD', 'KWD', 'OMR', 'TND') THEN 1000
+    ELSE 100
+END;
+
+ALTER TABLE orders
+    ALTER COLUMN amount SET NOT NULL,
+    DROP CONSTRAINT orders_currency_iso,
+    ALTER COLUMN currency DROP NOT NULL,
+    ALTER COLUMN currency SET DEFAULT 'usd',
+    DROP COLUMN amount_minor;
+
+UPDATE orders SET currency = lower(currency);
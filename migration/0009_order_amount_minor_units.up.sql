@@ -0,0 +1,19 @@
+-- Order amounts move from NUMERIC major units to integer minor units, the
+-- way Stripe reports them. Zero and three decimal currencies are scaled by
+-- their own exponent, currencies become upper case ISO 4217 codes.
+ALTER TABLE orders ADD COLUMN amount_minor BIGINT;
+
+UPDATE orders SET currency = upper(COALESCE(NULLIF(currency, ''), 'usd'));
+
+UPDATE orders SET amount_minor = round(amount * CASE
+    WHEN currency IN ('JPY', 'KRW', 'VND', 'CLP', 'ISK', 'PYG', 'UGX') THEN 1
+    WHEN currency IN ('BHD', 'JOD', 'KWD', 'OMR', 'TND') THEN 1000
+    ELSE 100
+END);
+
+ALTER TABLE orders
+    ALTER COLUMN amount_minor SET NOT NULL,
+    ALTER COLUMN currency SET DEFAULT 'USD',
+    ALTER COLUMN currency SET NOT NULL,
+    ADD CONSTRAINT orders_currency_iso CHECK (currency ~ '^[A-Z]{3}$'),
+    DROP COLUMN amount;
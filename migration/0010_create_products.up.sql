@@ -0,0 +1,29 @@
+-- The catalog behind each OrderType: what is delivered and what it costs.
+CREATE TABLE products (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_type SMALLINT NOT NULL,
+    name TEXT NOT NULL,
+
+    -- Deliverable specs
+    video_width INT NOT NULL,
+    video_height INT NOT NULL,
+    max_duration_seconds INT NOT NULL,
+    watermark_free BOOLEAN NOT NULL DEFAULT FALSE,
+
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+-- Checkout resolves an order type to exactly one active product.
+CREATE UNIQUE INDEX products_active_order_type_idx ON products (order_type) WHERE active;
+
+-- One price per currency, in minor units. Payments are charged for the
+-- order amount, not a Stripe price, so discounts apply.
+CREATE TABLE product_prices (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    amount_minor BIGINT NOT NULL CHECK (amount_minor >= 0),
+    PRIMARY KEY (product_id, currency)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN discount_minor,
+    DROP COLUMN promotion_id;
+
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,50 @@
+-- Discount codes redeemable at checkout. A code is either a percentage or
+-- a fixed amount in one currency.
+CREATE TABLE promotions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code TEXT NOT NULL UNIQUE CHECK (code = upper(code) AND code <> ''),
+    kind SMALLINT NOT NULL,
+    percent_off SMALLINT,
+    amount_off_minor BIGINT,
+    amount_off_currency TEXT CHECK (amount_off_currency ~ '^[A-Z]{3}$'),
+
+    -- Validity window, NULL leaves that side open
+    starts_at BIGINT,
+    ends_at BIGINT,
+
+    -- Usage limits, NULL means unlimited
+    max_redemptions INT CHECK (max_redemptions > 0),
+    max_per_email INT CHECK (max_per_email > 0),
+    redemption_count INT NOT NULL DEFAULT 0,
+
+    -- Order types the code applies to, empty for all of them
+    order_types SMALLINT[] NOT NULL DEFAULT '{}',
+
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+
+    CONSTRAINT promotions_discount CHECK (
+        (kind = 1 AND percent_off BETWEEN 1 AND 100) OR
+        (kind = 2 AND amount_off_minor > 0 AND amount_off_currency IS NOT NULL)
+    )
+);
+
+-- Every use of a code, one per order. Per email limits count these rows.
+CREATE TABLE promotion_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promotion_id UUID NOT NULL REFERENCES promotions(id),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    discount_minor BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX promotion_redemptions_email_idx ON promotion_redemptions (promotion_id, email);
+
+-- Orders keep what they were discounted by, amount_minor is what is charged.
+ALTER TABLE orders
+    ADD COLUMN promotion_id UUID REFERENCES promotions(id),
+    ADD COLUMN discount_minor BIGINT NOT NULL DEFAULT 0 CHECK (discount_minor >= 0);
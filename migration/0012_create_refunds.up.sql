@@ -0,0 +1,25 @@
+-- Money returned on an order, one row per refund request. Pending rows
+-- count against what is left to refund until Stripe answers.
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    amount_minor BIGINT NOT NULL CHECK (amount_minor > 0),
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    reason TEXT NOT NULL,
+
+    -- Who asked for it, actor_id is NULL for the system
+    actor_id UUID,
+    actor_name TEXT NOT NULL,
+
+    status SMALLINT NOT NULL DEFAULT 1,
+    stripe_refund_id TEXT,
+    failure_reason TEXT,
+
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX refunds_order_idx ON refunds (order_id, created_at);
+
+-- Refunds Stripe did not answer for are retried, least recently tried first
+CREATE INDEX refunds_pending_idx ON refunds (updated_at) WHERE status = 1;
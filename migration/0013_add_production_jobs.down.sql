@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS orders_production_job_id_idx;
+DROP INDEX IF EXISTS jobs_status_priority_created_at_id_idx;
+
+ALTER TABLE jobs
+    DROP COLUMN priority,
+    DROP COLUMN source_job_id,
+    DROP COLUMN video_width,
+    DROP COLUMN video_height,
+    DROP COLUMN max_duration_seconds;
@@ -0,0 +1,14 @@
+-- A paid order is rendered again by a production job. It points back at the
+-- sample job it was ordered from, renders at the product's specs and is
+-- claimed ahead of samples.
+ALTER TABLE jobs
+    ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0,
+    ADD COLUMN source_job_id UUID REFERENCES jobs(id) ON DELETE SET NULL,
+    ADD COLUMN video_width INT,
+    ADD COLUMN video_height INT,
+    ADD COLUMN max_duration_seconds INT;
+
+CREATE INDEX IF NOT EXISTS jobs_status_priority_created_at_id_idx ON jobs (status, priority DESC, created_at, id);
+
+-- Progress of a production job is mapped back to the one order it renders.
+CREATE UNIQUE INDEX IF NOT EXISTS orders_production_job_id_idx ON orders (production_job_id) WHERE production_job_id IS NOT NULL;
@@ -0,0 +1,28 @@
+-- One delivery per order. A row is queued as pending when the production
+-- job completes, or when an admin asks for a re-delivery, and the delivery
+-- worker hands out the download link and sends the email the order's
+-- delivery method calls for.
+CREATE TABLE deliveries (
+    order_id UUID PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    status SMALLINT NOT NULL,
+
+    -- Secret part of the download link, replaced on every re-delivery
+    token TEXT UNIQUE,
+
+    -- Download tracking
+    download_count INT NOT NULL DEFAULT 0,
+    last_downloaded_at BIGINT,
+
+    email_sent_at BIGINT,
+
+    -- Worker attempts since the delivery was last queued
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+
+    delivered_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+-- Pending (1) deliveries and sending (5) ones whose worker may have died
+CREATE INDEX deliveries_pending_idx ON deliveries (updated_at) WHERE status IN (1, 5);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_unpaid_expires_at_idx;
+DROP INDEX IF EXISTS orders_job_id_open_idx;
@@ -0,0 +1,42 @@
+-- Unpaid orders expire (payment_status 5) once expires_at passes, which
+-- frees their sample job to be ordered again, as does a full refund (4). A
+-- job has at most one open order, and points at it.
+
+-- Jobs ordered more than once keep their paid order, or else their newest
+-- one, and the other unpaid orders expire and give back their promotion
+-- redemptions. Two paid orders of one job need a refund decided by hand,
+-- the unique index below fails on them.
+WITH ranked AS (
+    SELECT id, payment_status, row_number() OVER (
+        PARTITION BY job_id
+        ORDER BY payment_status = 2 DESC, created_at DESC, id DESC
+    ) AS rank
+    FROM orders
+    WHERE payment_status NOT IN (4, 5)
+), expired AS (
+    UPDATE orders SET payment_status = 5, updated_at = extract(epoch FROM now())::BIGINT
+    FROM ranked
+    WHERE orders.id = ranked.id AND ranked.rank > 1 AND ranked.payment_status IN (1, 3)
+    RETURNING orders.id
+), released AS (
+    DELETE FROM promotion_redemptions
+    WHERE order_id IN (SELECT id FROM expired)
+    RETURNING promotion_id
+)
+UPDATE promotions SET redemption_count = redemption_count - counts.n
+FROM (SELECT promotion_id, count(*) AS n FROM released GROUP BY promotion_id) counts
+WHERE promotions.id = counts.promotion_id;
+
+UPDATE jobs SET converted_to_order = FALSE, order_id = NULL
+WHERE order_id IS NOT NULL AND NOT EXISTS (
+    SELECT 1 FROM orders WHERE orders.id = jobs.order_id AND orders.payment_status NOT IN (4, 5)
+);
+
+UPDATE jobs SET converted_to_order = TRUE, order_id = orders.id
+FROM orders
+WHERE orders.job_id = jobs.id AND orders.payment_status NOT IN (4, 5);
+
+CREATE UNIQUE INDEX IF NOT EXISTS orders_job_id_open_idx ON orders (job_id) WHERE payment_status NOT IN (4, 5);
+
+-- The sweeper looks for pending and failed orders past their payment window
+CREATE INDEX IF NOT EXISTS orders_unpaid_expires_at_idx ON orders (expires_at) WHERE payment_status IN (1, 3);